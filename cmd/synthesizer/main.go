@@ -3,8 +3,24 @@ package main
 
 //-- Imports -----------------------------------------------------------------------------------------------------------
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log"
 	"math/rand"
+	"net/url"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/JustonDavies/go_browser_forensics/configs"
@@ -12,64 +28,759 @@ import (
 )
 
 //-- Constants ---------------------------------------------------------------------------------------------------------
+var (
+	inspect = flag.Bool(`inspect`, false, `summarise existing browser data without modifying it`)
+	list    = flag.Bool(`list`, false, `list registered browsers and the profiles each one detects, then exit`)
+	dryRun  = flag.Bool(`dry-run`, false, `generate against in-memory copies and print the planned changes without writing any profile`)
+	format  = flag.String(`format`, `text`, `inspection, listing and plan output format, text or json`)
+
+	importBookmarks = flag.String(`import-bookmarks`, ``, `Netscape bookmarks.html file to inject instead of generated bookmarks`)
+	exportBookmarks = flag.String(`export-bookmarks`, ``, `Netscape bookmarks.html file to write injected bookmarks to for review`)
+	faviconPath     = flag.String(`favicons`, ``, `directory of <domain>.png icons to use instead of generated placeholder favicons`)
+	importTimeline  = flag.String(`import-timeline`, ``, `Google Takeout BrowserHistory.json or timestamp,url,title,transition CSV to replay instead of generated history`)
+	safariPath      = flag.String(`safari`, ``, `Library/Safari directory to write History.db and Bookmarks.plist into, for offline targets`)
+	seed            = flag.Int64(`seed`, 0, `seed for which items are generated and which profiles take them, times stay relative to the run and encryption IVs are always random`)
+	workers         = flag.Int(`workers`, runtime.NumCPU(), `profiles to load, purge and commit at once`)
+	purgeDomains    = flag.String(`purge-domains`, ``, `comma separated host globs, only matching items are purged and everything else is kept`)
+	purgePattern    = flag.String(`purge-pattern`, ``, `regular expression, only items whose URL matches are purged`)
+	purgeAfter      = flag.String(`purge-after`, ``, `RFC 3339 time or date, only items from then on are purged`)
+	purgeBefore     = flag.String(`purge-before`, ``, `RFC 3339 time or date, only items from before then are purged`)
+	purgeTypes      = flag.String(`purge-types`, ``, `comma separated history, downloads, credentials, cookies or bookmarks, only those are purged`)
+	memoryBudget    = flag.Int(`chrome-memory-budget`, 0, `MiB of pending visits each Chrome profile holds before flushing, when set an imported timeline is also streamed in chunks, other browsers and generated history are held until commit`)
+)
+
+var random *rand.Rand
+
+const timelineChunk = 10000 // Timeline rows injected at a time when streaming under a memory budget
 
 //-- Structs -----------------------------------------------------------------------------------------------------------
+// targets spreads generated items over the opened profiles. A profile that reports a kind of item unsupported is left
+// out of the rest of that kind, so those items go to profiles that take them.
+type targets struct {
+	browsers []browsers.Browser
+	refused  map[string]map[browsers.Profile]bool
+}
 
 //-- Exported Functions ------------------------------------------------------------------------------------------------
 func main() {
+	flag.Parse()
+	browsers.CHROME_FAVICON_PATH = *faviconPath
+	browsers.SAFARI_DATA_PATH = *safariPath
+	browsers.CHROME_HISTORY_MEMORY_BUDGET = *memoryBudget << 20
+	browsers.WORKERS = *workers
+	browsers.DRY_RUN = *dryRun
+
 	//-- Log nice output ----------
 	var start = time.Now().Unix()
 	log.Println(`Starting task...`)
 
+	//-- Seed generated items, logged so the same items can be generated again ----------
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	log.Printf(`Seeding with %d`, *seed)
+
+	browsers.SEED = *seed
+	random = rand.New(rand.NewSource(*seed))
+
+	browsers.PROGRESS = func(progress browsers.Progress) {
+		if progress.Op == `commit` && progress.Err == nil {
+			log.Printf(`Committed %s profile '%s' (%d/%d)`, progress.Browser, progress.Profile, progress.Done, progress.Total)
+		}
+	}
+
+	//-- Stop at the next item on an interrupt, nothing is written until commit ----------
+	var ctx, stop = signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	//-- Read inputs before touching any browser ----------
+	var history []browsers.History
+	if *importTimeline != `` && *memoryBudget <= 0 {
+		if items, err := readTimeline(*importTimeline); err != nil {
+			panic(fmt.Sprintf(`unable to import timeline from '%s': %s`, *importTimeline, err))
+		} else {
+			history = items
+		}
+	}
+
+	var filter, filterErr = readPurgeFilter()
+	if filterErr != nil {
+		panic(fmt.Sprintf(`unable to read purge filter: %s`, filterErr))
+	}
+
+	var bookmarks []browsers.Bookmark
+	if *importBookmarks != `` {
+		if items, err := readBookmarks(*importBookmarks); err != nil {
+			panic(fmt.Sprintf(`unable to import bookmarks from '%s': %s`, *importBookmarks, err))
+		} else {
+			bookmarks = items
+		}
+
+		for index := range bookmarks {
+			bookmarks[index].CreateWindow = configs.DefaultDuration
+		}
+	}
+
 	//-- Perform task ----------
-	var browserz = browsers.Open()
+	var browserz, err = browsers.Open(ctx)
+	logErrors(`unable to open browser`, err)
+
+	if *list {
+		defer closeBrowsers(browserz)
+		if err := writeBrowsers(os.Stdout, browserz, *format); err != nil {
+			log.Printf("unable to write browser list: \n\tError: '%s'", err)
+		}
+		return
+	}
 
 	if len(browserz) < 1 {
 		panic(`unable to open any supported browsers, aborting...`)
 	} else {
-		defer browsers.Close(browserz)
-		rand.Seed(time.Now().UnixNano())
+		defer closeBrowsers(browserz)
+	}
+
+	logErrors(`unable to load browser`, browsers.Load(ctx, browserz))
+
+	if *inspect {
+		var reports, err = browsers.Inspect(browserz)
+		logErrors(`unable to inspect browser`, err)
+
+		if err := writeReports(os.Stdout, reports, *format); err != nil {
+			log.Printf("unable to write inspection report: \n\tError: '%s'", err)
+		}
+		return
+	}
+
+	if filter != nil {
+		logErrors(`unable to purge browser`, browsers.PurgeMatching(ctx, browserz, *filter))
+	} else {
+		logErrors(`unable to purge browser`, browsers.Purge(ctx, browserz))
 	}
 
-	browsers.Load(browserz)
-	browsers.Purge(browserz)
+	var pool = &targets{browsers: browserz, refused: map[string]map[browsers.Profile]bool{}}
 
 	log.Println(`Creating history...`)
-	for _, item := range configs.ActivityItems {
-		var browser = browserz[rand.Intn(len(browserz))]
-		var item = browsers.History{
-			Name:        item.Name,
-			URL:         item.URL,
-			Visits:      rand.Intn(configs.MaximumVisits),
-			VisitWindow: configs.DefaultDuration,
+	if *importTimeline != `` && *memoryBudget > 0 {
+		//-- Stream the timeline, keeping only what the other generators need ----------
+		var seen = map[string]bool{}
+		var err = streamTimeline(*importTimeline, func(chunk []browsers.History) error {
+			injectHistory(ctx, pool, chunk)
+
+			for _, item := range chunk {
+				if !seen[item.URL] {
+					seen[item.URL] = true
+					history = append(history, browsers.History{Name: item.Name, URL: item.URL})
+				}
+			}
+
+			return ctx.Err()
+		})
+
+		if err != nil && ctx.Err() == nil {
+			log.Printf("unable to stream timeline from '%s', discarding changes: \n\tError: '%s'", *importTimeline, err)
+			return
+		}
+	} else {
+		if *importTimeline == `` {
+			history = generateHistory()
 		}
 
-		if err := browser.AddHistory(item); err != nil {
-			log.Printf("unable to inject history item for: \n\tURL: '%s' \n\tError: '%s'", item.URL, err)
+		injectHistory(ctx, pool, history)
+	}
+
+	log.Println(`Creating credentials...`)
+	var credentials = generateCredentials(configs.DefaultPersona, history)
+	for _, item := range credentials {
+		if ctx.Err() != nil {
+			break
+		}
+
+		var err = pool.add(`credentials`, func(profile browsers.Profile) error {
+			return profile.AddCredential(ctx, item)
+		})
+
+		if err != nil {
+			log.Printf("unable to inject credential for: \n\tURL: '%s' \n\tError: '%s'", item.URL, err)
 		}
 	}
 
-	log.Println(`Creating bookmarks...`)
-	for _, item := range configs.ActivityItems {
-		if rand.Intn(configs.BookmarkOneInX) == 0 {
-			var browser = browserz[rand.Intn(len(browserz))]
-			var item = browsers.Bookmark{
-				Name:         item.Name,
-				URL:          item.URL,
+	log.Println(`Creating form data...`)
+	for _, item := range generateFormEntries(configs.DefaultPersona, credentials, history) {
+		if ctx.Err() != nil {
+			break
+		}
+
+		var err = pool.add(`form entries`, func(profile browsers.Profile) error {
+			return profile.AddFormEntry(ctx, item)
+		})
+
+		if err != nil {
+			log.Printf("unable to inject form entry for: \n\tName: '%s' \n\tError: '%s'", item.Name, err)
+		}
+	}
+
+	log.Println(`Creating cookies...`)
+	for _, item := range generateCookies(history) {
+		if ctx.Err() != nil {
+			break
+		}
+
+		var err = pool.add(`cookies`, func(profile browsers.Profile) error {
+			return profile.AddCookie(ctx, item)
+		})
+
+		if err != nil {
+			log.Printf("unable to inject cookie for: \n\tHost: '%s' \n\tError: '%s'", item.Host, err)
+		}
+	}
+
+	log.Println(`Creating downloads...`)
+	for _, item := range generateDownloads(history) {
+		if ctx.Err() != nil {
+			break
+		}
+
+		var err = pool.add(`downloads`, func(profile browsers.Profile) error {
+			return profile.AddDownload(ctx, item)
+		})
+
+		if err != nil {
+			log.Printf("unable to inject download for: \n\tURL: '%s' \n\tError: '%s'", item.URL, err)
+		}
+	}
+
+	for _, browser := range browserz {
+		if ctx.Err() != nil {
+			break
+		}
+
+		var profile, err = browsers.RandomProfile(browser)
+		if err != nil {
+			log.Printf("unable to select a profile for: \n\tBrowser: '%s' \n\tError: '%s'", browser.Metadata().Name, err)
+			continue
+		}
+
+		var persona = configs.DefaultPersona
+		var item = browsers.Address{
+			FirstName:    persona.FirstName,
+			LastName:     persona.LastName,
+			Company:      persona.Company,
+			Street:       persona.Street,
+			City:         persona.City,
+			State:        persona.State,
+			Zip:          persona.Zip,
+			Country:      persona.Country,
+			Email:        persona.Email,
+			Phone:        persona.Phone,
+			Uses:         random.Intn(configs.MaximumFormUses) + 1,
+			CreateWindow: configs.DefaultDuration,
+		}
+
+		if err := profile.AddAddress(ctx, item); err != nil && !pool.refuses(`addresses`, profile, err) {
+			log.Printf("unable to inject address for: \n\tName: '%s %s' \n\tError: '%s'", item.FirstName, item.LastName, err)
+		}
+
+		for _, engine := range configs.SearchEngines {
+			var item = browsers.SearchEngine{
+				Name:         engine.Name,
+				Keyword:      engine.Keyword,
+				URL:          engine.URL,
+				Uses:         random.Intn(configs.MaximumFormUses),
 				CreateWindow: configs.DefaultDuration,
 			}
 
-			if err := browser.AddBookmark(item); err != nil {
-				log.Printf("unable to inject bookmark item for: \n\tURL: '%s' \n\tError: '%s'", item.URL, err)
+			if err := profile.AddSearchEngine(ctx, item); err != nil && !pool.refuses(`search engines`, profile, err) {
+				log.Printf("unable to inject search engine for: \n\tName: '%s' \n\tError: '%s'", item.Name, err)
 			}
 		}
 	}
 
+	log.Println(`Creating bookmarks...`)
+	if *importBookmarks == `` {
+		bookmarks = generateBookmarks()
+	}
+
+	for _, item := range bookmarks {
+		if ctx.Err() != nil {
+			break
+		}
+
+		var err = pool.add(`bookmarks`, func(profile browsers.Profile) error {
+			return profile.AddBookmark(ctx, item)
+		})
+
+		if err != nil {
+			log.Printf("unable to inject bookmark item for: \n\tURL: '%s' \n\tError: '%s'", item.URL, err)
+		}
+	}
+
+	if *exportBookmarks != `` {
+		if err := writeBookmarks(*exportBookmarks, bookmarks); err != nil {
+			log.Printf("unable to export bookmarks to: \n\tPath: '%s' \n\tError: '%s'", *exportBookmarks, err)
+		}
+	}
+
+	if ctx.Err() != nil {
+		log.Println(`Interrupted, discarding changes...`)
+		return
+	}
+
 	log.Println(`Committing changes...`)
-	browsers.Commit(browserz)
+	logErrors(`unable to commit browser`, browsers.Commit(ctx, browserz))
+
+	if ctx.Err() != nil {
+		log.Println(`Interrupted, uncommitted profiles were rolled back`)
+		return
+	}
+
+	if *dryRun {
+		var plans, err = browsers.Plans(browserz)
+		logErrors(`unable to plan browser`, err)
+
+		if err := writePlans(os.Stdout, plans, *format); err != nil {
+			log.Printf("unable to write plan: \n\tError: '%s'", err)
+		}
+		log.Println(`Dry run, no profile was written`)
+	}
 
 	//-- Log nice output ----------
 	log.Printf(`Task complete! It took %d seconds`, time.Now().Unix()-start)
 }
 
 //-- Internal Functions ------------------------------------------------------------------------------------------------
+// logErrors logs each failure of a browser operation on its own, a browser that is not installed is not worth a line.
+func logErrors(message string, err error) {
+	var failures browsers.Errors
+	if err == nil {
+		return
+	} else if !errors.As(err, &failures) {
+		failures = browsers.Errors{err}
+	}
+
+	for _, failure := range failures {
+		var located *browsers.Error
+		if errors.Is(failure, browsers.ErrNotFound) && errors.As(failure, &located) && located.Profile == `` {
+			continue
+		}
+
+		log.Printf("%s: \n\tError: '%s'", message, failure)
+	}
+}
+
+func closeBrowsers(browserz []browsers.Browser) {
+	logErrors(`unable to close browser`, browsers.Close(browserz))
+}
+
+func injectHistory(ctx context.Context, pool *targets, history []browsers.History) {
+	for _, item := range history {
+		if ctx.Err() != nil {
+			break
+		}
+
+		var err = pool.add(`history`, func(profile browsers.Profile) error {
+			return profile.AddHistory(ctx, item)
+		})
+
+		if err != nil {
+			log.Printf("unable to inject history item for: \n\tURL: '%s' \n\tError: '%s'", item.URL, err)
+		}
+	}
+}
+
+// add hands an item to random profiles until one takes it. A profile that refuses is logged once per kind, an item no
+// profile takes is dropped without another line.
+func (t *targets) add(kind string, add func(browsers.Profile) error) error {
+	var keep = func(profile browsers.Profile) bool {
+		return !t.refused[kind][profile]
+	}
+
+	for {
+		var profile, err = browsers.RandomProfileFunc(keep, t.browsers...)
+		if errors.Is(err, browsers.ErrUnsupported) {
+			return nil
+		} else if err == nil {
+			err = add(profile)
+		}
+
+		if !t.refuses(kind, profile, err) {
+			return err
+		}
+	}
+}
+
+// refuses reports whether err marks a kind of item unsupported by the profile, logging the first time it does.
+func (t *targets) refuses(kind string, profile browsers.Profile, err error) bool {
+	if !errors.Is(err, browsers.ErrUnsupported) {
+		return false
+	}
+
+	if !t.refused[kind][profile] {
+		log.Printf("skipping %s for: \n\tProfile: '%s %s' \n\tError: '%s'", kind, profile.Browser(), profile.Name(), err)
+
+		if t.refused[kind] == nil {
+			t.refused[kind] = map[browsers.Profile]bool{}
+		}
+		t.refused[kind][profile] = true
+	}
+
+	return true
+}
+
+// generateHistory builds every generated item at once, the Chrome memory budget only bounds the visits profiles hold.
+func generateHistory() []browsers.History {
+	var history []browsers.History
+
+	for _, item := range configs.ActivityItems {
+		history = append(history, browsers.History{
+			Name:        item.Name,
+			URL:         item.URL,
+			Visits:      random.Intn(configs.MaximumVisits),
+			VisitWindow: configs.DefaultDuration,
+		})
+	}
+
+	return history
+}
+
+func generateCredentials(persona configs.Persona, history []browsers.History) []browsers.Credential {
+	var credentials []browsers.Credential
+
+	for _, item := range history {
+		if random.Intn(configs.CredentialOneInX) == 0 {
+			var userName = persona.UserName
+			if random.Intn(2) == 0 {
+				userName = persona.Email
+			}
+
+			credentials = append(credentials, browsers.Credential{
+				URL:          item.URL,
+				UserName:     userName,
+				Password:     randomPassword(),
+				CreateWindow: configs.DefaultDuration,
+			})
+		}
+	}
+
+	return credentials
+}
+
+// generateFormEntries mirrors what a persona would have typed into forms: the user names of their saved logins, their
+// contact details on sign-up pages and searches for sites they went on to visit.
+func generateFormEntries(persona configs.Persona, credentials []browsers.Credential, history []browsers.History) []browsers.FormEntry {
+	var entries []browsers.FormEntry
+	var entry = func(name string, value string) browsers.FormEntry {
+		return browsers.FormEntry{Name: name, Value: value, Uses: random.Intn(configs.MaximumFormUses) + 1, CreateWindow: configs.DefaultDuration}
+	}
+
+	for _, credential := range credentials {
+		if strings.Contains(credential.UserName, `@`) {
+			entries = append(entries, entry(`email`, credential.UserName))
+		} else {
+			entries = append(entries, entry(`username`, credential.UserName))
+		}
+	}
+
+	if len(credentials) > 0 {
+		entries = append(entries,
+			entry(`firstname`, persona.FirstName),
+			entry(`lastname`, persona.LastName),
+			entry(`name`, persona.FirstName+` `+persona.LastName),
+			entry(`email`, persona.Email),
+			entry(`phone`, persona.Phone),
+			entry(`address`, persona.Street),
+			entry(`city`, persona.City),
+			entry(`zip`, persona.Zip),
+		)
+	}
+
+	for _, item := range history {
+		if item.Name != `` && random.Intn(configs.SearchOneInX) == 0 {
+			entries = append(entries, entry(`q`, strings.ToLower(strings.TrimSpace(item.Name))))
+		}
+	}
+
+	return entries
+}
+
+// generateCookies leaves an analytics cookie on every site of the sample and a login session on some, persistent
+// sessions being the ones a "remember me" box would have left.
+func generateCookies(history []browsers.History) []browsers.Cookie {
+	var cookies []browsers.Cookie
+
+	for _, item := range history {
+		var parsed, err = url.Parse(item.URL)
+		if err != nil || parsed.Hostname() == `` || random.Intn(configs.CookieOneInX) != 0 {
+			continue
+		}
+
+		var secure = parsed.Scheme == `https`
+		cookies = append(cookies, browsers.Cookie{
+			Host:         `.` + parsed.Hostname(),
+			Name:         `_ga`,
+			Value:        fmt.Sprintf(`GA1.1.%d.%d`, random.Int31(), time.Now().Add(-time.Duration(random.Int63n(int64(configs.DefaultDuration)))).Unix()),
+			Path:         `/`,
+			Secure:       secure,
+			Lifetime:     time.Hour * 24 * 400,
+			CreateWindow: configs.DefaultDuration,
+		})
+
+		if random.Intn(2) == 0 {
+			var lifetime time.Duration
+			if random.Intn(2) == 0 {
+				lifetime = time.Hour * 24 * 30
+			}
+
+			cookies = append(cookies, browsers.Cookie{
+				Host:         parsed.Hostname(),
+				Name:         `session_id`,
+				Value:        fmt.Sprintf(`%016x%016x`, random.Uint64(), random.Uint64()),
+				Path:         `/`,
+				Secure:       secure,
+				HTTPOnly:     true,
+				Lifetime:     lifetime,
+				CreateWindow: time.Hour * 24 * 14,
+			})
+		}
+	}
+
+	return cookies
+}
+
+func generateDownloads(history []browsers.History) []browsers.Download {
+	var downloads []browsers.Download
+
+	for _, item := range history {
+		var parsed, err = url.Parse(item.URL)
+		if err != nil || parsed.Hostname() == `` || random.Intn(configs.DownloadOneInX) != 0 {
+			continue
+		}
+
+		var kind = configs.DownloadTypes[random.Intn(len(configs.DownloadTypes))]
+		var name = fmt.Sprintf(kind.Pattern, strings.Split(strings.TrimPrefix(parsed.Hostname(), `www.`), `.`)[0])
+
+		downloads = append(downloads, browsers.Download{
+			URL:          strings.TrimSuffix(item.URL, `/`) + `/downloads/` + name,
+			FileName:     name,
+			Size:         1 + random.Int63n(kind.MaximumSize),
+			CreateWindow: configs.DefaultDuration,
+		})
+	}
+
+	return downloads
+}
+
+func randomPassword() string {
+	var alphabet = `abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789!@#$%`
+	var password = make([]byte, 12+random.Intn(8))
+
+	for index := range password {
+		password[index] = alphabet[random.Intn(len(alphabet))]
+	}
+
+	return string(password)
+}
+
+func generateBookmarks() []browsers.Bookmark {
+	var bookmarks []browsers.Bookmark
+
+	for _, item := range configs.ActivityItems {
+		if random.Intn(configs.BookmarkOneInX) == 0 {
+			var folder []string
+			if random.Intn(configs.BookmarkLooseOneInX) != 0 {
+				folder = []string{item.Category()}
+			}
+
+			bookmarks = append(bookmarks, browsers.Bookmark{
+				Name:      item.Name,
+				URL:       item.URL,
+				Folder:    folder,
+				CreatedAt: time.Now().Add(-time.Duration(random.Int63n(int64(configs.DefaultDuration)))),
+			})
+		}
+	}
+
+	return bookmarks
+}
+
+func writeReports(output io.Writer, reports []browsers.Report, format string) error {
+	switch format {
+	case `json`:
+		var encoder = json.NewEncoder(output)
+		encoder.SetIndent(``, `  `)
+		return encoder.Encode(reports)
+	case `text`:
+		for _, report := range reports {
+			if err := report.WriteText(output); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf(`unknown format '%s'`, format)
+	}
+}
+
+// readPurgeFilter builds the filter of a selective purge from its flags, nil when none is set and everything is purged.
+func readPurgeFilter() (*browsers.PurgeFilter, error) {
+	if *purgeDomains == `` && *purgePattern == `` && *purgeAfter == `` && *purgeBefore == `` && *purgeTypes == `` {
+		return nil, nil
+	}
+
+	var filter = new(browsers.PurgeFilter)
+
+	for _, domain := range strings.Split(*purgeDomains, `,`) {
+		if domain = strings.TrimSpace(domain); domain == `` {
+			continue
+		} else if _, err := path.Match(domain, ``); err != nil {
+			return nil, fmt.Errorf(`invalid domain glob '%s': %w`, domain, err)
+		} else {
+			filter.Domains = append(filter.Domains, domain)
+		}
+	}
+
+	if *purgePattern != `` {
+		if pattern, err := regexp.Compile(*purgePattern); err != nil {
+			return nil, err
+		} else {
+			filter.Pattern = pattern
+		}
+	}
+
+	if *purgeAfter != `` {
+		if moment, err := parseTime(*purgeAfter); err != nil {
+			return nil, err
+		} else {
+			filter.After = moment
+		}
+	}
+
+	if *purgeBefore != `` {
+		if moment, err := parseTime(*purgeBefore); err != nil {
+			return nil, err
+		} else {
+			filter.Before = moment
+		}
+	}
+
+	for _, name := range strings.Split(*purgeTypes, `,`) {
+		if strings.TrimSpace(name) == `` {
+			continue
+		} else if kind, err := browsers.ParseDataType(name); err != nil {
+			return nil, err
+		} else {
+			filter.Types = append(filter.Types, kind)
+		}
+	}
+
+	return filter, nil
+}
+
+func parseTime(raw string) (time.Time, error) {
+	if moment, err := time.Parse(time.RFC3339, raw); err == nil {
+		return moment, nil
+	}
+
+	return time.ParseInLocation(`2006-01-02`, raw, time.Local)
+}
+
+func writePlans(output io.Writer, plans []browsers.Plan, format string) error {
+	switch format {
+	case `json`:
+		var encoder = json.NewEncoder(output)
+		encoder.SetIndent(``, `  `)
+		return encoder.Encode(plans)
+	case `text`:
+		for _, plan := range plans {
+			if err := plan.WriteText(output); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf(`unknown format '%s'`, format)
+	}
+}
+
+func writeBrowsers(output io.Writer, browserz []browsers.Browser, format string) error {
+	var detected []browsers.Metadata
+	for _, browser := range browserz {
+		detected = append(detected, browser.Metadata())
+	}
+
+	switch format {
+	case `json`:
+		var encoder = json.NewEncoder(output)
+		encoder.SetIndent(``, `  `)
+		return encoder.Encode(map[string]interface{}{`registered`: browsers.Registered(), `detected`: detected})
+	case `text`:
+		var writer = tabwriter.NewWriter(output, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(writer, "Registered\t%s\n\n", strings.Join(browsers.Registered(), `, `))
+		fmt.Fprintf(writer, "Browser\tEngine\tVersion\tProfiles\n")
+		for _, metadata := range detected {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", metadata.Name, metadata.Engine, metadata.Version, strings.Join(metadata.Profiles, `, `))
+		}
+		return writer.Flush()
+	default:
+		return fmt.Errorf(`unknown format '%s'`, format)
+	}
+}
+
+func readTimeline(path string) ([]browsers.History, error) {
+	var file *os.File
+	if handle, err := os.Open(path); err != nil {
+		return nil, err
+	} else {
+		file = handle
+		defer file.Close()
+	}
+
+	if strings.EqualFold(filepath.Ext(path), `.json`) {
+		return browsers.ReadTakeoutHistory(file)
+	}
+
+	return browsers.ReadTimelineCSV(file)
+}
+
+func streamTimeline(path string, handle func([]browsers.History) error) error {
+	var file *os.File
+	if opened, err := os.Open(path); err != nil {
+		return err
+	} else {
+		file = opened
+		defer file.Close()
+	}
+
+	if strings.EqualFold(filepath.Ext(path), `.json`) {
+		return browsers.StreamTakeoutHistory(file, timelineChunk, handle)
+	}
+
+	return browsers.StreamTimelineCSV(file, timelineChunk, handle)
+}
+
+func readBookmarks(path string) ([]browsers.Bookmark, error) {
+	var file *os.File
+	if handle, err := os.Open(path); err != nil {
+		return nil, err
+	} else {
+		file = handle
+		defer file.Close()
+	}
+
+	return browsers.ReadNetscapeBookmarks(file)
+}
+
+func writeBookmarks(path string, bookmarks []browsers.Bookmark) error {
+	var file *os.File
+	if handle, err := os.Create(path); err != nil {
+		return err
+	} else {
+		file = handle
+	}
+
+	if err := browsers.WriteNetscapeBookmarks(file, bookmarks); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,51 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package configs
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"strings"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+const BookmarkLooseOneInX = 5
+
+const DefaultCategory = `Miscellaneous`
+
+var Categories = []Category{
+	{`Government`, []string{`.gov`, `.mil`, `government`, `ministry`, `department of`}},
+	{`Insurance`, []string{`insurance`, `assurance`, `reinsurance`, `underwriters`, `indemnity`}},
+	{`Finance`, []string{`bank`, `finance`, `financial`, `capital`, `credit`, `invest`, `paypal`, `money`, `loan`, `trade`, `coin`, `stock`}},
+	{`Education`, []string{`.edu`, `university`, `college`, `school`, `academy`, `learn`, `course`, `tutorial`}},
+	{`Health`, []string{`health`, `medical`, `medicine`, `clinic`, `pharma`, `hospital`, `care`}},
+	{`News`, []string{`news`, `times`, `herald`, `daily`, `journal`, `tribune`, `press`, `gazette`, `magazine`}},
+	{`Shopping`, []string{`shop`, `store`, `buy`, `mall`, `market`, `deal`, `amazon`, `ebay`, `aliexpress`, `coupon`}},
+	{`Travel`, []string{`travel`, `airline`, `airways`, `hotel`, `flight`, `trip`, `tour`, `booking`}},
+	{`Entertainment`, []string{`game`, `movie`, `music`, `video`, `anime`, `film`, `stream`, `radio`, `tv`, `play`}},
+	{`Social`, []string{`social`, `chat`, `forum`, `community`, `facebook`, `twitter`, `reddit`, `dating`, `blog`}},
+	{`Technology`, []string{`soft`, `tech`, `cloud`, `data`, `host`, `web`, `dev`, `code`, `linux`, `android`, `app`, `server`, `digital`, `cyber`, `security`}},
+}
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+type Category struct {
+	Name     string
+	Keywords []string
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+// Category files an activity item under the first category with a keyword in its name or URL, categories are checked
+// in order so the more specific ones are listed first.
+func (i ActivityItem) Category() string {
+	var haystack = strings.ToLower(i.Name + ` ` + i.URL)
+
+	for _, category := range Categories {
+		for _, keyword := range category.Keywords {
+			if strings.Contains(haystack, keyword) {
+				return category.Name
+			}
+		}
+	}
+
+	return DefaultCategory
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
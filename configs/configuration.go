@@ -13,6 +13,18 @@ const DefaultDuration = time.Duration(time.Hour * 24 * 7 * 52 * 4)
 
 const BookmarkOneInX = 100
 
+const CookieOneInX = 3
+
+const DownloadOneInX = 25
+
+var DownloadTypes = []DownloadType{
+	{`%s-invoice.pdf`, 2 << 20},
+	{`%s-setup.exe`, 120 << 20},
+	{`%s-export.zip`, 60 << 20},
+	{`%s-report.docx`, 1 << 20},
+	{`%s-photo.jpg`, 6 << 20},
+}
+
 var ActivityItems = []ActivityItem{
 	{`Google Inc.`, `https://google.com`},
 	{`1001fonts`, `https://1001fonts.com`},
@@ -19597,6 +19609,12 @@ type ActivityItem struct {
 	URL  string
 }
 
+// DownloadType names a file after the site it came from, Pattern takes the site's name.
+type DownloadType struct {
+	Pattern     string
+	MaximumSize int64
+}
+
 //-- Exported Functions ------------------------------------------------------------------------------------------------
 
 //-- Internal Functions ------------------------------------------------------------------------------------------------
@@ -0,0 +1,57 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package configs
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+const CredentialOneInX = 40
+
+const SearchOneInX = 15
+
+const MaximumFormUses = 30
+
+var DefaultPersona = Persona{
+	FirstName: `Jordan`,
+	LastName:  `Avery`,
+	UserName:  `javery`,
+	Email:     `jordan.avery@example.com`,
+	Phone:     `+1 555 0134`,
+	Company:   `Avery Consulting`,
+	Street:    `1428 Elm Street`,
+	City:      `Springwood`,
+	State:     `OH`,
+	Zip:       `43004`,
+	Country:   `US`,
+}
+
+var SearchEngines = []SearchEngineItem{
+	{`DuckDuckGo`, `duckduckgo.com`, `https://duckduckgo.com/?q={searchTerms}`},
+	{`Bing`, `bing.com`, `https://www.bing.com/search?q={searchTerms}`},
+	{`Wikipedia`, `w`, `https://en.wikipedia.org/w/index.php?search={searchTerms}`},
+	{`YouTube`, `yt`, `https://www.youtube.com/results?search_query={searchTerms}`},
+}
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+type Persona struct {
+	FirstName string
+	LastName  string
+	UserName  string
+	Email     string
+	Phone     string
+	Company   string
+	Street    string
+	City      string
+	State     string
+	Zip       string
+	Country   string
+}
+
+type SearchEngineItem struct {
+	Name    string
+	Keyword string
+	URL     string
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
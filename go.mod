@@ -1,5 +1,7 @@
 module github.com/JustonDavies/go_browser_forensics
 
+go 1.24
+
 require (
 	cloud.google.com/go v0.35.1 // indirect
 	github.com/denisenkom/go-mssqldb v0.0.0-20190204142019-df6d76eb9289 // indirect
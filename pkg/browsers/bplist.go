@@ -0,0 +1,492 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+	"unicode/utf16"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+var (
+	BPLIST_MAGIC        = []byte(`bplist00`)
+	BPLIST_TRAILER_SIZE = 32
+	BPLIST_MAX_DEPTH    = 512
+
+	coreDataEpoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// Object markers, the high nibble of each object's first byte, CFBinaryPList.c
+const (
+	bplistSimple  = 0x00
+	bplistInteger = 0x10
+	bplistReal    = 0x20
+	bplistDate    = 0x30
+	bplistData    = 0x40
+	bplistASCII   = 0x50
+	bplistUTF16   = 0x60
+	bplistUID     = 0x80
+	bplistArray   = 0xa0
+	bplistDict    = 0xd0
+
+	bplistNull  = 0x00
+	bplistFalse = 0x08
+	bplistTrue  = 0x09
+)
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+// plistUID is a keyed archiver object reference, kept distinct from integers so it survives a round trip.
+type plistUID uint64
+
+type bplistReader struct {
+	data     []byte
+	offsets  []uint64
+	refSize  int
+	visiting map[uint64]bool
+}
+
+type bplistWriter struct {
+	objects  []interface{}
+	children [][]uint64
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+// readBinaryPlist decodes a bplist00 document into dictionaries (map[string]interface{}), arrays ([]interface{}),
+// string, int64, float64, bool, []byte, time.Time and plistUID values.
+func readBinaryPlist(input io.Reader) (interface{}, error) {
+	var data, err = io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < len(BPLIST_MAGIC)+BPLIST_TRAILER_SIZE || string(data[:len(BPLIST_MAGIC)]) != string(BPLIST_MAGIC) {
+		return nil, errors.New(`not a binary property list`)
+	}
+
+	//-- Read trailer ----------
+	var reader = &bplistReader{data: data, visiting: map[uint64]bool{}}
+	var top, table uint64
+	var offsetSize int
+	{
+		var trailer = data[len(data)-BPLIST_TRAILER_SIZE:]
+		offsetSize = int(trailer[6])
+		reader.refSize = int(trailer[7])
+
+		var count = binary.BigEndian.Uint64(trailer[8:])
+		top = binary.BigEndian.Uint64(trailer[16:])
+		table = binary.BigEndian.Uint64(trailer[24:])
+
+		if offsetSize < 1 || offsetSize > 8 || reader.refSize < 1 || reader.refSize > 8 {
+			return nil, errors.New(`invalid binary property list trailer`)
+		} else if count == 0 || top >= count || count > uint64(len(data)) {
+			return nil, errors.New(`invalid binary property list object count`)
+		} else if table < uint64(len(BPLIST_MAGIC)) || table+count*uint64(offsetSize) > uint64(len(data)-BPLIST_TRAILER_SIZE) {
+			return nil, errors.New(`binary property list offset table out of range`)
+		}
+
+		reader.offsets = make([]uint64, count)
+		for index := range reader.offsets {
+			reader.offsets[index] = bplistUint(data[table+uint64(index*offsetSize):], offsetSize)
+			if reader.offsets[index] >= table {
+				return nil, errors.New(`binary property list object offset out of range`)
+			}
+		}
+	}
+
+	return reader.object(top, 0)
+}
+
+func (r *bplistReader) object(index uint64, depth int) (interface{}, error) {
+	if index >= uint64(len(r.offsets)) {
+		return nil, fmt.Errorf(`binary property list reference %d out of range`, index)
+	} else if depth > BPLIST_MAX_DEPTH || r.visiting[index] {
+		return nil, errors.New(`binary property list nests too deeply or refers to itself`)
+	}
+
+	var position = r.offsets[index]
+	var marker = r.data[position]
+	var info = int(marker & 0x0f)
+	position++
+
+	switch marker & 0xf0 {
+	case bplistSimple:
+		switch marker {
+		case bplistNull:
+			return nil, nil
+		case bplistFalse:
+			return false, nil
+		case bplistTrue:
+			return true, nil
+		}
+
+	case bplistInteger:
+		if body, err := r.take(position, 1<<uint(info)); err != nil {
+			return nil, err
+		} else if len(body) == 16 {
+			return int64(binary.BigEndian.Uint64(body[8:])), nil //NOTE: 128 bit integers only carry 64 bit values
+		} else if len(body) == 8 {
+			return int64(binary.BigEndian.Uint64(body)), nil
+		} else {
+			return int64(bplistUint(body, len(body))), nil
+		}
+
+	case bplistReal:
+		if body, err := r.take(position, 1<<uint(info)); err != nil {
+			return nil, err
+		} else if len(body) == 4 {
+			return float64(math.Float32frombits(binary.BigEndian.Uint32(body))), nil
+		} else if len(body) == 8 {
+			return math.Float64frombits(binary.BigEndian.Uint64(body)), nil
+		}
+
+	case bplistDate:
+		if body, err := r.take(position, 8); err != nil {
+			return nil, err
+		} else {
+			return fromCoreDataTimestamp(math.Float64frombits(binary.BigEndian.Uint64(body))), nil
+		}
+
+	case bplistData, bplistASCII:
+		var length, start, err = r.length(position, info)
+		if err != nil {
+			return nil, err
+		}
+
+		var body []byte
+		if body, err = r.take(start, int(length)); err != nil {
+			return nil, err
+		} else if marker&0xf0 == bplistASCII {
+			return string(body), nil
+		}
+
+		return append([]byte{}, body...), nil
+
+	case bplistUTF16:
+		var length, start, err = r.length(position, info)
+		if err != nil {
+			return nil, err
+		}
+
+		var body []byte
+		if body, err = r.take(start, int(length)*2); err != nil {
+			return nil, err
+		}
+
+		var units = make([]uint16, length)
+		for unit := range units {
+			units[unit] = binary.BigEndian.Uint16(body[unit*2:])
+		}
+
+		return string(utf16.Decode(units)), nil
+
+	case bplistUID:
+		if body, err := r.take(position, info+1); err != nil {
+			return nil, err
+		} else {
+			return plistUID(bplistUint(body, len(body))), nil
+		}
+
+	case bplistArray, bplistDict:
+		var count, start, err = r.length(position, info)
+		if err != nil {
+			return nil, err
+		}
+
+		var references = int(count)
+		if marker&0xf0 == bplistDict {
+			references = references * 2
+		}
+
+		var body []byte
+		if body, err = r.take(start, references*r.refSize); err != nil {
+			return nil, err
+		}
+
+		r.visiting[index] = true
+		defer delete(r.visiting, index)
+
+		var values = make([]interface{}, references)
+		for reference := range values {
+			if values[reference], err = r.object(bplistUint(body[reference*r.refSize:], r.refSize), depth+1); err != nil {
+				return nil, err
+			}
+		}
+
+		if marker&0xf0 == bplistArray {
+			return values, nil
+		}
+
+		var dictionary = make(map[string]interface{}, count)
+		for key := 0; key < int(count); key++ {
+			if name, ok := values[key].(string); !ok {
+				return nil, errors.New(`binary property list dictionary key is not a string`)
+			} else {
+				dictionary[name] = values[int(count)+key]
+			}
+		}
+
+		return dictionary, nil
+	}
+
+	return nil, fmt.Errorf(`unsupported binary property list marker 0x%02x`, marker)
+}
+
+// length resolves an object's count, a nibble of 15 means the count follows as an integer object.
+func (r *bplistReader) length(position uint64, info int) (uint64, uint64, error) {
+	if info != 0x0f {
+		return uint64(info), position, nil
+	}
+
+	var marker, err = r.take(position, 1)
+	if err != nil {
+		return 0, 0, err
+	} else if marker[0]&0xf0 != bplistInteger {
+		return 0, 0, errors.New(`binary property list length is not an integer`)
+	}
+
+	var size = 1 << uint(marker[0]&0x0f)
+	var body []byte
+	if body, err = r.take(position+1, size); err != nil {
+		return 0, 0, err
+	}
+
+	return bplistUint(body, size), position + 1 + uint64(size), nil
+}
+
+func (r *bplistReader) take(position uint64, size int) ([]byte, error) {
+	if size < 0 || position+uint64(size) > uint64(len(r.data)) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	return r.data[position : position+uint64(size)], nil
+}
+
+// writeBinaryPlist encodes the value types readBinaryPlist produces, plus int and nil, with dictionary keys sorted so
+// the same document always yields the same bytes.
+func writeBinaryPlist(output io.Writer, value interface{}) error {
+	var writer = new(bplistWriter)
+	if _, err := writer.flatten(value, 0); err != nil {
+		return err
+	}
+
+	var refSize = bplistWidth(uint64(len(writer.objects)))
+	var body = append([]byte{}, BPLIST_MAGIC...)
+	var offsets = make([]uint64, len(writer.objects))
+
+	//-- Encode objects ----------
+	for index, object := range writer.objects {
+		offsets[index] = uint64(len(body))
+
+		switch typed := object.(type) {
+		case nil:
+			body = append(body, bplistNull)
+		case bool:
+			if typed {
+				body = append(body, bplistTrue)
+			} else {
+				body = append(body, bplistFalse)
+			}
+		case int64:
+			body = bplistAppendInteger(body, typed)
+		case float64:
+			body = append(body, bplistReal|3)
+			body = binary.BigEndian.AppendUint64(body, math.Float64bits(typed))
+		case time.Time:
+			body = append(body, bplistDate|3)
+			body = binary.BigEndian.AppendUint64(body, math.Float64bits(coreDataTimestamp(typed)))
+		case []byte:
+			body = bplistAppendLength(body, bplistData, len(typed))
+			body = append(body, typed...)
+		case string:
+			if bplistIsASCII(typed) {
+				body = bplistAppendLength(body, bplistASCII, len(typed))
+				body = append(body, typed...)
+			} else {
+				var units = utf16.Encode([]rune(typed))
+				body = bplistAppendLength(body, bplistUTF16, len(units))
+				for _, unit := range units {
+					body = binary.BigEndian.AppendUint16(body, unit)
+				}
+			}
+		case plistUID:
+			var size = bplistSize(uint64(typed))
+			body = append(body, bplistUID|byte(size-1))
+			body = bplistAppendUint(body, uint64(typed), size)
+		case []interface{}:
+			body = bplistAppendLength(body, bplistArray, len(typed))
+			for _, child := range writer.children[index] {
+				body = bplistAppendUint(body, child, refSize)
+			}
+		case map[string]interface{}:
+			body = bplistAppendLength(body, bplistDict, len(typed))
+			for _, child := range writer.children[index] {
+				body = bplistAppendUint(body, child, refSize)
+			}
+		}
+	}
+
+	//-- Append offset table and trailer ----------
+	{
+		var table = uint64(len(body))
+		var offsetSize = bplistWidth(table)
+		for _, offset := range offsets {
+			body = bplistAppendUint(body, offset, offsetSize)
+		}
+
+		body = append(body, 0, 0, 0, 0, 0, 0, byte(offsetSize), byte(refSize))
+		body = binary.BigEndian.AppendUint64(body, uint64(len(writer.objects)))
+		body = binary.BigEndian.AppendUint64(body, 0)
+		body = binary.BigEndian.AppendUint64(body, table)
+	}
+
+	_, err := output.Write(body)
+	return err
+}
+
+// flatten numbers every object depth first, a container's references are the indexes of its flattened children.
+func (w *bplistWriter) flatten(value interface{}, depth int) (uint64, error) {
+	if depth > BPLIST_MAX_DEPTH {
+		return 0, errors.New(`property list nests too deeply`)
+	}
+
+	if typed, ok := value.(int); ok {
+		value = int64(typed)
+	}
+
+	var index = uint64(len(w.objects))
+	w.objects = append(w.objects, value)
+	w.children = append(w.children, nil)
+
+	var children []uint64
+	switch typed := value.(type) {
+	case nil, bool, int64, float64, time.Time, []byte, string, plistUID:
+		return index, nil
+
+	case []interface{}:
+		for _, child := range typed {
+			if reference, err := w.flatten(child, depth+1); err != nil {
+				return 0, err
+			} else {
+				children = append(children, reference)
+			}
+		}
+
+	case map[string]interface{}:
+		var keys = make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		var values []uint64
+		for _, key := range keys {
+			if reference, err := w.flatten(key, depth+1); err != nil {
+				return 0, err
+			} else {
+				children = append(children, reference)
+			}
+
+			if reference, err := w.flatten(typed[key], depth+1); err != nil {
+				return 0, err
+			} else {
+				values = append(values, reference)
+			}
+		}
+
+		children = append(children, values...)
+
+	default:
+		return 0, fmt.Errorf(`unsupported property list type %T`, value)
+	}
+
+	w.children[index] = children
+	return index, nil
+}
+
+func bplistAppendInteger(body []byte, value int64) []byte {
+	var size = 8 //NOTE: Negative values are always stored as signed 64 bit integers
+	if value >= 0 {
+		size = bplistWidth(uint64(value))
+	}
+
+	var exponent = 0
+	for 1<<uint(exponent) < size {
+		exponent++
+	}
+
+	body = append(body, bplistInteger|byte(exponent))
+	return bplistAppendUint(body, uint64(value), size)
+}
+
+func bplistAppendLength(body []byte, marker byte, length int) []byte {
+	if length < 0x0f {
+		return append(body, marker|byte(length))
+	}
+
+	return bplistAppendInteger(append(body, marker|0x0f), int64(length))
+}
+
+func bplistAppendUint(body []byte, value uint64, size int) []byte {
+	for shift := (size - 1) * 8; shift >= 0; shift = shift - 8 {
+		body = append(body, byte(value>>uint(shift)))
+	}
+
+	return body
+}
+
+func bplistUint(data []byte, size int) uint64 {
+	var value uint64
+	for _, part := range data[:size] {
+		value = value<<8 | uint64(part)
+	}
+
+	return value
+}
+
+// bplistSize is the fewest bytes able to hold value, at least one.
+func bplistSize(value uint64) int {
+	var size = 1
+	for value > 0xff {
+		value = value >> 8
+		size++
+	}
+
+	return size
+}
+
+// bplistWidth rounds bplistSize up to the 1, 2, 4 or 8 byte integers Apple's writers produce.
+func bplistWidth(value uint64) int {
+	var size = 1
+	for size < bplistSize(value) {
+		size = size * 2
+	}
+
+	return size
+}
+
+func bplistIsASCII(value string) bool {
+	for index := 0; index < len(value); index++ {
+		if value[index] >= 0x80 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// coreDataTimestamp converts to Apple's reference date, seconds since 2001-01-01 UTC as a double.
+func coreDataTimestamp(moment time.Time) float64 {
+	return float64(moment.Sub(coreDataEpoch)) / float64(time.Second)
+}
+
+func fromCoreDataTimestamp(timestamp float64) time.Time {
+	return coreDataEpoch.Add(time.Duration(timestamp * float64(time.Second)))
+}
@@ -0,0 +1,129 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+func TestBinaryPlistRoundTrip(t *testing.T) {
+	var many = make([]interface{}, 20)
+	var wide = map[string]interface{}{}
+	for index := range many {
+		many[index] = int64(index)
+		wide[strings.Repeat(`k`, index+1)] = int64(-index)
+	}
+
+	var cases = []struct {
+		name  string
+		value interface{}
+	}{
+		{`zero`, int64(0)},
+		{`one byte integer`, int64(0xff)},
+		{`two byte integer`, int64(0x100)},
+		{`four byte integer`, int64(0x10000)},
+		{`eight byte integer`, int64(1 << 40)},
+		{`largest integer`, int64(math.MaxInt64)},
+		{`negative integer`, int64(-1)},
+		{`smallest integer`, int64(math.MinInt64)},
+		{`real`, 3.25},
+		{`true`, true},
+		{`false`, false},
+		{`empty string`, ``},
+		{`ascii string`, `Favourites`},
+		{`ascii string of 14`, strings.Repeat(`a`, 14)},
+		{`ascii string of 15`, strings.Repeat(`a`, 15)},
+		{`ascii string of 300`, strings.Repeat(`a`, 300)},
+		{`utf16 string`, `Lesezeichen-Menü`},
+		{`utf16 string of 15`, strings.Repeat(`é`, 15)},
+		{`utf16 surrogate pairs`, `bookmarks 📚📖`},
+		{`date`, time.Date(2019, 7, 4, 12, 30, 15, 0, time.UTC)},
+		{`date before reference`, time.Date(1999, 12, 31, 23, 59, 59, 0, time.UTC)},
+		{`empty data`, []byte{}},
+		{`data`, []byte{0, 1, 2, 0xfe, 0xff}},
+		{`data of 15`, bytes.Repeat([]byte{0xab}, 15)},
+		{`data of 70000`, bytes.Repeat([]byte{0xcd}, 70000)},
+		{`uid`, plistUID(300)},
+		{`empty array`, []interface{}{}},
+		{`array of 15`, many[:15]},
+		{`array of 20`, many},
+		{`empty dictionary`, map[string]interface{}{}},
+		{`dictionary of 20`, wide},
+		{`nested`, map[string]interface{}{
+			`Title`:           ``,
+			`WebBookmarkType`: `WebBookmarkTypeList`,
+			`Children`: []interface{}{
+				map[string]interface{}{`URLString`: `https://example.com/`, `URIDictionary`: map[string]interface{}{`title`: `Example ✓`}},
+				[]interface{}{[]interface{}{int64(-5), `deep`}},
+			},
+		}},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			var encoded bytes.Buffer
+			if err := writeBinaryPlist(&encoded, test.value); err != nil {
+				t.Fatalf(`writeBinaryPlist: %s`, err)
+			}
+
+			var decoded, err = readBinaryPlist(bytes.NewReader(encoded.Bytes()))
+			if err != nil {
+				t.Fatalf(`readBinaryPlist: %s`, err)
+			} else if !reflect.DeepEqual(decoded, test.value) {
+				t.Fatalf(`round trip gave %#v, want %#v`, decoded, test.value)
+			}
+
+			var again bytes.Buffer
+			if err := writeBinaryPlist(&again, decoded); err != nil {
+				t.Fatalf(`writeBinaryPlist again: %s`, err)
+			} else if !bytes.Equal(again.Bytes(), encoded.Bytes()) {
+				t.Fatalf(`rewriting the decoded value changed its bytes`)
+			}
+		})
+	}
+}
+
+func TestBinaryPlistWritesInt(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := writeBinaryPlist(&encoded, map[string]interface{}{`WebBookmarkFileVersion`: 1}); err != nil {
+		t.Fatalf(`writeBinaryPlist: %s`, err)
+	}
+
+	var decoded, err = readBinaryPlist(&encoded)
+	if err != nil {
+		t.Fatalf(`readBinaryPlist: %s`, err)
+	} else if want := map[string]interface{}{`WebBookmarkFileVersion`: int64(1)}; !reflect.DeepEqual(decoded, want) {
+		t.Fatalf(`round trip gave %#v, want %#v`, decoded, want)
+	}
+}
+
+func TestBinaryPlistRejectsMalformed(t *testing.T) {
+	var valid bytes.Buffer
+	if err := writeBinaryPlist(&valid, []interface{}{`a`, int64(1)}); err != nil {
+		t.Fatalf(`writeBinaryPlist: %s`, err)
+	}
+
+	var cases = []struct {
+		name string
+		data []byte
+	}{
+		{`empty`, nil},
+		{`wrong magic`, append([]byte(`bplist01`), valid.Bytes()[len(BPLIST_MAGIC):]...)},
+		{`truncated`, valid.Bytes()[:valid.Len()-1]},
+		{`xml`, []byte(`<?xml version="1.0" encoding="UTF-8"?><plist version="1.0"><dict/></plist>`)},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := readBinaryPlist(bytes.NewReader(test.data)); err == nil {
+				t.Fatalf(`readBinaryPlist accepted a malformed document`)
+			}
+		})
+	}
+}
@@ -3,25 +3,123 @@ package browsers
 
 //-- Imports -----------------------------------------------------------------------------------------------------------
 import (
-	"log"
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"io"
 	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/jinzhu/gorm"
 )
 
 //-- Constants ---------------------------------------------------------------------------------------------------------
 var webkitEpoch = time.Date(1601, 1, 1, 0, 0, 0, 0, time.UTC)
 
+// CHROME_HISTORY_MEMORY_BUDGET bounds the bytes of pending visits a Chrome profile holds before flushing them into its
+// open history transaction, zero holds everything until commit. Flushed visits are still rolled back with the
+// transaction. Only Chrome flushes early, the other browsers hold their history until commit whatever the budget.
+var CHROME_HISTORY_MEMORY_BUDGET = 0
+
+// WORKERS bounds how many profiles are loaded, purged or committed at once across every browser, each profile has its
+// own files and random source so they need no other coordination.
+var WORKERS = runtime.NumCPU()
+
+// SEED seeds the random source of each profile opened after it is set, mixed with the profile's browser and name so a
+// fixed seed generates the same items whatever order profiles are processed in. Zero seeds from the clock. Generated
+// timestamps stay relative to the time of the run, and salts and IVs never come from it.
+var SEED int64 = 0
+
+// PROGRESS is called, when set, as each profile finishes loading, purging or committing. Calls are never concurrent.
+var PROGRESS func(Progress)
+
+// STAGED_FILE_SUFFIX names the copy a file is written to during commit, beside the file it replaces.
+var STAGED_FILE_SUFFIX = `.staged`
+
+// picker chooses the profiles RandomProfile returns, Open reseeds it from SEED.
+var picker = newRandom(``, ``)
+
+// SQLITE_MAXIMUM_VARIABLES is the bound parameter limit of SQLite builds before 3.32, batched inserts stay below it.
+var SQLITE_MAXIMUM_VARIABLES = 999
+
+// Transition describes how a visit was made, values match Chromium's core page transitions.
+type Transition int
+
+const (
+	TransitionLink Transition = iota
+	TransitionTyped
+	TransitionAutoBookmark
+	TransitionAutoSubframe
+	TransitionManualSubframe
+	TransitionGenerated
+	TransitionAutoToplevel
+	TransitionFormSubmit
+	TransitionReload
+	TransitionKeyword
+	TransitionKeywordGenerated
+)
+
+var transitionNames = []string{
+	`LINK`,
+	`TYPED`,
+	`AUTO_BOOKMARK`,
+	`AUTO_SUBFRAME`,
+	`MANUAL_SUBFRAME`,
+	`GENERATED`,
+	`AUTO_TOPLEVEL`,
+	`FORM_SUBMIT`,
+	`RELOAD`,
+	`KEYWORD`,
+	`KEYWORD_GENERATED`,
+}
+
 //-- Structs -----------------------------------------------------------------------------------------------------------
+// Browser is a set of profiles sharing a lifecycle. Purge and the Add methods of its profiles only stage changes. Commit
+// writes each profile's files beside their targets, commits its databases one transaction apiece in a fixed order and
+// then moves the files into place. A profile that fails or is cancelled before its first database commits is left as
+// it was. SQLite cannot commit several databases at once, so a failure after that point leaves the databases already
+// committed in place and the staged files discarded.
 type Browser interface {
-	AddHistory(History) error
-	AddBookmark(Bookmark) error
-	AddCredential(Credential) error
+	Metadata() Metadata
+	Profiles() []Profile
 
-	open() error
-	load() error
-	close() error
-	purge() error
-	commit() error
+	Open(ctx context.Context) error
+	Load(ctx context.Context) error
+	Inspect() ([]Report, error)
+	Close() error
+	Purge(ctx context.Context) error
+	Commit(ctx context.Context) error
+}
+
+// Profile is a single profile of an opened browser, items added to it are written when the browser commits. An Add method
+// returns ErrUnsupported for items the browser keeps nowhere this package can write.
+type Profile interface {
+	Name() string
+	Path() string
+	Browser() string
+
+	AddHistory(context.Context, History) error
+	AddBookmark(context.Context, Bookmark) error
+	AddCredential(context.Context, Credential) error
+	AddFormEntry(context.Context, FormEntry) error
+	AddCookie(context.Context, Cookie) error
+	AddDownload(context.Context, Download) error
+	AddAddress(context.Context, Address) error
+	AddSearchEngine(context.Context, SearchEngine) error
+}
+
+// Metadata describes a browser implementation and, once opened, the installed version and profiles it found.
+type Metadata struct {
+	Name     string   `json:"name"`
+	Engine   string   `json:"engine"`
+	Version  string   `json:"version"`
+	Profiles []string `json:"profiles"`
 }
 
 type History struct {
@@ -29,6 +127,12 @@ type History struct {
 	URL         string
 	Visits      int
 	VisitWindow time.Duration
+	Timeline    []Visit
+}
+
+type Visit struct {
+	Time       time.Time
+	Transition Transition
 }
 
 type Credential struct {
@@ -38,65 +142,598 @@ type Credential struct {
 	CreateWindow time.Duration
 }
 
+type FormEntry struct {
+	Name         string
+	Value        string
+	Uses         int
+	CreateWindow time.Duration
+}
+
+// Cookie is stored host-only unless Host carries a leading dot, a zero Lifetime makes it a session cookie.
+type Cookie struct {
+	Host         string
+	Name         string
+	Value        string
+	Path         string
+	Secure       bool
+	HTTPOnly     bool
+	Lifetime     time.Duration
+	CreateWindow time.Duration
+}
+
+// Download is a completed download of URL saved as FileName in the user's downloads folder.
+type Download struct {
+	URL          string
+	FileName     string
+	Size         int64
+	CreateWindow time.Duration
+}
+
+type Address struct {
+	FirstName    string
+	LastName     string
+	Company      string
+	Street       string
+	City         string
+	State        string
+	Zip          string
+	Country      string
+	Email        string
+	Phone        string
+	Uses         int
+	CreateWindow time.Duration
+}
+
+type SearchEngine struct {
+	Name         string
+	Keyword      string
+	URL          string
+	Uses         int
+	CreateWindow time.Duration
+}
+
 type Bookmark struct {
 	Name         string
 	URL          string
+	Folder       []string
+	CreatedAt    time.Time
 	CreateWindow time.Duration
 }
 
+// Progress reports a profile finishing a lifecycle step, Done counts the profiles of the call finished so far.
+type Progress struct {
+	Op      string
+	Browser string
+	Profile string
+	Done    int
+	Total   int
+	Err     error
+}
+
+// pool bounds the profiles worked on at once and counts those finished, browsers processed together share one.
+type pool struct {
+	slots chan struct{}
+	lock  sync.Mutex
+	done  int
+	total int
+}
+
+type poolKey struct{}
+
+// random is a profile's own source of randomness, a profile is only ever worked on by one goroutine at a time.
+type random struct {
+	*rand.Rand
+}
+
+// transactions holds the open transaction of each database a profile writes to in the order they were begun, so purged
+// and added rows land together or not at all within each database.
+type transactions []transaction
+
+type transaction struct {
+	orm *gorm.DB
+	tx  *gorm.DB
+}
+
+// stagedFiles holds the files a profile rewrites or removes on commit, in the order they were staged. Each is written
+// beside its target and only moved into place once every database transaction has committed.
+type stagedFiles []stagedFile
+
+type stagedFile struct {
+	path      string
+	temporary string // Written copy to move over path, empty when path is removed
+	directory string // Missing parent of path, created when the copy moves into place
+}
+
 //-- Exported Functions ------------------------------------------------------------------------------------------------
-func Open() []Browser {
+func ParseTransition(name string) (Transition, error) {
+	for index, candidate := range transitionNames {
+		if strings.EqualFold(strings.TrimSpace(name), candidate) {
+			return Transition(index), nil
+		}
+	}
+
+	return TransitionLink, fmt.Errorf(`unknown transition '%s'`, name)
+}
+
+func (t Transition) String() string {
+	if int(t) < 0 || int(t) >= len(transitionNames) {
+		return fmt.Sprintf(`TRANSITION_%d`, int(t))
+	}
+
+	return transitionNames[t]
+}
+
+// RandomProfile picks a browser at random and then one of its profiles, spreading items the way a persona switching
+// between everything installed would.
+func RandomProfile(browsers ...Browser) (Profile, error) {
+	if len(browsers) < 1 {
+		return nil, fmt.Errorf(`no browsers detected, unable to act: %w`, ErrNotFound)
+	}
+
+	var profiles = browsers[picker.Intn(len(browsers))].Profiles()
+	if len(profiles) < 1 {
+		return nil, fmt.Errorf(`no profiles detected, unable to act: %w`, ErrNotFound)
+	}
+
+	return profiles[picker.Intn(len(profiles))], nil
+}
+
+// RandomProfileFunc is RandomProfile limited to the profiles keep accepts, browsers left without one are passed over. It
+// reports ErrUnsupported when keep turns every profile down.
+func RandomProfileFunc(keep func(Profile) bool, browsers ...Browser) (Profile, error) {
+	if len(browsers) < 1 {
+		return nil, fmt.Errorf(`no browsers detected, unable to act: %w`, ErrNotFound)
+	}
+
+	var candidates [][]Profile
+	for _, browser := range browsers {
+		var kept []Profile
+		for _, profile := range browser.Profiles() {
+			if keep(profile) {
+				kept = append(kept, profile)
+			}
+		}
+
+		if len(kept) > 0 {
+			candidates = append(candidates, kept)
+		}
+	}
+
+	if len(candidates) < 1 {
+		return nil, fmt.Errorf(`no profile takes the item: %w`, ErrUnsupported)
+	}
+
+	var profiles = candidates[picker.Intn(len(candidates))]
+	return profiles[picker.Intn(len(profiles))], nil
+}
+
+// FindProfile returns the profile with the given name, as shown in the browser's profile picker.
+func FindProfile(browser Browser, name string) (Profile, error) {
+	for _, profile := range browser.Profiles() {
+		if profile.Name() == name {
+			return profile, nil
+		}
+	}
+
+	return nil, &Error{Browser: browser.Metadata().Name, Profile: name, Op: `find`, Err: ErrNotFound}
+}
+
+// Open creates and opens every registered browser, keeping those with at least one usable profile. Failures are
+// returned together, browsers that are not installed report ErrNotFound. RandomProfile is reseeded from SEED.
+func Open(ctx context.Context) ([]Browser, error) {
 	var browsers []Browser
+	var errs Errors
 
-	{
-		var browser = new(chrome)
-		if err := browser.open(); err != nil {
-			log.Println(`error connecting to chrome data sets: `, err)
-		} else {
+	picker = newRandom(``, ``)
+
+	for _, registered := range registry {
+		if err := ctx.Err(); err != nil {
+			return browsers, errs.add(err).errorOrNil()
+		}
+
+		var browser = registered.factory()
+		if browser == nil {
+			continue
+		}
+
+		errs = errs.add(browser.Open(ctx))
+		if len(browser.Profiles()) > 0 {
 			browsers = append(browsers, browser)
 		}
 	}
 
-	return browsers
+	return browsers, errs.errorOrNil()
+}
+
+func Load(ctx context.Context, browsers []Browser) error {
+	return eachBrowser(ctx, browsers, Browser.Load)
 }
 
-func Load(browsers []Browser) {
+func Inspect(browsers []Browser) ([]Report, error) {
+	var reports []Report
+	var errs Errors
+
 	for _, browser := range browsers {
-		if err := browser.load(); err != nil {
-			log.Println(`error committing browser: `, err)
-		}
+		var items, err = browser.Inspect()
+		reports = append(reports, items...)
+		errs = errs.add(err)
 	}
+
+	return reports, errs.errorOrNil()
 }
 
-func Close(browsers []Browser) {
+// Close releases every browser, changes staged but not committed are discarded. The copies of a dry run are dropped
+// with them.
+func Close(browsers []Browser) error {
+	var errs Errors
 	for _, browser := range browsers {
-		if err := browser.close(); err != nil {
-			log.Println(`error closing browser: `, err)
-		}
+		errs = errs.add(browser.Close())
 	}
+
+	if DRY_RUN {
+		errs = errs.add(planned.release())
+	}
+
+	return errs.errorOrNil()
 }
 
-func Purge(browsers []Browser) {
+func Purge(ctx context.Context, browsers []Browser) error {
+	return eachBrowser(ctx, browsers, Browser.Purge)
+}
+
+// Commit writes the changes staged in every browser. A profile that fails or is cancelled before its first database
+// commits is rolled back, one that fails later keeps the databases already committed, see Browser.
+func Commit(ctx context.Context, browsers []Browser) error {
+	return eachBrowser(ctx, browsers, Browser.Commit)
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+// eachBrowser runs a lifecycle step on every browser at once under one shared pool, so no more than WORKERS profiles
+// are worked on together. Errors are gathered in browser order, the result does not depend on scheduling.
+func eachBrowser(ctx context.Context, browsers []Browser, step func(Browser, context.Context) error) error {
+	var total int
 	for _, browser := range browsers {
-		if err := browser.purge(); err != nil {
-			log.Println(`error purging browser: `, err)
+		total += len(browser.Profiles())
+	}
+	ctx = context.WithValue(ctx, poolKey{}, newPool(total))
+
+	var results = make([]error, len(browsers))
+	var group sync.WaitGroup
+	for index, browser := range browsers {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			results[index] = step(browser, ctx)
+		}()
+	}
+	group.Wait()
+
+	var errs Errors
+	for _, err := range results {
+		errs = errs.add(err)
+	}
+
+	return errs.errorOrNil()
+}
+
+// eachProfile runs work for every profile on the pool of the context, or a pool of its own when called on a single
+// browser. Errors are gathered in profile order and progress is reported as each one finishes.
+func eachProfile(ctx context.Context, operation string, profiles []Profile, work func(index int) error) Errors {
+	var shared, ok = ctx.Value(poolKey{}).(*pool)
+	if !ok {
+		shared = newPool(len(profiles))
+	}
+
+	var results = make([]error, len(profiles))
+	var group sync.WaitGroup
+	for index := range profiles {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+
+			shared.slots <- struct{}{}
+			results[index] = work(index)
+			<-shared.slots
+
+			shared.finished(operation, profiles[index], results[index])
+		}()
+	}
+	group.Wait()
+
+	var errs Errors
+	for _, err := range results {
+		errs = errs.add(err)
+	}
+
+	return errs
+}
+
+func newPool(total int) *pool {
+	return &pool{slots: make(chan struct{}, max(WORKERS, 1)), total: total}
+}
+
+// finished counts a profile as done and reports it to PROGRESS, holding the lock so calls never overlap.
+func (p *pool) finished(operation string, profile Profile, err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.done++
+	if PROGRESS != nil {
+		PROGRESS(Progress{Op: operation, Browser: profile.Browser(), Profile: profile.Name(), Done: p.done, Total: p.total, Err: err})
+	}
+}
+
+// newRandom seeds a source from SEED and the identity of the profile it belongs to.
+func newRandom(browser string, profile string) random {
+	var seed = SEED
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	var identity = fnv.New64a()
+	identity.Write([]byte(browser + "\x00" + profile))
+
+	return random{rand.New(rand.NewSource(seed ^ int64(identity.Sum64())))}
+}
+
+// begin returns the open transaction on a database, starting one the first time it is written to.
+func (t *transactions) begin(orm *gorm.DB) *gorm.DB {
+	for _, open := range *t {
+		if open.orm == orm {
+			return open.tx
 		}
 	}
+
+	var tx = orm.Begin()
+	*t = append(*t, transaction{orm: orm, tx: tx})
+
+	return tx
 }
 
-func Commit(browsers []Browser) {
-	for _, browser := range browsers {
-		if err := browser.commit(); err != nil {
-			log.Println(`error committing browser: `, err)
+// commit commits every open transaction in the order they were begun, those after a failure are rolled back. SQLite
+// commits each database on its own, so the databases committed before a failure stay committed.
+func (t *transactions) commit() error {
+	var failed error
+	for _, open := range *t {
+		if failed != nil {
+			open.tx.Rollback()
+		} else if result := open.tx.Commit(); result.Error != nil {
+			failed = result.Error
 		}
 	}
+
+	*t = nil
+	return failed
 }
 
-//-- Internal Functions ------------------------------------------------------------------------------------------------
-func randomWebKitTimestamp(duration time.Duration) int64 {
-	rand.Seed(time.Now().UnixNano())
+func (t *transactions) rollback() {
+	for _, open := range *t {
+		open.tx.Rollback()
+	}
+
+	*t = nil
+}
+
+// create opens the staged copy of a file for writing, replacing whatever was staged for it before. A copy whose
+// directory is missing waits in the closest parent that exists until commit creates the directory. During a dry run
+// the write is only measured.
+func (s *stagedFiles) create(path string, permissions os.FileMode) (io.WriteCloser, error) {
+	if DRY_RUN {
+		return createFile(path, permissions)
+	}
+
+	s.discard(path)
+
+	var staged = stagedFile{path: path, temporary: path + STAGED_FILE_SUFFIX}
+	if _, err := os.Stat(filepath.Dir(path)); os.IsNotExist(err) {
+		var parent = filepath.Dir(path)
+		for {
+			if _, err := os.Stat(parent); err == nil || parent == filepath.Dir(parent) {
+				break
+			}
+			parent = filepath.Dir(parent)
+		}
+
+		staged.directory = filepath.Dir(path)
+		staged.temporary = filepath.Join(parent, filepath.Base(path)+STAGED_FILE_SUFFIX)
+	}
+
+	var file, err = os.OpenFile(staged.temporary, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	*s = append(*s, staged)
+	return file, nil
+}
+
+// remove stages the removal of a file, replacing whatever was staged for it before. During a dry run the removal is
+// only recorded.
+func (s *stagedFiles) remove(path string) error {
+	if DRY_RUN {
+		return removeFile(path)
+	}
+
+	s.discard(path)
+	*s = append(*s, stagedFile{path: path})
+
+	return nil
+}
+
+// commit moves the staged files into place and removes those staged for removal, in the order they were staged. The
+// copies left after a failure are discarded.
+func (s *stagedFiles) commit() error {
+	defer s.rollback()
+
+	for len(*s) > 0 {
+		var file = (*s)[0]
+
+		if file.directory != `` {
+			if err := makeDirectory(file.directory); err != nil {
+				return fileError(file.directory, err)
+			}
+		}
+
+		var err error
+		if file.temporary == `` {
+			err = removeFile(file.path)
+		} else {
+			err = os.Rename(file.temporary, file.path)
+		}
+
+		if err != nil {
+			return fileError(file.path, err)
+		}
+
+		*s = (*s)[1:]
+	}
+
+	return nil
+}
 
+// rollback discards every staged copy, leaving the files they would have replaced as they are.
+func (s *stagedFiles) rollback() {
+	for _, file := range *s {
+		if file.temporary != `` {
+			os.Remove(file.temporary)
+		}
+	}
+
+	*s = nil
+}
+
+func (s *stagedFiles) discard(path string) {
+	var kept stagedFiles
+	for _, file := range *s {
+		if file.path != path {
+			kept = append(kept, file)
+		} else if file.temporary != `` {
+			os.Remove(file.temporary)
+		}
+	}
+
+	*s = kept
+}
+
+// insertRows writes rows with multi-row INSERT statements sized to the bound parameter limit. The statement for a full
+// batch is prepared once and reused, a large commit costs one round trip per batch rather than one per row.
+func insertRows(ctx context.Context, tx *gorm.DB, table string, columns []string, rows [][]interface{}) error {
+	var size = SQLITE_MAXIMUM_VARIABLES / len(columns)
+	var statement = func(count int) string {
+		var row = `(?` + strings.Repeat(`, ?`, len(columns)-1) + `)`
+		return fmt.Sprintf(`INSERT INTO %s (%s) VALUES %s`, table, strings.Join(columns, `, `), row+strings.Repeat(`, `+row, count-1))
+	}
+
+	var prepared *sql.Stmt
+	defer func() {
+		if prepared != nil {
+			prepared.Close()
+		}
+	}()
+
+	for start := 0; start < len(rows); start += size {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var batch = rows[start:min(start+size, len(rows))]
+		var values = make([]interface{}, 0, len(batch)*len(columns))
+		for _, row := range batch {
+			values = append(values, row...)
+		}
+
+		//-- Write the remainder with a one-off statement ----------
+		if len(batch) < size {
+			if _, err := tx.CommonDB().Exec(statement(len(batch)), values...); err != nil {
+				return err
+			}
+			continue
+		}
+
+		//-- Write full batches with the prepared statement ----------
+		if prepared == nil {
+			if stmt, err := tx.CommonDB().Prepare(statement(size)); err != nil {
+				return err
+			} else {
+				prepared = stmt
+			}
+		}
+
+		if _, err := prepared.Exec(values...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// execBatched runs a statement over values in batches below the bound parameter limit, the statement's `%s` is replaced
+// by the placeholders of each batch, as in `DELETE FROM visits WHERE id IN (%s)`.
+func execBatched(ctx context.Context, tx *gorm.DB, statement string, values []interface{}) error {
+	for start := 0; start < len(values); start += SQLITE_MAXIMUM_VARIABLES {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var batch = values[start:min(start+SQLITE_MAXIMUM_VARIABLES, len(values))]
+		var placeholders = `?` + strings.Repeat(`, ?`, len(batch)-1)
+
+		if _, err := tx.CommonDB().Exec(fmt.Sprintf(statement, placeholders), batch...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r random) webKitTimestamp(duration time.Duration) int64 {
 	var microMultiplier = int64(1000000)
-	var randomUnix = time.Now().Unix() - rand.Int63n(int64(duration.Seconds())) - webkitEpoch.Unix()
+	var randomUnix = time.Now().Unix() - r.Int63n(int64(duration.Seconds())) - webkitEpoch.Unix()
 	return randomUnix * microMultiplier
 }
+
+func webKitTimestamp(moment time.Time) int64 {
+	var microMultiplier = int64(1000000)
+	return (moment.Unix()-webkitEpoch.Unix())*microMultiplier + int64(moment.Nanosecond()/1000)
+}
+
+func (r random) unixTimestamp(duration time.Duration) int64 {
+	return time.Now().Unix() - r.Int63n(int64(duration.Seconds()))
+}
+
+func fromWebKitTimestamp(timestamp int64) time.Time {
+	var microMultiplier = int64(1000000)
+	return time.Unix(timestamp/microMultiplier+webkitEpoch.Unix(), (timestamp%microMultiplier)*1000)
+}
+
+func (r random) prTimestamp(duration time.Duration) int64 {
+	return r.unixTimestamp(duration)*1000000 + r.Int63n(1000000)
+}
+
+// prTimestamp converts to NSPR's PRTime, microseconds since the Unix epoch, used throughout Firefox's profile.
+func prTimestamp(moment time.Time) int64 {
+	return moment.UnixNano() / 1000
+}
+
+func fromPRTimestamp(timestamp int64) time.Time {
+	return time.Unix(timestamp/1000000, (timestamp%1000000)*1000)
+}
+
+func (r random) guid() string {
+	var bytes = make([]byte, 16)
+	r.Read(bytes)
+
+	bytes[6] = (bytes[6] & 0x0f) | 0x40
+	bytes[8] = (bytes[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf(`%x-%x-%x-%x-%x`, bytes[0:4], bytes[4:6], bytes[6:8], bytes[8:10], bytes[10:16])
+}
+
+// readVersionFile returns the trimmed contents of a file a browser records its version in, empty when unreadable.
+func readVersionFile(path string) string {
+	if data, err := os.ReadFile(path); err != nil {
+		return ``
+	} else {
+		return strings.TrimSpace(string(data))
+	}
+}
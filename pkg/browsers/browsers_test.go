@@ -0,0 +1,350 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+func TestStagedFiles(t *testing.T) {
+	var directory = t.TempDir()
+	var rewritten = filepath.Join(directory, `Bookmarks`)
+	var removed = filepath.Join(directory, `Current Session`)
+
+	var reset = func() {
+		for path, content := range map[string]string{rewritten: `old`, removed: `session`} {
+			if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+				t.Fatalf(`WriteFile: %s`, err)
+			}
+		}
+	}
+
+	var stage = func(staged *stagedFiles) {
+		for _, content := range []string{`first`, `second`} {
+			var file, err = staged.create(rewritten, 0600)
+			if err != nil {
+				t.Fatalf(`create: %s`, err)
+			} else if _, err := file.Write([]byte(content)); err != nil {
+				t.Fatalf(`Write: %s`, err)
+			} else if err := file.Close(); err != nil {
+				t.Fatalf(`Close: %s`, err)
+			}
+		}
+
+		if err := staged.remove(removed); err != nil {
+			t.Fatalf(`remove: %s`, err)
+		}
+	}
+
+	//-- Rolled back files are left as they were ----------
+	{
+		var staged stagedFiles
+		reset()
+		stage(&staged)
+		staged.rollback()
+
+		expectFile(t, rewritten, `old`)
+		expectFile(t, removed, `session`)
+		expectFile(t, rewritten+STAGED_FILE_SUFFIX, ``)
+	}
+
+	//-- Committed files are replaced by the last staged copy ----------
+	{
+		var staged stagedFiles
+		reset()
+		stage(&staged)
+
+		if err := staged.commit(); err != nil {
+			t.Fatalf(`commit: %s`, err)
+		} else if len(staged) != 0 {
+			t.Fatalf(`%d files still staged after commit`, len(staged))
+		}
+
+		expectFile(t, rewritten, `second`)
+		expectFile(t, removed, ``)
+		expectFile(t, rewritten+STAGED_FILE_SUFFIX, ``)
+	}
+
+	//-- A missing directory is only created when its files are committed ----------
+	{
+		var nested = filepath.Join(directory, `Sessions`, `Session_1`)
+
+		for _, commit := range []bool{false, true} {
+			var staged stagedFiles
+			if file, err := staged.create(nested, 0600); err != nil {
+				t.Fatalf(`create: %s`, err)
+			} else if _, err := file.Write([]byte(`tabs`)); err != nil {
+				t.Fatalf(`Write: %s`, err)
+			} else if err := file.Close(); err != nil {
+				t.Fatalf(`Close: %s`, err)
+			}
+
+			if !commit {
+				staged.rollback()
+				expectFile(t, filepath.Dir(nested), ``)
+			} else if err := staged.commit(); err != nil {
+				t.Fatalf(`commit: %s`, err)
+			} else {
+				expectFile(t, nested, `tabs`)
+			}
+			expectFile(t, filepath.Join(directory, filepath.Base(nested)+STAGED_FILE_SUFFIX), ``)
+		}
+	}
+}
+
+func TestInsertRows(t *testing.T) {
+	var cases = []struct {
+		columns int
+		rows    int
+	}{
+		{3, 0},
+		{3, 1},
+		{3, SQLITE_MAXIMUM_VARIABLES/3 - 1},
+		{3, SQLITE_MAXIMUM_VARIABLES / 3},
+		{3, SQLITE_MAXIMUM_VARIABLES/3 + 1},
+		{3, SQLITE_MAXIMUM_VARIABLES/3*2 + 1},
+		{3, SQLITE_MAXIMUM_VARIABLES + 1},
+		{2, SQLITE_MAXIMUM_VARIABLES / 2},
+		{2, SQLITE_MAXIMUM_VARIABLES/2 + 1},
+		{1, SQLITE_MAXIMUM_VARIABLES},
+		{1, SQLITE_MAXIMUM_VARIABLES + 1},
+		{8, 10000},
+	}
+
+	for _, test := range cases {
+		t.Run(fmt.Sprintf(`%d columns %d rows`, test.columns, test.rows), func(t *testing.T) {
+			var tx = openTestTable(t, test.columns)
+
+			var columns []string
+			var rows [][]interface{}
+			for column := 0; column < test.columns; column++ {
+				columns = append(columns, fmt.Sprintf(`c%d`, column))
+			}
+			for row := 0; row < test.rows; row++ {
+				var values = []interface{}{row + 1}
+				for column := 1; column < test.columns; column++ {
+					values = append(values, fmt.Sprintf(`%d.%d`, row, column))
+				}
+				rows = append(rows, values)
+			}
+
+			if err := insertRows(context.Background(), tx, `test`, columns, rows); err != nil {
+				t.Fatalf(`insertRows: %s`, err)
+			}
+
+			expectRows(t, tx, test.rows, test.rows*(test.rows+1)/2)
+		})
+	}
+}
+
+func TestExecBatched(t *testing.T) {
+	var cases = []int{0, 1, SQLITE_MAXIMUM_VARIABLES - 1, SQLITE_MAXIMUM_VARIABLES, SQLITE_MAXIMUM_VARIABLES + 1, SQLITE_MAXIMUM_VARIABLES * 2, 5000}
+	var stored = 6000
+
+	for _, deleted := range cases {
+		t.Run(fmt.Sprintf(`%d values`, deleted), func(t *testing.T) {
+			var tx = openTestTable(t, 1)
+
+			var rows [][]interface{}
+			for row := 1; row <= stored; row++ {
+				rows = append(rows, []interface{}{row})
+			}
+			if err := insertRows(context.Background(), tx, `test`, []string{`c0`}, rows); err != nil {
+				t.Fatalf(`insertRows: %s`, err)
+			}
+
+			var values []interface{}
+			for row := 1; row <= deleted; row++ {
+				values = append(values, row)
+			}
+			if err := execBatched(context.Background(), tx, `DELETE FROM test WHERE c0 IN (%s)`, values); err != nil {
+				t.Fatalf(`execBatched: %s`, err)
+			}
+
+			expectRows(t, tx, stored-deleted, stored*(stored+1)/2-deleted*(deleted+1)/2)
+		})
+	}
+}
+
+func TestBatchesStopWhenCancelled(t *testing.T) {
+	var tx = openTestTable(t, 1)
+	var ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+
+	if err := insertRows(ctx, tx, `test`, []string{`c0`}, [][]interface{}{{1}}); err != context.Canceled {
+		t.Errorf(`insertRows returned %v, want %v`, err, context.Canceled)
+	} else if err := execBatched(ctx, tx, `DELETE FROM test WHERE c0 IN (%s)`, []interface{}{1}); err != context.Canceled {
+		t.Errorf(`execBatched returned %v, want %v`, err, context.Canceled)
+	}
+}
+
+// TestSeededCommitRepeats commits the same items twice under one seed and expects identical rows, time columns drawn
+// against the clock aside.
+func TestSeededCommitRepeats(t *testing.T) {
+	var ctx = context.Background()
+	var day = time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	var previous = SEED
+	SEED = 7
+	t.Cleanup(func() { SEED = previous })
+
+	//-- Chrome segments with usage on several days ----------
+	{
+		var commit = func() []string {
+			var profile = openChromeProfile(t)
+			for index, host := range []string{`news.example.com`, `mail.example.org`} {
+				var item = History{Name: host, URL: `https://` + host + `/`}
+				for offset := 0; offset < 6; offset++ {
+					item.Timeline = append(item.Timeline, Visit{Time: day.AddDate(0, 0, offset*(index+1)), Transition: TransitionTyped})
+				}
+
+				if err := profile.AddHistory(ctx, item); err != nil {
+					t.Fatalf(`AddHistory: %s`, err)
+				}
+			}
+
+			if err := profile.commit(ctx); err != nil {
+				t.Fatalf(`commit: %s`, err)
+			}
+
+			return tableRows(t, profile.historyDatabase, `urls`, `visits`, `segments`, `segment_usage`)
+		}
+
+		expectSameRows(t, commit(), commit())
+	}
+
+	//-- Firefox download annotations ----------
+	{
+		var commit = func() []string {
+			var profile = openFirefoxProfile(t)
+			if err := profile.load(); err != nil {
+				t.Fatalf(`load: %s`, err)
+			}
+
+			for index := 0; index < 6; index++ {
+				var item = Download{URL: fmt.Sprintf(`https://downloads.example.com/file%d.zip`, index), FileName: fmt.Sprintf(`file%d.zip`, index), Size: 1 << 20, CreateWindow: time.Hour}
+				if err := profile.AddDownload(ctx, item); err != nil {
+					t.Fatalf(`AddDownload: %s`, err)
+				}
+			}
+
+			if err := profile.commit(ctx); err != nil {
+				t.Fatalf(`commit: %s`, err)
+			}
+
+			return tableRows(t, profile.placesDatabase, `(SELECT id, url, guid, url_hash FROM moz_places)`, `moz_anno_attributes`,
+				`(SELECT id, place_id, anno_attribute_id FROM moz_annos)`)
+		}
+
+		expectSameRows(t, commit(), commit())
+	}
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+// openTestTable opens a transaction on a new database holding a table named test with the given number of columns, the
+// first of them an integer key.
+func openTestTable(t *testing.T, columns int) *gorm.DB {
+	t.Helper()
+
+	var orm, err = gorm.Open(`sqlite3`, `file:`+filepath.Join(t.TempDir(), `test.db`))
+	if err != nil {
+		t.Fatalf(`Open: %s`, err)
+	}
+	t.Cleanup(func() { orm.Close() })
+
+	var definition = `c0 INTEGER PRIMARY KEY`
+	for column := 1; column < columns; column++ {
+		definition += fmt.Sprintf(`, c%d TEXT NOT NULL`, column)
+	}
+	if result := orm.Exec(`CREATE TABLE test (` + definition + `)`); result.Error != nil {
+		t.Fatalf(`CREATE TABLE: %s`, result.Error)
+	}
+
+	var tx = orm.Begin()
+	t.Cleanup(func() { tx.Rollback() })
+
+	return tx
+}
+
+// expectRows fails the test unless the test table holds count rows whose keys add up to sum, so a batch written twice
+// or skipped is caught as well as a short count.
+func expectRows(t *testing.T, tx *gorm.DB, count int, sum int) {
+	t.Helper()
+
+	var stored, total int
+	if err := tx.Raw(`SELECT COUNT(*), COALESCE(SUM(c0), 0) FROM test`).Row().Scan(&stored, &total); err != nil {
+		t.Fatalf(`query: %s`, err)
+	} else if stored != count || total != sum {
+		t.Errorf(`table holds %d rows summing to %d, want %d summing to %d`, stored, total, count, sum)
+	}
+}
+
+// expectFile fails the test unless the file holds content, an empty content expects the file to be missing.
+func expectFile(t *testing.T, path string, content string) {
+	t.Helper()
+
+	var data, err = os.ReadFile(path)
+	switch {
+	case content == `` && !os.IsNotExist(err):
+		t.Errorf(`%s exists, expected it to be missing`, filepath.Base(path))
+	case content != `` && err != nil:
+		t.Errorf(`%s: %s`, filepath.Base(path), err)
+	case content != `` && string(data) != content:
+		t.Errorf(`%s holds '%s', expected '%s'`, filepath.Base(path), data, content)
+	}
+}
+
+// tableRows renders every row of the given tables or subqueries ordered by their first column, one line per row.
+func tableRows(t *testing.T, orm *gorm.DB, sources ...string) []string {
+	t.Helper()
+
+	var lines []string
+	for _, source := range sources {
+		var rows, err = orm.Raw(`SELECT * FROM ` + source + ` ORDER BY 1`).Rows()
+		if err != nil {
+			t.Fatalf(`%s: %s`, source, err)
+		}
+
+		var columns, _ = rows.Columns()
+		for rows.Next() {
+			var values = make([]interface{}, len(columns))
+			var pointers = make([]interface{}, len(columns))
+			for index := range values {
+				pointers[index] = &values[index]
+			}
+
+			if err := rows.Scan(pointers...); err != nil {
+				rows.Close()
+				t.Fatalf(`%s: %s`, source, err)
+			}
+
+			lines = append(lines, fmt.Sprintf(`%s %v`, source, values))
+		}
+		rows.Close()
+	}
+
+	return lines
+}
+
+// expectSameRows fails the test at the first row two commits wrote differently.
+func expectSameRows(t *testing.T, first []string, second []string) {
+	t.Helper()
+
+	if len(first) != len(second) {
+		t.Fatalf(`first commit wrote %d rows, the second %d`, len(first), len(second))
+	}
+
+	for index := range first {
+		if first[index] != second[index] {
+			t.Fatalf("row %d differs:\n%s\n%s", index, first[index], second[index])
+		}
+	}
+}
@@ -3,14 +3,25 @@ package browsers
 
 //-- Imports -----------------------------------------------------------------------------------------------------------
 import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/md5"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"encoding/hex"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"log"
-	"math/rand"
+	"hash"
+	"io"
+	"net/url"
 	"os"
 	"runtime"
+	"sort"
+	"strconv"
 	"time"
+	"unicode/utf16"
+	"unsafe"
 
 	"github.com/jinzhu/gorm"
 	_ "github.com/mattn/go-sqlite3"
@@ -18,8 +29,28 @@ import (
 
 //-- Constants ---------------------------------------------------------------------------------------------------------
 var (
-	CHROME_STATE_FILE        = `Local State`
-	CHROME_BOOKMARK_BUFFER   = 1000
+	CHROME_STATE_FILE      = `Local State`
+	CHROME_VERSION_FILE    = `Last Version`
+	CHROME_HISTORY_FILE    = `History`
+	CHROME_LOGIN_DATA_FILE = `Login Data`
+	CHROME_BOOKMARKS_FILE  = `Bookmarks`
+	CHROME_BOOKMARK_BUFFER = 1000
+	CHROME_BOOKMARK_ROOTS  = []string{`bookmark_bar`, `other`, `synced`}
+
+	CHROME_TRANSITION_CHAIN = 0x30000000 // CHAIN_START | CHAIN_END, every synthesized visit is a single step redirect chain
+	CHROME_TYPED_ONE_IN_X   = 8
+	CHROME_CREDENTIAL_USES  = 20
+
+	CHROME_BOOKMARK_BAR_GUID     = `0bc5d13f-2cba-5d74-951f-3f233fe6c908`
+	CHROME_OTHER_BOOKMARKS_GUID  = `82b081ec-3dd3-529c-8475-ab6c344590dd`
+	CHROME_MOBILE_BOOKMARKS_GUID = `4cf2e351-0e85-532b-bb37-df045d8f8d0f`
+
+	CHROME_LINUX_PASSWORD = `peanuts`
+	CHROME_LINUX_SALT     = `saltysalt`
+
+	chromeHistoryURLSize   = int(unsafe.Sizeof(chromeHistoryURL{}))
+	chromeHistoryVisitSize = int(unsafe.Sizeof(chromeHistoryVisit{}) + unsafe.Sizeof(&chromeHistoryVisit{}))
+
 	CHROME_LINUX_DATA_PATH   = fmt.Sprintf(`%s/.config/google-chrome/`, os.Getenv(`HOME`))
 	CHROME_DARWIN_DATA_PATH  = fmt.Sprintf(`%s/Library/Application Support/Google/Chrome/`, os.Getenv(`HOME`))
 	CHROME_WINDOWS_DATA_PATH = fmt.Sprintf(`%s\Google\Chrome\User Data\`, os.Getenv(`LOCALAPPDATA`))
@@ -43,14 +74,36 @@ type chromeState struct {
 }
 
 type chromeProfile struct {
+	name     string
 	dataPath string
 
 	historyDatabase    *gorm.DB
 	credentialDatabase *gorm.DB
+	webDatabase        *gorm.DB
+	addressTable       string
+	topSitesDatabase   *gorm.DB
+	shortcutDatabase   *gorm.DB
+	faviconDatabase    *gorm.DB
+	cookieDatabase     *gorm.DB
+	cookieFile         string
 	bookmarkFile       *os.File
+	transactions       transactions
+	staged             stagedFiles
+	random             random
+	purging            bool
+	purged             bool
+	purgeFilters       []PurgeFilter
+	stagedFilters      int
+
+	historyIndex   map[string]*chromeHistoryURL
+	pendingHistory int
 
 	historyItems     []*chromeHistoryURL
 	credentialItems  []*chromeCredential
+	formItems        []*chromeAutofill
+	cookieItems      []*chromeCookie
+	addressItems     []*chromeAddress
+	keywordItems     []*chromeKeyword
 	bookmarkManifest *chromeBookmarksManifest
 }
 
@@ -65,7 +118,10 @@ type chromeHistoryURL struct {
 	LastVisitTime int `gorm:"not null"`
 
 	//-- Relations ----------
-	Visits []*chromeHistoryVisit `gorm:"foreignkey:URL"`
+	Visits    []*chromeHistoryVisit `gorm:"foreignkey:URL"`
+	stored    bool
+	dirty     bool
+	lastTyped int
 
 	//-- System Variables ----------
 	TypedCount int `gorm:"default:0;not null"`
@@ -129,6 +185,9 @@ type chromeCredential struct {
 	SkipZeroClick          int
 	GenerationUploadStatus int
 	PossibleUsernamePairs  []byte
+
+	//-- Relations ----------
+	stored bool
 }
 
 func (chromeCredential) TableName() string {
@@ -137,57 +196,133 @@ func (chromeCredential) TableName() string {
 
 type chromeBookmark struct {
 	ID   string `json:"id"`
+	GUID string `json:"guid,omitempty"`
 	Name string `json:"name"`
 	Type string `json:"type"`
-	URL  string `json:"url"`
+	URL  string `json:"url,omitempty"`
 
 	CreatedAt string `json:"date_added"`
+	UpdatedAt string `json:"date_modified,omitempty"`
+
+	MetaInfo map[string]string `json:"meta_info,omitempty"`
+
+	Children []*chromeBookmark `json:"children,omitempty"`
 }
 
-type chromeBookmarkSet struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
-	Type string `json:"type"`
+// MarshalJSON always emits `children` for folders, Chrome refuses to decode a folder without the key even when empty.
+func (c *chromeBookmark) MarshalJSON() ([]byte, error) {
+	type node chromeBookmark
 
-	CreatedAt string `json:"date_added"`
-	UpdatedAt string `json:"date_modified"`
+	if c.Type != `folder` {
+		return json.Marshal((*node)(c))
+	}
+
+	var children = c.Children
+	if children == nil {
+		children = []*chromeBookmark{}
+	}
+
+	return json.Marshal(struct {
+		*node
+		Children []*chromeBookmark `json:"children"`
+	}{(*node)(c), children})
+}
+
+func (c *chromeBookmark) checksum(digest hash.Hash) {
+	if c.Type == `folder` {
+		checksumFolder(digest, c.ID, c.Name)
+
+		for _, child := range c.Children {
+			child.checksum(digest)
+		}
+	} else {
+		checksumURL(digest, c.ID, c.Name, c.URL)
+	}
+}
+
+func (c *chromeBookmark) report() *BookmarkNode {
+	var node = &BookmarkNode{Name: c.Name, URL: c.URL}
 
-	Bookmarks []*chromeBookmark `json:"children"`
+	for _, child := range c.Children {
+		node.Children = append(node.Children, child.report())
+	}
+
+	return node
+}
+
+func (c *chromeBookmark) count() int {
+	var count = 0
+
+	for _, child := range c.Children {
+		if child.Type == `folder` {
+			count = count + child.count()
+		} else {
+			count = count + 1
+		}
+	}
+
+	return count
+}
+
+func (c *chromeBookmark) maximumID() int {
+	var maximum, _ = strconv.Atoi(c.ID)
+
+	for _, child := range c.Children {
+		if id := child.maximumID(); id > maximum {
+			maximum = id
+		}
+	}
+
+	return maximum
+}
+
+func (c *chromeBookmark) folder(name string) *chromeBookmark {
+	for _, child := range c.Children {
+		if child.Type == `folder` && child.Name == name {
+			return child
+		}
+	}
+
+	return nil
 }
 
 type chromeBookmarksManifest struct {
-	//Checksum string `json:"checksum"`
+	Checksum string `json:"checksum"`
 
-	Folders map[string]*chromeBookmarkSet `json:"roots"`
+	Folders map[string]*chromeBookmark `json:"roots"`
 
-	Version int `json:"version"`
+	SyncMetadata string `json:"sync_metadata,omitempty"`
+	Version      int    `json:"version"`
 }
 
-func (c *chromeBookmarksManifest) init() *chromeBookmarksManifest {
-	c.Folders = map[string]*chromeBookmarkSet{
+func (c *chromeBookmarksManifest) init(random random) *chromeBookmarksManifest {
+	c.Folders = map[string]*chromeBookmark{
 		`bookmark_bar`: {
 			ID:        `1`,
+			GUID:      CHROME_BOOKMARK_BAR_GUID,
 			Name:      `Bookmarks bar`,
 			Type:      `folder`,
-			CreatedAt: fmt.Sprintf(`%d`, randomWebKitTimestamp(time.Duration(24*time.Hour))),
-			UpdatedAt: fmt.Sprintf(`%d`, randomWebKitTimestamp(time.Duration(1*time.Hour))),
-			Bookmarks: []*chromeBookmark{},
+			CreatedAt: fmt.Sprintf(`%d`, random.webKitTimestamp(time.Duration(24*time.Hour))),
+			UpdatedAt: fmt.Sprintf(`%d`, random.webKitTimestamp(time.Duration(1*time.Hour))),
+			Children:  []*chromeBookmark{},
 		},
 		`other`: {
 			ID:        `2`,
+			GUID:      CHROME_OTHER_BOOKMARKS_GUID,
 			Name:      `Other Bookmarks`,
 			Type:      `folder`,
-			CreatedAt: fmt.Sprintf(`%d`, randomWebKitTimestamp(time.Duration(24*time.Hour))),
-			UpdatedAt: fmt.Sprintf(`%d`, randomWebKitTimestamp(time.Duration(1*time.Hour))),
-			Bookmarks: []*chromeBookmark{},
+			CreatedAt: fmt.Sprintf(`%d`, random.webKitTimestamp(time.Duration(24*time.Hour))),
+			UpdatedAt: fmt.Sprintf(`%d`, random.webKitTimestamp(time.Duration(1*time.Hour))),
+			Children:  []*chromeBookmark{},
 		},
 		`synced`: {
 			ID:        `3`,
+			GUID:      CHROME_MOBILE_BOOKMARKS_GUID,
 			Name:      `Mobile Bookmarks`,
 			Type:      `folder`,
-			CreatedAt: fmt.Sprintf(`%d`, randomWebKitTimestamp(time.Duration(24*time.Hour))),
-			UpdatedAt: fmt.Sprintf(`%d`, randomWebKitTimestamp(time.Duration(1*time.Hour))),
-			Bookmarks: []*chromeBookmark{},
+			CreatedAt: fmt.Sprintf(`%d`, random.webKitTimestamp(time.Duration(24*time.Hour))),
+			UpdatedAt: fmt.Sprintf(`%d`, random.webKitTimestamp(time.Duration(1*time.Hour))),
+			Children:  []*chromeBookmark{},
 		},
 	}
 
@@ -196,124 +331,299 @@ func (c *chromeBookmarksManifest) init() *chromeBookmarksManifest {
 	return c
 }
 
+func (c *chromeBookmarksManifest) fill(random random) *chromeBookmarksManifest {
+	var defaults = new(chromeBookmarksManifest).init(random)
+
+	if c.Folders == nil {
+		c.Folders = map[string]*chromeBookmark{}
+	}
+
+	for name, set := range defaults.Folders {
+		if _, ok := c.Folders[name]; !ok {
+			c.Folders[name] = set
+		}
+	}
+
+	if c.Version == 0 {
+		c.Version = defaults.Version
+	}
+
+	return c
+}
+
+// checksum mirrors Chromium's BookmarkCodec: an MD5 digest over the id, UTF-16 title and type (plus url for url nodes)
+// of every node, walking the roots in their encoded order.
+func (c *chromeBookmarksManifest) checksum() string {
+	var digest = md5.New()
+
+	for _, name := range CHROME_BOOKMARK_ROOTS {
+		if set, ok := c.Folders[name]; ok {
+			set.checksum(digest)
+		}
+	}
+
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
 func (c *chromeBookmarksManifest) bookmarkCount() int {
 	var count = 0
 
 	for _, set := range c.Folders {
-		count = count + len(set.Bookmarks)
+		count = count + set.count()
 	}
 
 	return count
 }
 
+func (c *chromeBookmarksManifest) nextID() string {
+	var maximum = CHROME_BOOKMARK_BUFFER
+
+	for _, set := range c.Folders {
+		if id := set.maximumID(); id >= maximum {
+			maximum = id + 1
+		}
+	}
+
+	return strconv.Itoa(maximum)
+}
+
 //-- Exported Functions ------------------------------------------------------------------------------------------------
-func (c *chrome) AddHistory(item History) error {
-	//-- Select random profile ----------
-	var profile *chromeProfile
+func (c *chrome) Metadata() Metadata {
+	var metadata = Metadata{Name: `Chrome`, Engine: `Blink`, Version: readVersionFile(c.dataPath + CHROME_VERSION_FILE)}
+	for _, profile := range c.profiles {
+		metadata.Profiles = append(metadata.Profiles, profile.name)
+	}
+
+	return metadata
+}
+
+func (c *chrome) Profiles() []Profile {
+	var profiles []Profile
+	for _, profile := range c.profiles {
+		profiles = append(profiles, profile)
+	}
+
+	return profiles
+}
+
+func (c *chromeProfile) Name() string {
+	return c.name
+}
+
+func (c *chromeProfile) Path() string {
+	return c.dataPath
+}
+
+func (c *chromeProfile) Browser() string {
+	return `Chrome`
+}
+
+func (c *chromeProfile) AddHistory(ctx context.Context, item History) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	//-- Find or create URL row, Chrome keeps a single row per url ----------
+	var entry *chromeHistoryURL
 	{
-		if len(c.profiles) < 1 {
-			return errors.New(`no profiles detected, unable to act`)
+		if c.historyIndex == nil {
+			c.historyIndex = map[string]*chromeHistoryURL{}
+		}
+
+		if existing, ok := c.historyIndex[item.URL]; ok {
+			entry = existing
+			entry.dirty = true
+
+			if item.Name != `` {
+				entry.Title = item.Name
+			}
 		} else {
-			profile = c.profiles[rand.Intn(len(c.profiles))]
+			entry = &chromeHistoryURL{URL: item.URL, Title: item.Name}
+			c.historyItems = append(c.historyItems, entry)
+			c.historyIndex[item.URL] = entry
+			c.pendingHistory += chromeHistoryURLSize + len(entry.URL) + len(entry.Title)
 		}
 	}
 
-	//-- Create history entry ----------
+	//-- Add visits ----------
 	{
-		var newEntry = &chromeHistoryURL{
-			URL:           item.URL,
-			Title:         item.Name,
-			VisitCount:    item.Visits,
-			LastVisitTime: int(randomWebKitTimestamp(item.VisitWindow)),
-		}
+		var visits []*chromeHistoryVisit
 
-		//-- Add individual visit data ----------
-		for i := 0; i < item.Visits; i++ {
-			var visit = &chromeHistoryVisit{
-				URL:       int(newEntry.ID),
-				VisitTime: int(randomWebKitTimestamp(item.VisitWindow)),
+		if len(item.Timeline) > 0 {
+			//-- Replay recorded visits ----------
+			for _, recorded := range item.Timeline {
+				var visit = &chromeHistoryVisit{
+					VisitTime: int(webKitTimestamp(recorded.Time)),
 
-				Transition:    805306374,
-				VisitDuration: 60000000,
+					Transition:    int(recorded.Transition) | CHROME_TRANSITION_CHAIN,
+					VisitDuration: 60000000,
+				}
+
+				if visit.VisitTime > entry.LastVisitTime {
+					entry.LastVisitTime = visit.VisitTime
+				}
+
+				visits = append(visits, visit)
+			}
+		} else {
+			if last := int(c.random.webKitTimestamp(item.VisitWindow)); last > entry.LastVisitTime {
+				entry.LastVisitTime = last
 			}
 
-			newEntry.Visits = append(newEntry.Visits, visit)
+			//-- Add individual visit data ----------
+			for i := 0; i < item.Visits; i++ {
+				var visit = &chromeHistoryVisit{
+					VisitTime: int(c.random.webKitTimestamp(item.VisitWindow)),
+
+					Transition:    int(TransitionAutoToplevel) | CHROME_TRANSITION_CHAIN,
+					VisitDuration: 60000000,
+				}
+
+				if c.random.Intn(CHROME_TYPED_ONE_IN_X) == 0 {
+					visit.Transition = int(TransitionTyped) | CHROME_TRANSITION_CHAIN
+				}
 
+				visits = append(visits, visit)
+			}
+		}
+
+		for _, visit := range visits {
+			if Transition(visit.Transition&0xff) == TransitionTyped {
+				entry.TypedCount++
+				if visit.VisitTime > entry.lastTyped {
+					entry.lastTyped = visit.VisitTime
+				}
+			}
 		}
 
-		profile.historyItems = append(profile.historyItems, newEntry)
+		entry.VisitCount = entry.VisitCount + len(visits)
+		entry.Visits = append(entry.Visits, visits...)
+		c.pendingHistory += len(visits) * chromeHistoryVisitSize
+	}
+
+	//-- Flush pending visits once over budget ----------
+	{
+		if CHROME_HISTORY_MEMORY_BUDGET > 0 && c.pendingHistory > CHROME_HISTORY_MEMORY_BUDGET {
+			if err := c.flushHistory(ctx); err != nil {
+				return fileError(c.dataPath+CHROME_HISTORY_FILE, err)
+			}
+		}
 	}
 
 	//-- Return ---------
 	return nil
 }
 
-func (c *chrome) AddBookmark(item Bookmark) error {
-	//-- Select random profile ----------
-	var profile *chromeProfile
-	{
-		if len(c.profiles) < 1 {
-			return errors.New(`no profiles detected, unable to act`)
-		} else {
-			profile = c.profiles[rand.Intn(len(c.profiles))]
-		}
+func (c *chromeProfile) AddBookmark(ctx context.Context, item Bookmark) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
 	//-- Create new bookmark item ----------
 	var newEntry = &chromeBookmark{
-		ID:        fmt.Sprintf(`%d`, len(profile.bookmarkManifest.Folders)+profile.bookmarkManifest.bookmarkCount()+CHROME_BOOKMARK_BUFFER),
-		Name:      item.Name,
-		Type:      `url`,
-		URL:       item.URL,
-		CreatedAt: fmt.Sprintf(`%d`, randomWebKitTimestamp(item.CreateWindow)),
+		GUID: c.random.guid(),
+		Name: item.Name,
+		Type: `url`,
+		URL:  item.URL,
 	}
 
-	//-- Insert into random position ----------
+	if item.CreatedAt.IsZero() {
+		newEntry.CreatedAt = fmt.Sprintf(`%d`, c.random.webKitTimestamp(item.CreateWindow))
+	} else {
+		newEntry.CreatedAt = fmt.Sprintf(`%d`, webKitTimestamp(item.CreatedAt))
+	}
+
+	//-- Select root, foldered bookmarks are kept together on the bar ----------
+	var parent *chromeBookmark
+	{
+		if len(item.Folder) > 0 {
+			parent = c.bookmarkManifest.Folders[`bookmark_bar`]
+		} else {
+			var roots = []string{`bookmark_bar`, `other`}
+			parent = c.bookmarkManifest.Folders[roots[c.random.Intn(len(roots))]]
+		}
+	}
+
+	//-- Walk or create sub-folders ----------
 	{
-		var bookmarkSets []string
-		for set := range profile.bookmarkManifest.Folders {
-			bookmarkSets = append(bookmarkSets, set)
+		for _, name := range item.Folder {
+			var folder = parent.folder(name)
+			if folder == nil {
+				folder = &chromeBookmark{
+					ID:        c.bookmarkManifest.nextID(),
+					GUID:      c.random.guid(),
+					Name:      name,
+					Type:      `folder`,
+					CreatedAt: newEntry.CreatedAt,
+					UpdatedAt: newEntry.CreatedAt,
+					Children:  []*chromeBookmark{},
+				}
+
+				parent.Children = append(parent.Children, folder)
+			} else if webKitBefore(newEntry.CreatedAt, folder.CreatedAt) {
+				folder.CreatedAt = newEntry.CreatedAt
+			}
+
+			parent = folder
 		}
+	}
 
-		var randomSet = bookmarkSets[rand.Intn(len(bookmarkSets)-1)]
-		profile.bookmarkManifest.Folders[randomSet].Bookmarks = append(profile.bookmarkManifest.Folders[randomSet].Bookmarks, newEntry)
+	//-- Insert and touch parent ----------
+	{
+		newEntry.ID = c.bookmarkManifest.nextID()
+		parent.Children = append(parent.Children, newEntry)
+
+		if webKitBefore(parent.UpdatedAt, newEntry.CreatedAt) {
+			parent.UpdatedAt = newEntry.CreatedAt
+		}
 	}
 
 	//-- Return ---------
 	return nil
 }
 
-func (c *chrome) AddCredential(item Credential) error {
-	//-- Select random profile ----------
-	//var profile *chromeProfile
-	//{
-	//	if len(c.profiles) < 1 {
-	//		return errors.New(`no profiles detected, unable to act`)
-	//	} else {
-	//		var names []string
-	//		for set := range c.profiles {
-	//			names = append(names, set)
-	//		}
-	//
-	//		var random = names[rand.Intn(len(names)-1)]
-	//		profile =  c.profiles[random]
-	//	}
-	//}
-
-	//-- Create credential entry ----------
-	//TODO: I need to map the values we have to this
-	//{
-	//	var newEntry = &chromeCredential{}
-	//
-	//	profile.credentialItems = append(profile.credentialItems, newEntry)
-	//}
+func (c *chromeProfile) AddCredential(ctx context.Context, item Credential) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	//-- Create credential entry, skipped where the password cannot be encrypted ----------
+	{
+		var password, err = chromeEncryptPassword(item.Password)
+		if err != nil {
+			return fileError(c.dataPath+CHROME_LOGIN_DATA_FILE, err)
+		}
+
+		var realm = item.URL
+		if parsed, err := url.Parse(item.URL); err == nil && parsed.Host != `` {
+			realm = parsed.Scheme + `://` + parsed.Host + `/`
+		}
+
+		c.credentialItems = append(c.credentialItems, &chromeCredential{
+			OriginURL:       item.URL,
+			ActionURL:       item.URL,
+			SignonRealm:     realm,
+			UsernameValue:   item.UserName,
+			PasswordValue:   password,
+			DateCreated:     int(c.random.webKitTimestamp(item.CreateWindow)),
+			UsernameElement: `username`,
+			PasswordElement: `password`,
+			Preferred:       1,
+			TimesUsed:       1 + c.random.Intn(CHROME_CREDENTIAL_USES),
+		})
+	}
 
 	//-- Return ---------
 	return nil
 }
 
+func (c *chromeProfile) AddDownload(ctx context.Context, item Download) error {
+	//TODO: Downloads belong in the downloads and downloads_url_chains tables of History
+	return ErrUnsupported
+}
+
 //-- Internal Functions ------------------------------------------------------------------------------------------------
-func (c *chrome) open() error {
+func (c *chrome) Open(ctx context.Context) error {
 	//-- Determine OS-specific Data Path ----------
 	{
 		switch runtime.GOOS {
@@ -329,7 +639,7 @@ func (c *chrome) open() error {
 	//-- Open/Parse `Local State` file----------
 	{
 		if file, err := os.Open(c.dataPath + CHROME_STATE_FILE); err != nil {
-			return err
+			return &Error{Browser: `Chrome`, Op: `open`, File: c.dataPath + CHROME_STATE_FILE, Err: err}
 		} else {
 			c.stateFile = file
 		}
@@ -337,40 +647,50 @@ func (c *chrome) open() error {
 		var parser = json.NewDecoder(c.stateFile)
 
 		if err := parser.Decode(&c.state); err != nil {
-			return err
+			return &Error{Browser: `Chrome`, Op: `open`, File: c.dataPath + CHROME_STATE_FILE, Err: err}
 		}
 	}
 
-	//-- Connect to detected profiles ----------
+	//-- Connect to detected profiles, those that fail are reported while the rest stay usable ----------
+	var errs Errors
 	{
-		var errs []error
+		//NOTE: Local State keeps profiles in a map, sorting them keeps RandomProfile's picks stable under a fixed seed
+		var directories []string
 		for directory := range c.state.Profile.Info {
-			var profile = chromeProfile{dataPath: c.dataPath + directory + `/`}
+			directories = append(directories, directory)
+		}
+		sort.Strings(directories)
+
+		for _, directory := range directories {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			var info = c.state.Profile.Info[directory]
+			var profile = &chromeProfile{name: info.Name, dataPath: c.dataPath + directory + `/`, random: newRandom(`Chrome`, info.Name)}
 			if err := profile.open(); err != nil {
-				log.Printf(`Chrome: unable to connect to profile %s`, directory) //NOTE: Just doing this as a kindness, though it DOES break convention for the project
-				errs = append(errs, err)
+				errs = append(errs, profileError(profile, `open`, err))
 			} else {
-				c.profiles = append(c.profiles, &profile)
+				c.profiles = append(c.profiles, profile)
 			}
 		}
 
-		if len(c.profiles) < 1 {
-			return errors.New(`unable to open any profiles`)
+		if len(c.profiles) < 1 && len(errs) < 1 {
+			return &Error{Browser: `Chrome`, Op: `open`, File: c.dataPath, Err: ErrNotFound}
 		}
 	}
 
 	//-- Return ---------
-	return nil
+	return errs.errorOrNil()
 }
 
 func (c *chromeProfile) open() error {
 	//-- Open history database ----------
 	{
-		var dataSourceName = fmt.Sprintf(`file:%sHistory`, c.dataPath)
-		if orm, err := gorm.Open(`sqlite3`, dataSourceName); err != nil {
-			return err
+		if orm, err := openDatabase(c.dataPath + CHROME_HISTORY_FILE); err != nil {
+			return fileError(c.dataPath+CHROME_HISTORY_FILE, err)
 		} else if err := orm.DB().Ping(); err != nil {
-			return err
+			return fileError(c.dataPath+CHROME_HISTORY_FILE, err)
 		} else {
 			c.historyDatabase = orm
 		}
@@ -378,22 +698,49 @@ func (c *chromeProfile) open() error {
 
 	//-- Open credential database ----------
 	{
-		var dataSourceName = fmt.Sprintf(`file:%sLogin Data`, c.dataPath)
-		if orm, err := gorm.Open(`sqlite3`, dataSourceName); err != nil {
-			return err
+		if orm, err := openDatabase(c.dataPath + CHROME_LOGIN_DATA_FILE); err != nil {
+			return fileError(c.dataPath+CHROME_LOGIN_DATA_FILE, err)
 		} else if err := orm.DB().Ping(); err != nil {
-			return err
+			return fileError(c.dataPath+CHROME_LOGIN_DATA_FILE, err)
 		} else {
 			c.credentialDatabase = orm
 		}
 	}
 
+	//-- Open web data database ----------
+	{
+		if err := c.openWebData(); err != nil {
+			return fileError(c.dataPath+CHROME_WEB_DATA_FILE, err)
+		}
+	}
+
+	//-- Open top sites and shortcut databases ----------
+	{
+		if err := c.openTopSites(); err != nil {
+			return err
+		}
+	}
+
+	//-- Open favicon database ----------
+	{
+		if err := c.openFavicons(); err != nil {
+			return fileError(c.dataPath+CHROME_FAVICONS_FILE, err)
+		}
+	}
+
+	//-- Open cookie database ----------
+	{
+		if err := c.openCookies(); err != nil {
+			return fileError(c.dataPath+CHROME_COOKIES_FILES[0], err)
+		}
+	}
+
 	//-- Open/Parse Bookmark file ----------
 	{
-		if file, err := os.Open(fmt.Sprintf(`%sBookmarks`, c.dataPath)); os.IsNotExist(err) {
+		if file, err := os.Open(c.dataPath + CHROME_BOOKMARKS_FILE); os.IsNotExist(err) {
 			c.bookmarkFile = nil //NOTE: Maybe I should create the file or return err to eliminate error handling elsewhere.
 		} else if err != nil {
-			return err
+			return fileError(c.dataPath+CHROME_BOOKMARKS_FILE, err)
 		} else {
 			c.bookmarkFile = file
 		}
@@ -403,18 +750,19 @@ func (c *chromeProfile) open() error {
 	return nil
 }
 
-func (c *chrome) load() error {
+func (c *chrome) Load(ctx context.Context) error {
 	//-- Load each profile ----------
 	{
-		var errs []error
-		for _, profile := range c.profiles {
-			if err := profile.load(); err != nil {
-				errs = append(errs, err)
+		var errs = eachProfile(ctx, `load`, c.Profiles(), func(index int) error {
+			if err := ctx.Err(); err != nil {
+				return profileError(c.profiles[index], `load`, err)
 			}
-		}
+
+			return profileError(c.profiles[index], `load`, c.profiles[index].load())
+		})
 
 		if len(errs) > 0 {
-			return errors.New(`one or more errors encountered trying to load profiles`)
+			return errs
 		}
 	}
 
@@ -428,7 +776,13 @@ func (c *chromeProfile) load() error {
 		c.historyItems = []*chromeHistoryURL{}
 
 		if result := c.historyDatabase.Find(&c.historyItems); result.Error != nil {
-			return result.Error
+			return fileError(c.dataPath+CHROME_HISTORY_FILE, result.Error)
+		}
+
+		c.historyIndex = map[string]*chromeHistoryURL{}
+		for _, item := range c.historyItems {
+			item.stored = true
+			c.historyIndex[item.URL] = item
 		}
 	}
 
@@ -437,18 +791,26 @@ func (c *chromeProfile) load() error {
 		c.credentialItems = []*chromeCredential{}
 
 		if result := c.credentialDatabase.Find(&c.credentialItems); result.Error != nil {
-			return result.Error
+			return fileError(c.dataPath+CHROME_LOGIN_DATA_FILE, result.Error)
+		}
+
+		for _, item := range c.credentialItems {
+			item.stored = true
 		}
 	}
 
 	//-- Open/Parse bookmark manifest ----------
 	{
-		c.bookmarkManifest = new(chromeBookmarksManifest).init()
+		c.bookmarkManifest = new(chromeBookmarksManifest).init(c.random)
 		if c.bookmarkFile != nil {
+			var manifest = new(chromeBookmarksManifest)
+
 			var parser = json.NewDecoder(c.bookmarkFile)
-			if err := parser.Decode(&c.bookmarkManifest); err != nil {
-				return err
+			if err := parser.Decode(manifest); err != nil {
+				return fileError(c.dataPath+CHROME_BOOKMARKS_FILE, err)
 			}
+
+			c.bookmarkManifest = manifest.fill(c.random) //NOTE: Chromium keeps a tree whose checksum mismatches, writing corrects it
 		}
 	}
 
@@ -456,7 +818,93 @@ func (c *chromeProfile) load() error {
 	return nil
 }
 
-func (c *chrome) close() error {
+func (c *chrome) Inspect() ([]Report, error) {
+	//-- Inspect each profile ----------
+	var reports []Report
+	{
+		var errs Errors
+		for _, profile := range c.profiles {
+			if report, err := profile.inspect(); err != nil {
+				errs = append(errs, profileError(profile, `inspect`, err))
+			} else {
+				reports = append(reports, report)
+			}
+		}
+
+		if len(errs) > 0 {
+			return reports, errs
+		}
+	}
+
+	//-- Return ---------
+	return reports, nil
+}
+
+func (c *chromeProfile) inspect() (Report, error) {
+	var report = Report{
+		Browser:     `Chrome`,
+		Profile:     c.name,
+		Path:        c.dataPath,
+		URLs:        len(c.historyItems),
+		Credentials: len(c.credentialItems),
+		Bookmarks:   c.bookmarkManifest.bookmarkCount(),
+	}
+
+	//-- Summarise loaded history ----------
+	{
+		var domains = map[string]int{}
+		for _, item := range c.historyItems {
+			domains[reportDomain(item.URL)] += item.VisitCount
+		}
+
+		report.rankDomains(domains)
+	}
+
+	//-- Summarise individual visits ----------
+	{
+		if rows, err := c.historyDatabase.Raw(`SELECT visit_time FROM visits`).Rows(); err != nil {
+			return report, fileError(c.dataPath+CHROME_HISTORY_FILE, err)
+		} else {
+			defer rows.Close()
+
+			for rows.Next() {
+				var timestamp int64
+				if err := rows.Scan(&timestamp); err != nil {
+					return report, fileError(c.dataPath+CHROME_HISTORY_FILE, err)
+				}
+
+				report.addVisit(fromWebKitTimestamp(timestamp))
+			}
+
+			if err := rows.Err(); err != nil {
+				return report, fileError(c.dataPath+CHROME_HISTORY_FILE, err)
+			}
+		}
+	}
+
+	//-- Summarise open tabs ----------
+	{
+		if tabs, err := c.readSessions(); err != nil {
+			return report, fileError(c.dataPath+CHROME_SESSIONS_DIR, err)
+		} else {
+			report.OpenTabs = tabs
+		}
+	}
+
+	//-- Summarise bookmark tree ----------
+	{
+		for _, name := range CHROME_BOOKMARK_ROOTS {
+			if set, ok := c.bookmarkManifest.Folders[name]; ok {
+				report.BookmarkTree = append(report.BookmarkTree, set.report())
+			}
+		}
+	}
+
+	//-- Return ---------
+	return report, nil
+}
+
+func (c *chrome) Close() error {
 	//-- Close local state file ----------
 	{
 		if err := c.stateFile.Close(); err != nil {
@@ -466,15 +914,15 @@ func (c *chrome) close() error {
 
 	//-- Close detected profiles ----------
 	{
-		var errs []error
+		var errs Errors
 		for _, profile := range c.profiles {
 			if err := profile.close(); err != nil {
-				errs = append(errs, err)
+				errs = append(errs, profileError(profile, `close`, err))
 			}
 		}
 
 		if len(errs) > 0 {
-			return errors.New(`one or more errors encountered trying to close profiles`)
+			return errs
 		}
 	}
 
@@ -483,26 +931,58 @@ func (c *chrome) close() error {
 }
 
 func (c *chromeProfile) close() error {
+	//-- Discard history flushed but never committed ----------
+	{
+		c.transactions.rollback()
+	}
 
 	//-- Close history database ----------
 	{
 		if err := c.historyDatabase.Close(); err != nil {
-			return err
+			return fileError(c.dataPath+CHROME_HISTORY_FILE, err)
 		}
 	}
 
 	//-- Close credential database ----------
 	{
 		if err := c.credentialDatabase.Close(); err != nil {
+			return fileError(c.dataPath+CHROME_LOGIN_DATA_FILE, err)
+		}
+	}
+
+	//-- Close web data database ----------
+	{
+		if err := c.closeWebData(); err != nil {
+			return fileError(c.dataPath+CHROME_WEB_DATA_FILE, err)
+		}
+	}
+
+	//-- Close top sites and shortcut databases ----------
+	{
+		if err := c.closeTopSites(); err != nil {
 			return err
 		}
 	}
 
+	//-- Close favicon database ----------
+	{
+		if err := c.closeFavicons(); err != nil {
+			return fileError(c.dataPath+CHROME_FAVICONS_FILE, err)
+		}
+	}
+
+	//-- Close cookie database ----------
+	{
+		if err := c.closeCookies(); err != nil {
+			return fileError(c.cookieFile, err)
+		}
+	}
+
 	//-- Close Bookmark file ----------
 	{
 		if c.bookmarkFile != nil {
 			if err := c.bookmarkFile.Close(); err != nil {
-				return err
+				return fileError(c.dataPath+CHROME_BOOKMARKS_FILE, err)
 			}
 		}
 	}
@@ -511,18 +991,20 @@ func (c *chromeProfile) close() error {
 	return nil
 }
 
-func (c *chrome) purge() error {
+func (c *chrome) Purge(ctx context.Context) error {
 	//-- Purge detected profiles ----------
 	{
-		var errs []error
-		for _, profile := range c.profiles {
-			if err := profile.purge(); err != nil {
-				errs = append(errs, err)
+		var errs = eachProfile(ctx, `purge`, c.Profiles(), func(index int) error {
+			if err := ctx.Err(); err != nil {
+				return profileError(c.profiles[index], `purge`, err)
 			}
-		}
+
+			c.profiles[index].purge()
+			return nil
+		})
 
 		if len(errs) > 0 {
-			return errors.New(`one or more errors encountered trying to purge profiles`)
+			return errs
 		}
 	}
 
@@ -530,105 +1012,139 @@ func (c *chrome) purge() error {
 	return nil
 }
 
-func (c *chromeProfile) purge() error {
+// purge drops every pending and loaded item, the files themselves are emptied at the start of the next commit.
+func (c *chromeProfile) purge() {
+	c.purging = true
+	c.purged = false
+	c.purgeFilters = nil
+	c.stagedFilters = 0
+
+	c.historyItems = []*chromeHistoryURL{}
+	c.historyIndex = map[string]*chromeHistoryURL{}
+	c.pendingHistory = 0
+	c.credentialItems = []*chromeCredential{}
+	c.formItems = []*chromeAutofill{}
+	c.addressItems = []*chromeAddress{}
+	c.keywordItems = []*chromeKeyword{}
+	c.cookieItems = []*chromeCookie{}
+	c.bookmarkManifest = new(chromeBookmarksManifest).init(c.random)
+}
+
+// stagePurge applies staged purges to the open transactions ahead of the first write to them, once per commit.
+func (c *chromeProfile) stagePurge(ctx context.Context) error {
+	if c.purging && !c.purged {
+		if err := c.purgeDatabases(); err != nil {
+			return err
+		}
+
+		c.purged = true
+	}
+
+	return c.stagePurgeMatching(ctx)
+}
+
+// purgeDatabases empties every database in the profile's open transactions.
+func (c *chromeProfile) purgeDatabases() error {
 	//-- Purge history database ----------
 	{
-		var ctx = c.historyDatabase.Begin()
+		var tx = c.transactions.begin(c.historyDatabase)
 
 		//-- Purge flat URL history ----------
 		{
-			if result := ctx.Exec(`DELETE FROM urls`); result.Error != nil {
-				return result.Error
+			if result := tx.Exec(`DELETE FROM urls`); result.Error != nil {
+				return fileError(c.dataPath+CHROME_HISTORY_FILE, result.Error)
 			}
 		}
 
 		//-- Purge individual visit history ----------
 		{
-			if result := ctx.Exec(`DELETE FROM visits`); result.Error != nil {
-				return result.Error
-			} else if result := ctx.Exec(`DELETE FROM visit_source`); result.Error != nil {
-				return result.Error
+			if result := tx.Exec(`DELETE FROM visits`); result.Error != nil {
+				return fileError(c.dataPath+CHROME_HISTORY_FILE, result.Error)
+			} else if result := tx.Exec(`DELETE FROM visit_source`); result.Error != nil {
+				return fileError(c.dataPath+CHROME_HISTORY_FILE, result.Error)
 			}
 		}
 
 		//-- Purge individual download historyDatabase ----------
 		{
-			if result := ctx.Exec(`DELETE FROM downloads`); result.Error != nil {
-				return result.Error
-			} else if result := ctx.Exec(`DELETE FROM downloads_slices`); result.Error != nil {
-				return result.Error
-			} else if result := ctx.Exec(`DELETE FROM downloads_url_chains`); result.Error != nil {
-				return result.Error
+			if result := tx.Exec(`DELETE FROM downloads`); result.Error != nil {
+				return fileError(c.dataPath+CHROME_HISTORY_FILE, result.Error)
+			} else if result := tx.Exec(`DELETE FROM downloads_slices`); result.Error != nil {
+				return fileError(c.dataPath+CHROME_HISTORY_FILE, result.Error)
+			} else if result := tx.Exec(`DELETE FROM downloads_url_chains`); result.Error != nil {
+				return fileError(c.dataPath+CHROME_HISTORY_FILE, result.Error)
 			}
 		}
 
 		//-- Purge individual search terms ----------
 		{
-			if result := ctx.Exec(`DELETE FROM keyword_search_terms`); result.Error != nil {
-				return result.Error
+			if result := tx.Exec(`DELETE FROM keyword_search_terms`); result.Error != nil {
+				return fileError(c.dataPath+CHROME_HISTORY_FILE, result.Error)
 			}
 		}
 
 		//-- Purge segments ----------
 		{
-			if result := ctx.Exec(`DELETE FROM segment_usage`); result.Error != nil {
-				return result.Error
-			} else if result := ctx.Exec(`DELETE FROM segments`); result.Error != nil {
-				return result.Error
+			if result := tx.Exec(`DELETE FROM segment_usage`); result.Error != nil {
+				return fileError(c.dataPath+CHROME_HISTORY_FILE, result.Error)
+			} else if result := tx.Exec(`DELETE FROM segments`); result.Error != nil {
+				return fileError(c.dataPath+CHROME_HISTORY_FILE, result.Error)
 			}
 		}
-
-		//-- Commit ----------
-		{
-			if result := ctx.Commit(); result.Error != nil {
-				return result.Error
-			}
-		}
-
-		c.historyItems = []*chromeHistoryURL{}
 	}
 
 	//-- Purge credential database ----------
 	{
-		var ctx = c.credentialDatabase.Begin()
+		var tx = c.transactions.begin(c.credentialDatabase)
 
-		if result := ctx.Exec(`DELETE FROM logins`); result.Error != nil {
-			return result.Error
-		} else if result := ctx.Exec(`DELETE FROM stats`); result.Error != nil {
-			return result.Error
-		} else if result := ctx.Commit(); result.Error != nil {
-			return result.Error
+		if result := tx.Exec(`DELETE FROM logins`); result.Error != nil {
+			return fileError(c.dataPath+CHROME_LOGIN_DATA_FILE, result.Error)
+		} else if result := tx.Exec(`DELETE FROM stats`); result.Error != nil {
+			return fileError(c.dataPath+CHROME_LOGIN_DATA_FILE, result.Error)
 		}
+	}
 
-		c.credentialItems = []*chromeCredential{}
+	//-- Purge web data database ----------
+	{
+		if err := c.purgeWebData(); err != nil {
+			return fileError(c.dataPath+CHROME_WEB_DATA_FILE, err)
+		}
 	}
 
-	// Purge Bookmarks
+	//-- Purge favicons ----------
 	{
-		c.bookmarkManifest = new(chromeBookmarksManifest).init()
-		if err := c.writeBookmarks(); err != nil {
-			return err
+		if err := c.purgeFavicons(); err != nil {
+			return fileError(c.dataPath+CHROME_FAVICONS_FILE, err)
 		}
+	}
 
-		c.bookmarkManifest = new(chromeBookmarksManifest).init()
+	//-- Purge cookies ----------
+	{
+		if err := c.purgeCookies(); err != nil {
+			return fileError(c.cookieFile, err)
+		}
 	}
 
 	//-- Return ---------
 	return nil
 }
 
-func (c *chrome) commit() error {
+func (c *chrome) Commit(ctx context.Context) error {
 	//-- Commit detected profiles ----------
 	{
-		var errs []error
-		for _, profile := range c.profiles {
-			if err := profile.commit(); err != nil {
-				errs = append(errs, err)
+		var errs = eachProfile(ctx, `commit`, c.Profiles(), func(index int) error {
+			var profile = c.profiles[index]
+			if err := profile.commit(ctx); err != nil {
+				profile.transactions.rollback()
+				profile.staged.rollback()
+				return profileError(profile, `commit`, err)
 			}
-		}
+
+			return nil
+		})
 
 		if len(errs) > 0 {
-			return errors.New(`one or more errors encountered trying to commit profiles`)
+			return errs
 		}
 	}
 
@@ -636,83 +1152,347 @@ func (c *chrome) commit() error {
 	return nil
 }
 
-func (c *chromeProfile) commit() error {
+// commit writes the staged purge and items to every database in one transaction each, committed in the order they were
+// begun. The session and bookmark files are staged beside their targets and only moved into place once those hold.
+func (c *chromeProfile) commit(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	//-- Purge databases ----------
+	{
+		if err := c.stagePurge(ctx); err != nil {
+			return err
+		}
+	}
+
 	//-- Commit pending history to database ----------
 	{
-		var ctx = c.historyDatabase.Begin()
+		if err := c.flushHistory(ctx); err != nil {
+			return fileError(c.dataPath+CHROME_HISTORY_FILE, err)
+		}
+	}
 
-		for _, history := range c.historyItems {
+	//-- Commit pending credentials to database ----------
+	{
+		var tx = c.transactions.begin(c.credentialDatabase)
 
-			if result := ctx.Save(history); result.Error != nil {
-				return result.Error
+		for _, credential := range c.credentialItems {
+			if credential.stored {
+				continue
+			} else if result := tx.Create(credential); result.Error != nil {
+				return fileError(c.dataPath+CHROME_LOGIN_DATA_FILE, result.Error)
 			}
+
+			credential.stored = true
 		}
+	}
 
-		if result := ctx.Commit(); result.Error != nil {
-			return result.Error
+	//-- Regenerate top sites and shortcuts from history, unless a selective purge only removes matching ones ----------
+	{
+		if err := c.commitTopSites(); err != nil {
+			return fileError(c.dataPath+CHROME_HISTORY_FILE, err)
 		}
 	}
 
-	//-- Commit pending credentials to database ----------
+	//-- Map favicons onto history ----------
 	{
-		var ctx = c.credentialDatabase.Begin()
+		if err := c.commitFavicons(ctx); err != nil {
+			return fileError(c.dataPath+CHROME_FAVICONS_FILE, err)
+		}
+	}
 
-		for _, credential := range c.credentialItems {
+	//-- Commit pending form data ----------
+	{
+		if err := c.commitWebData(); err != nil {
+			return fileError(c.dataPath+CHROME_WEB_DATA_FILE, err)
+		}
+	}
 
-			if result := ctx.Save(credential); result.Error != nil {
-				return result.Error
-			}
+	//-- Commit pending cookies ----------
+	{
+		if err := c.commitCookies(); err != nil {
+			return fileError(c.cookieFile, err)
 		}
+	}
 
-		if result := ctx.Commit(); result.Error != nil {
-			return result.Error
+	//-- Replace session and tab restore files with tabs from the latest visits once they hold purged history ----------
+	{
+		if matched, err := c.sessionsMatching(); err != nil {
+			return fileError(c.dataPath+CHROME_SESSIONS_DIR, err)
+		} else if c.purging || matched {
+			if err := c.purgeSessions(); err != nil {
+				return fileError(c.dataPath+CHROME_SESSIONS_DIR, err)
+			} else if err := c.commitSessions(); err != nil {
+				return fileError(c.dataPath+CHROME_SESSIONS_DIR, err)
+			}
 		}
 	}
 
-	//-- Commit pending bookmarkManifest ----------
+	//-- Stage pending bookmarks ----------
 	{
 		if err := c.writeBookmarks(); err != nil {
+			return fileError(c.dataPath+CHROME_BOOKMARKS_FILE, err)
+		}
+	}
+
+	//-- Commit database transactions, the last point a cancellation leaves the profile untouched ----------
+	{
+		if err := ctx.Err(); err != nil {
+			return err
+		} else if err := c.transactions.commit(); err != nil {
 			return err
 		}
+
+		c.formItems = []*chromeAutofill{}
+		c.addressItems = []*chromeAddress{}
+		c.keywordItems = []*chromeKeyword{}
+		c.cookieItems = []*chromeCookie{}
 	}
 
+	//-- Move staged files into place ----------
+	{
+		if err := c.staged.commit(); err != nil {
+			return err
+		}
+	}
+
+	c.purging = false
+	c.purged = false
+	c.purgeFilters = nil
+	c.stagedFilters = 0
+
 	//-- Return ---------
 	return nil
 }
 
-func (c *chromeProfile) writeBookmarks() error {
+// flushHistory writes the pending URLs and visits into the open history transaction and releases the visits, so a
+// long run holds no more than CHROME_HISTORY_MEMORY_BUDGET of them at once. URL rows stay in memory for later visits to
+// merge into, and for the top sites, shortcuts and favicons derived from them at commit.
+func (c *chromeProfile) flushHistory(ctx context.Context) error {
+	if err := c.stagePurge(ctx); err != nil {
+		return err
+	}
+
+	var tx = c.transactions.begin(c.historyDatabase)
 
-	//-- Re-create and truncate file ----------
+	var segments []*chromeSegment
+	if assigned, err := c.assignSegments(tx); err != nil {
+		return err
+	} else {
+		segments = assigned
+	}
+
+	if err := c.writeHistory(ctx, tx); err != nil {
+		return err
+	} else if err := c.writeSegments(ctx, tx, segments); err != nil {
+		return err
+	}
+
+	for _, item := range c.historyItems {
+		item.Visits = nil
+	}
+	c.pendingHistory = 0
+
+	return nil
+}
+
+// writeHistory inserts the URLs added since the last write and the visits pending on any URL in batches, updating the
+// counters of stored URLs that gained visits. New URLs are given ids past the highest stored one up front so their
+// visits, and the segments assigned to them, can reference them before they are written.
+func (c *chromeProfile) writeHistory(ctx context.Context, tx *gorm.DB) error {
+	var urls, updates, visits [][]interface{}
+
+	//-- Reserve ids past the stored rows ----------
+	var nextURL, nextVisit uint
 	{
-		if c.bookmarkFile != nil {
-			if err := c.bookmarkFile.Close(); err != nil {
-				return err
+		if err := tx.Table(`urls`).Select(`COALESCE(MAX(id), 0)`).Row().Scan(&nextURL); err != nil {
+			return err
+		} else if err := tx.Table(`visits`).Select(`COALESCE(MAX(id), 0)`).Row().Scan(&nextVisit); err != nil {
+			return err
+		}
+	}
+
+	//-- Collect new and changed rows ----------
+	{
+		for _, item := range c.historyItems {
+			switch {
+			case !item.stored:
+				nextURL++
+				item.ID = nextURL
+				urls = append(urls, []interface{}{item.ID, item.URL, item.Title, item.VisitCount, item.TypedCount, item.LastVisitTime, item.Hidden})
+			case item.dirty:
+				updates = append(updates, []interface{}{item.Title, item.VisitCount, item.TypedCount, item.LastVisitTime, item.ID})
+			default:
+				continue
+			}
+
+			for _, visit := range item.Visits {
+				nextVisit++
+				visit.ID = nextVisit
+				visit.URL = int(item.ID)
+				visits = append(visits, []interface{}{visit.ID, visit.URL, visit.VisitTime, visit.FromVisit, visit.Transition, visit.SegmentID, visit.VisitDuration, visit.IncrementedOmniboxTypedScore})
 			}
 		}
+	}
 
-		if file, err := os.Create(fmt.Sprintf(`%sBookmarks`, c.dataPath)); err != nil {
+	//-- Insert batches ----------
+	{
+		if err := insertRows(ctx, tx, `urls`, []string{`id`, `url`, `title`, `visit_count`, `typed_count`, `last_visit_time`, `hidden`}, urls); err != nil {
+			return err
+		} else if err := insertRows(ctx, tx, `visits`, []string{`id`, `url`, `visit_time`, `from_visit`, `transition`, `segment_id`, `visit_duration`, `incremented_omnibox_typed_score`}, visits); err != nil {
 			return err
-		} else {
-			c.bookmarkFile = file
+		}
+	}
+
+	//-- Update changed counters ----------
+	if len(updates) > 0 {
+		var statement, err = tx.CommonDB().Prepare(`UPDATE urls SET title = ?, visit_count = ?, typed_count = ?, last_visit_time = ? WHERE id = ?`)
+		if err != nil {
+			return err
+		}
+		defer statement.Close()
+
+		for _, update := range updates {
+			if _, err := statement.Exec(update...); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, item := range c.historyItems {
+		item.stored = true
+		item.dirty = false
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (c *chromeProfile) writeBookmarks() error {
+
+	//-- Release the file read at load ----------
+	{
+		if c.bookmarkFile != nil {
+			if err := c.bookmarkFile.Close(); err != nil {
+				return fileError(c.dataPath+CHROME_BOOKMARKS_FILE, err)
+			}
+			c.bookmarkFile = nil
 		}
 	}
 
 	//-- Clear backup file ----------
 	{
-		if err := os.Remove(fmt.Sprintf(`%sBookmarks.bak`, c.dataPath)); err != nil && !os.IsNotExist(err) {
-			return err
+		if err := c.staged.remove(c.dataPath + CHROME_BOOKMARKS_FILE + `.bak`); err != nil {
+			return fileError(c.dataPath+CHROME_BOOKMARKS_FILE+`.bak`, err)
 		}
 	}
 
 	//-- Write fresh bookmark file ----------
 	{
-		if output, err := json.Marshal(c.bookmarkManifest); err != nil {
-			return err
-		} else if _, err := c.bookmarkFile.WriteString(string(output)); err != nil {
-			return err
+		c.bookmarkManifest.Checksum = c.bookmarkManifest.checksum()
+
+		var output, err = json.Marshal(c.bookmarkManifest)
+		if err != nil {
+			return fileError(c.dataPath+CHROME_BOOKMARKS_FILE, err)
+		}
+
+		var file io.WriteCloser
+		if created, err := c.staged.create(c.dataPath+CHROME_BOOKMARKS_FILE, 0666); err != nil {
+			return fileError(c.dataPath+CHROME_BOOKMARKS_FILE, err)
+		} else {
+			file = created
+		}
+
+		if _, err := file.Write(output); err != nil {
+			file.Close()
+			return fileError(c.dataPath+CHROME_BOOKMARKS_FILE, err)
+		} else if err := file.Close(); err != nil {
+			return fileError(c.dataPath+CHROME_BOOKMARKS_FILE, err)
 		}
 	}
 
 	//-- Return ---------
 	return nil
 }
+
+// openOptionalDatabase connects to a database Chrome creates lazily, a missing file yields a nil handle rather than an
+// empty database without Chrome's schema.
+func openOptionalDatabase(path string) (*gorm.DB, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	} else if orm, err := openDatabase(path); err != nil {
+		return nil, err
+	} else if err := orm.DB().Ping(); err != nil {
+		return nil, err
+	} else {
+		return orm, nil
+	}
+}
+
+// missingColumns lists which of the given columns a table lacks, so rows can omit fields older schema versions predate.
+func missingColumns(tx *gorm.DB, table string, columns ...string) []string {
+	var missing []string
+
+	for _, column := range columns {
+		if !tx.Dialect().HasColumn(table, column) {
+			missing = append(missing, column)
+		}
+	}
+
+	return missing
+}
+
+func checksumFolder(digest hash.Hash, id string, name string) {
+	digest.Write([]byte(id))
+	digest.Write(utf16Bytes(name))
+	digest.Write([]byte(`folder`))
+}
+
+func checksumURL(digest hash.Hash, id string, name string, url string) {
+	digest.Write([]byte(id))
+	digest.Write(utf16Bytes(name))
+	digest.Write([]byte(`url`))
+	digest.Write([]byte(url))
+}
+
+func utf16Bytes(value string) []byte {
+	var units = utf16.Encode([]rune(value))
+	var output = make([]byte, len(units)*2)
+
+	for i, unit := range units {
+		output[i*2] = byte(unit)
+		output[i*2+1] = byte(unit >> 8)
+	}
+
+	return output
+}
+
+func webKitBefore(left string, right string) bool {
+	var leftTime, _ = strconv.ParseInt(left, 10, 64)
+	var rightTime, _ = strconv.ParseInt(right, 10, 64)
+
+	return leftTime < rightTime
+}
+
+// chromeEncryptPassword applies the "v10" scheme Chrome on Linux falls back to without a keyring, AES-128-CBC under
+// a key derived from the fixed password "peanuts". Other platforms protect the key with DPAPI or the Keychain.
+func chromeEncryptPassword(password string) ([]byte, error) {
+	if runtime.GOOS != `linux` {
+		return nil, fmt.Errorf(`%w: chrome password encryption on %s`, ErrUnsupported, runtime.GOOS)
+	}
+
+	var key, err = pbkdf2.Key(sha1.New, CHROME_LINUX_PASSWORD, []byte(CHROME_LINUX_SALT), 1, 16)
+	if err != nil {
+		return nil, err
+	}
+
+	var ciphertext []byte
+	if ciphertext, err = cbcEncrypt(aes.NewCipher, key, bytes.Repeat([]byte{' '}, aes.BlockSize), []byte(password)); err != nil {
+		return nil, err
+	}
+
+	return append([]byte(`v10`), ciphertext...), nil
+}
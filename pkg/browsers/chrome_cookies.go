@@ -0,0 +1,228 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+var (
+	CHROME_COOKIES_FILES = []string{`Network/Cookies`, `Cookies`} // Chrome 96 moved the database under Network/
+
+	CHROME_COOKIE_OPTIONAL_COLUMNS = []string{`top_frame_site_key`, `source_port`, `last_update_utc`, `source_type`, `has_cross_site_ancestor`}
+)
+
+// Cookie priority, same site and source scheme values, net/extras/sqlite/sqlite_persistent_cookie_store.cc
+const (
+	chromeCookiePriorityMedium    = 1
+	chromeCookieSameSiteUnset     = -1
+	chromeCookieSchemeNonSecure   = 1
+	chromeCookieSchemeSecure      = 2
+	chromeCookiePortNonSecure     = 80
+	chromeCookiePortSecure        = 443
+	chromeCookieSourceTypeUnknown = 0
+)
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+type chromeCookie struct {
+	//-- Primary Key ----------
+
+	//-- User Variables ----------
+	CreationUTC    int64  `gorm:"column:creation_utc"`
+	HostKey        string `gorm:"column:host_key"`
+	Name           string `gorm:"column:name"`
+	Value          string `gorm:"column:value"`
+	EncryptedValue []byte `gorm:"column:encrypted_value"`
+	Path           string `gorm:"column:path"`
+	ExpiresUTC     int64  `gorm:"column:expires_utc"`
+	IsSecure       bool   `gorm:"column:is_secure"`
+	IsHTTPOnly     bool   `gorm:"column:is_httponly"`
+	LastAccessUTC  int64  `gorm:"column:last_access_utc"`
+
+	//-- System Variables ----------
+	HasExpires           bool   `gorm:"column:has_expires"`
+	IsPersistent         bool   `gorm:"column:is_persistent"`
+	Priority             int    `gorm:"column:priority"`
+	SameSite             int    `gorm:"column:samesite"`
+	SourceScheme         int    `gorm:"column:source_scheme"`
+	TopFrameSiteKey      string `gorm:"column:top_frame_site_key"`
+	SourcePort           int    `gorm:"column:source_port"`
+	LastUpdateUTC        int64  `gorm:"column:last_update_utc"`
+	SourceType           int    `gorm:"column:source_type"`
+	HasCrossSiteAncestor bool   `gorm:"column:has_cross_site_ancestor"`
+}
+
+func (chromeCookie) TableName() string {
+	return `cookies`
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+func (c *chromeProfile) AddCookie(ctx context.Context, item Cookie) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	} else if c.cookieDatabase == nil {
+		return fileError(c.dataPath+CHROME_COOKIES_FILES[0], fmt.Errorf(`%w, start Chrome once to create the database`, ErrUnsupported))
+	}
+
+	//-- Create cookie, replacing any with the same key as Chrome would ----------
+	{
+		var created = c.random.webKitTimestamp(item.CreateWindow)
+		var lifetime = int64(item.Lifetime / time.Microsecond)
+		var now = webKitTimestamp(time.Now())
+
+		var earliest = created
+		if lifetime > 0 && now-lifetime > earliest {
+			earliest = now - lifetime //NOTE: Visits refresh persistent cookies, the last one has to fall within a lifetime
+		}
+		var accessed = earliest + c.random.Int63n(now-earliest+1)
+
+		//NOTE: Chrome reads the plain value column whenever encrypted_value is empty
+		var cookie = &chromeCookie{
+			CreationUTC:    created,
+			HostKey:        strings.ToLower(item.Host),
+			Name:           item.Name,
+			Value:          item.Value,
+			EncryptedValue: []byte{},
+			Path:           item.Path,
+			IsSecure:       item.Secure,
+			IsHTTPOnly:     item.HTTPOnly,
+			LastAccessUTC:  accessed,
+			Priority:       chromeCookiePriorityMedium,
+			SameSite:       chromeCookieSameSiteUnset,
+			SourceScheme:   chromeCookieSchemeNonSecure,
+			SourcePort:     chromeCookiePortNonSecure,
+			LastUpdateUTC:  accessed,
+			SourceType:     chromeCookieSourceTypeUnknown,
+		}
+
+		if cookie.Path == `` {
+			cookie.Path = `/`
+		}
+
+		for index, existing := range c.cookieItems {
+			if existing.HostKey == cookie.HostKey && existing.Name == cookie.Name && existing.Path == cookie.Path {
+				c.cookieItems = append(c.cookieItems[:index], c.cookieItems[index+1:]...)
+				break
+			}
+		}
+
+		if item.Secure {
+			cookie.SourceScheme = chromeCookieSchemeSecure
+			cookie.SourcePort = chromeCookiePortSecure
+		}
+
+		if lifetime > 0 {
+			cookie.ExpiresUTC = accessed + lifetime
+			cookie.HasExpires = true
+			cookie.IsPersistent = true
+		}
+
+		c.cookieItems = append(c.cookieItems, cookie)
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+func (c *chromeProfile) openCookies() error {
+	for _, name := range CHROME_COOKIES_FILES {
+		if orm, err := openOptionalDatabase(c.dataPath + name); err != nil {
+			return err
+		} else if orm != nil {
+			c.cookieDatabase = orm
+			c.cookieFile = c.dataPath + name
+			break
+		}
+	}
+
+	return nil
+}
+
+func (c *chromeProfile) closeCookies() error {
+	if c.cookieDatabase != nil {
+		return c.cookieDatabase.Close()
+	}
+
+	return nil
+}
+
+func (c *chromeProfile) purgeCookies() error {
+	if c.cookieDatabase == nil {
+		return nil
+	} else if result := c.transactions.begin(c.cookieDatabase).Exec(`DELETE FROM cookies`); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// purgeCookiesMatching deletes the cookies created within the filter's range whose host and path match.
+func (c *chromeProfile) purgeCookiesMatching(ctx context.Context, filter PurgeFilter) error {
+	if c.cookieDatabase == nil {
+		return nil
+	}
+
+	var tx = c.transactions.begin(c.cookieDatabase)
+
+	var matched []interface{}
+	{
+		var rows, err = tx.Raw(`SELECT rowid, host_key, path, is_secure, creation_utc FROM cookies`).Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id, created int64
+			var host, path string
+			var secure bool
+			if err := rows.Scan(&id, &host, &path, &secure, &created); err != nil {
+				return err
+			} else if filter.matchesURL(chromeCookieURL(host, path, secure)) && filter.within(fromWebKitTimestamp(created)) {
+				matched = append(matched, id)
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	return execBatched(ctx, tx, `DELETE FROM cookies WHERE rowid IN (%s)`, matched)
+}
+
+// commitCookies writes pending cookies, leaving out columns added after the schema version of the profile's database.
+func (c *chromeProfile) commitCookies() error {
+	if c.cookieDatabase == nil {
+		return nil
+	}
+
+	var omitted = missingColumns(c.cookieDatabase, `cookies`, CHROME_COOKIE_OPTIONAL_COLUMNS...)
+	var tx = c.transactions.begin(c.cookieDatabase)
+
+	//-- Commit cookies ----------
+	{
+		for _, item := range c.cookieItems {
+			if result := tx.Omit(omitted...).Create(item); result.Error != nil {
+				return result.Error
+			}
+		}
+	}
+
+	return nil
+}
+
+// chromeCookieURL rebuilds the URL a cookie is sent to, so it can be matched like any other item.
+func chromeCookieURL(host string, path string, secure bool) string {
+	var scheme = `http://`
+	if secure {
+		scheme = `https://`
+	}
+
+	return scheme + strings.TrimPrefix(host, `.`) + path
+}
@@ -0,0 +1,298 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"image"
+	"image/color"
+	"image/png"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+var (
+	CHROME_FAVICONS_FILE = `Favicons`
+	CHROME_FAVICON_PATH  = `` // Directory of <domain>.png icons to use before falling back to generated placeholders
+	CHROME_FAVICON_SIZE  = 16
+
+	CHROME_FAVICON_TYPE = 1 // favicon_base::IconType::kFavicon
+)
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+type chromeFavicon struct {
+	//-- Primary Key ----------
+	ID uint `gorm:"column:id;primary_key"`
+
+	//-- User Variables ----------
+	URL      string `gorm:"column:url"`
+	IconType int    `gorm:"column:icon_type"`
+}
+
+func (chromeFavicon) TableName() string {
+	return `favicons`
+}
+
+type chromeFaviconBitmap struct {
+	//-- Primary Key ----------
+	ID uint `gorm:"column:id;primary_key"`
+
+	//-- User Variables ----------
+	IconID    uint   `gorm:"column:icon_id"`
+	ImageData []byte `gorm:"column:image_data"`
+	Width     int    `gorm:"column:width"`
+	Height    int    `gorm:"column:height"`
+
+	//-- System Variables ----------
+	LastUpdated   int64 `gorm:"column:last_updated"`
+	LastRequested int64 `gorm:"column:last_requested"`
+}
+
+func (chromeFaviconBitmap) TableName() string {
+	return `favicon_bitmaps`
+}
+
+type chromeIconMapping struct {
+	//-- Primary Key ----------
+	ID uint `gorm:"column:id;primary_key"`
+
+	//-- User Variables ----------
+	PageURL string `gorm:"column:page_url"`
+	IconID  uint   `gorm:"column:icon_id"`
+}
+
+func (chromeIconMapping) TableName() string {
+	return `icon_mapping`
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+func (c *chromeProfile) openFavicons() error {
+	if orm, err := openOptionalDatabase(c.dataPath + CHROME_FAVICONS_FILE); err != nil {
+		return err
+	} else {
+		c.faviconDatabase = orm
+	}
+
+	return nil
+}
+
+func (c *chromeProfile) closeFavicons() error {
+	if c.faviconDatabase != nil {
+		return c.faviconDatabase.Close()
+	}
+
+	return nil
+}
+
+func (c *chromeProfile) purgeFavicons() error {
+	if c.faviconDatabase == nil {
+		return nil
+	}
+
+	var tx = c.transactions.begin(c.faviconDatabase)
+
+	for _, table := range []string{`icon_mapping`, `favicon_bitmaps`, `favicons`} {
+		if result := tx.Exec(`DELETE FROM ` + table); result.Error != nil {
+			return result.Error
+		}
+	}
+
+	return nil
+}
+
+// purgeFaviconsMatching unmaps the given pages from their icons and deletes the icons no page is mapped to anymore.
+func (c *chromeProfile) purgeFaviconsMatching(ctx context.Context, pages map[string]bool) error {
+	if c.faviconDatabase == nil || len(pages) == 0 {
+		return nil
+	}
+
+	var tx = c.transactions.begin(c.faviconDatabase)
+
+	//-- Unmap pages ----------
+	var candidates = map[uint]bool{}
+	{
+		var mappings []*chromeIconMapping
+		if result := tx.Select(`id, page_url, icon_id`).Find(&mappings); result.Error != nil {
+			return result.Error
+		}
+
+		var unmapped []interface{}
+		for _, mapping := range mappings {
+			if pages[mapping.PageURL] {
+				unmapped = append(unmapped, mapping.ID)
+				candidates[mapping.IconID] = true
+			}
+		}
+
+		if err := execBatched(ctx, tx, `DELETE FROM icon_mapping WHERE id IN (%s)`, unmapped); err != nil {
+			return err
+		}
+	}
+
+	//-- Delete icons left unmapped ----------
+	{
+		var mappings []*chromeIconMapping
+		if result := tx.Select(`icon_id`).Find(&mappings); result.Error != nil {
+			return result.Error
+		}
+
+		for _, mapping := range mappings {
+			delete(candidates, mapping.IconID)
+		}
+
+		var icons []interface{}
+		for id := range candidates {
+			icons = append(icons, id)
+		}
+
+		if err := execBatched(ctx, tx, `DELETE FROM favicon_bitmaps WHERE icon_id IN (%s)`, icons); err != nil {
+			return err
+		} else if err := execBatched(ctx, tx, `DELETE FROM favicons WHERE id IN (%s)`, icons); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// commitFavicons maps every history page without an icon to its domain's favicon, creating the favicon and its bitmap
+// the first time a domain is seen. Existing mappings and icons are read once up front and new mappings written in
+// batches.
+func (c *chromeProfile) commitFavicons(ctx context.Context) error {
+	if c.faviconDatabase == nil {
+		return nil
+	}
+
+	var tx = c.transactions.begin(c.faviconDatabase)
+
+	//-- Index pages with an icon and the icons themselves ----------
+	var mapped = map[string]bool{}
+	var icons = map[string]uint{}
+	{
+		var mappings []*chromeIconMapping
+		if result := tx.Select(`page_url`).Find(&mappings); result.Error != nil {
+			return result.Error
+		}
+
+		for _, mapping := range mappings {
+			mapped[mapping.PageURL] = true
+		}
+
+		var favicons []*chromeFavicon
+		if result := tx.Select(`id, url`).Find(&favicons); result.Error != nil {
+			return result.Error
+		}
+
+		for _, icon := range favicons {
+			icons[icon.URL] = icon.ID
+		}
+	}
+
+	var rows [][]interface{}
+	for _, item := range c.historyItems {
+		var domain, iconURL = chromeFaviconURL(item.URL)
+		if domain == `` || mapped[item.URL] {
+			continue
+		}
+
+		//-- Find or create the domain's icon ----------
+		var iconID, ok = icons[iconURL]
+		if !ok {
+			var icon = &chromeFavicon{URL: iconURL, IconType: CHROME_FAVICON_TYPE}
+			if result := tx.Create(icon); result.Error != nil {
+				return result.Error
+			}
+
+			var data, width, height = chromeFaviconImage(domain)
+			var bitmap = &chromeFaviconBitmap{
+				IconID:      icon.ID,
+				ImageData:   data,
+				Width:       width,
+				Height:      height,
+				LastUpdated: webKitTimestamp(time.Now()),
+			}
+			if result := tx.Create(bitmap); result.Error != nil {
+				return result.Error
+			}
+
+			iconID = icon.ID
+			icons[iconURL] = iconID
+		}
+
+		//-- Map page to icon ----------
+		mapped[item.URL] = true
+		rows = append(rows, []interface{}{item.URL, iconID})
+	}
+
+	return insertRows(ctx, tx, `icon_mapping`, []string{`page_url`, `icon_id`}, rows)
+}
+
+func chromeFaviconURL(raw string) (string, string) {
+	if parsed, err := url.Parse(raw); err != nil || parsed.Hostname() == `` {
+		return ``, ``
+	} else {
+		return strings.ToLower(parsed.Hostname()), parsed.Scheme + `://` + parsed.Host + `/favicon.ico`
+	}
+}
+
+// chromeFaviconImage prefers a PNG from CHROME_FAVICON_PATH, falling back to the placeholder for the domain.
+func chromeFaviconImage(domain string) ([]byte, int, int) {
+	if CHROME_FAVICON_PATH != `` {
+		for _, name := range []string{domain, strings.TrimPrefix(domain, `www.`)} {
+			if data, err := os.ReadFile(filepath.Join(CHROME_FAVICON_PATH, name+`.png`)); err == nil {
+				if config, err := png.DecodeConfig(bytes.NewReader(data)); err == nil {
+					return data, config.Width, config.Height
+				}
+			} else if !os.IsNotExist(err) {
+				break
+			}
+		}
+	}
+
+	return placeholderFavicon(domain, CHROME_FAVICON_SIZE), CHROME_FAVICON_SIZE, CHROME_FAVICON_SIZE
+}
+
+// placeholderFavicon draws a mirrored 4x4 block pattern coloured from the domain's digest, so a domain always gets the
+// same icon across runs and profiles.
+func placeholderFavicon(domain string, size int) []byte {
+	var digest = md5.Sum([]byte(strings.TrimPrefix(domain, `www.`)))
+	var foreground = color.RGBA{R: digest[0], G: digest[1], B: digest[2], A: 0xff}
+	var background = color.RGBA{R: 0xff - digest[0]/4, G: 0xff - digest[1]/4, B: 0xff - digest[2]/4, A: 0xff}
+
+	var canvas = image.NewRGBA(image.Rect(0, 0, size, size))
+	var cell = size / 4
+
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			var column, row = x / cell, y / cell
+			if column > 3 {
+				column = 3
+			}
+			if row > 3 {
+				row = 3
+			}
+			if column > 1 {
+				column = 3 - column
+			}
+
+			if digest[3+row*2+column]&1 == 1 {
+				canvas.Set(x, y, foreground)
+			} else {
+				canvas.Set(x, y, background)
+			}
+		}
+	}
+
+	var output bytes.Buffer
+	png.Encode(&output, canvas)
+
+	return output.Bytes()
+}
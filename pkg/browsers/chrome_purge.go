@@ -0,0 +1,545 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"context"
+	"database/sql"
+	"strconv"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+// chromeURLPurge tallies the visits a selective purge removes from, and leaves on, a matching URL row.
+type chromeURLPurge struct {
+	url         string
+	visitCount  int
+	typedCount  int
+	purged      int
+	purgedTyped int
+	kept        int
+	lastKept    int
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+// PurgeMatching stages the removal of the items matching filter from every profile, the rows depending on removed
+// history go with it. Everything else is kept.
+func (c *chrome) PurgeMatching(ctx context.Context, filter PurgeFilter) error {
+	//-- Purge matching items of detected profiles ----------
+	{
+		var errs = eachProfile(ctx, `purge`, c.Profiles(), func(index int) error {
+			if err := ctx.Err(); err != nil {
+				return profileError(c.profiles[index], `purge`, err)
+			}
+
+			c.profiles[index].purgeMatching(filter)
+			return nil
+		})
+
+		if len(errs) > 0 {
+			return errs
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+// purgeMatching drops the pending and loaded items matching filter, stored rows are deleted at the start of the next
+// commit.
+func (c *chromeProfile) purgeMatching(filter PurgeFilter) {
+	c.purgeFilters = append(c.purgeFilters, filter)
+
+	//-- Drop pending visits and URLs ----------
+	if filter.includes(DataHistory) {
+		c.purgePendingHistory(filter)
+	}
+
+	//-- Drop credentials ----------
+	if filter.includes(DataCredentials) {
+		var kept = []*chromeCredential{}
+		for _, item := range c.credentialItems {
+			if !filter.matchesURL(item.OriginURL) || !filter.within(fromWebKitTimestamp(int64(item.DateCreated))) {
+				kept = append(kept, item)
+			}
+		}
+		c.credentialItems = kept
+	}
+
+	//-- Drop pending cookies ----------
+	if filter.includes(DataCookies) {
+		var kept = []*chromeCookie{}
+		for _, item := range c.cookieItems {
+			if !filter.matchesURL(chromeCookieURL(item.HostKey, item.Path, item.IsSecure)) || !filter.within(fromWebKitTimestamp(item.CreationUTC)) {
+				kept = append(kept, item)
+			}
+		}
+		c.cookieItems = kept
+	}
+
+	//-- Drop bookmarks ----------
+	if filter.includes(DataBookmarks) {
+		for _, set := range c.bookmarkManifest.Folders {
+			set.prune(filter)
+		}
+	}
+}
+
+// purgePendingHistory drops the visits not yet written that match filter, and URLs not yet written left without any.
+func (c *chromeProfile) purgePendingHistory(filter PurgeFilter) {
+	var items = []*chromeHistoryURL{}
+
+	for _, item := range c.historyItems {
+		if !filter.matchesURL(item.URL) {
+			items = append(items, item)
+			continue
+		}
+
+		var visits = []*chromeHistoryVisit{}
+		for _, visit := range item.Visits {
+			if !filter.within(fromWebKitTimestamp(int64(visit.VisitTime))) {
+				visits = append(visits, visit)
+				continue
+			}
+
+			item.VisitCount--
+			if Transition(visit.Transition&0xff) == TransitionTyped {
+				item.TypedCount--
+			}
+			c.pendingHistory -= chromeHistoryVisitSize
+		}
+		item.Visits = visits
+
+		//-- Forget URLs only known from dropped visits ----------
+		if !item.stored {
+			if len(item.Visits) == 0 {
+				delete(c.historyIndex, item.URL)
+				c.pendingHistory -= chromeHistoryURLSize + len(item.URL) + len(item.Title)
+				continue
+			}
+
+			item.LastVisitTime, item.lastTyped = chromeLatestVisits(item.Visits)
+		}
+
+		items = append(items, item)
+	}
+
+	c.historyItems = items
+}
+
+// stagePurgeMatching applies the selective purges staged since the last commit to the open transactions, each one once.
+func (c *chromeProfile) stagePurgeMatching(ctx context.Context) error {
+	for ; c.stagedFilters < len(c.purgeFilters); c.stagedFilters++ {
+		var filter = c.purgeFilters[c.stagedFilters]
+
+		//-- Purge history and everything derived from it ----------
+		if filter.includes(DataHistory) {
+			if err := c.purgeHistoryMatching(ctx, filter); err != nil {
+				return fileError(c.dataPath+CHROME_HISTORY_FILE, err)
+			} else if err := c.purgeTopSitesMatching(ctx, filter); err != nil {
+				return err
+			}
+		}
+
+		//-- Purge downloads ----------
+		if filter.includes(DataDownloads) {
+			if err := c.purgeDownloadsMatching(ctx, filter); err != nil {
+				return fileError(c.dataPath+CHROME_HISTORY_FILE, err)
+			}
+		}
+
+		//-- Purge credentials ----------
+		if filter.includes(DataCredentials) {
+			if err := c.purgeCredentialsMatching(ctx, filter); err != nil {
+				return fileError(c.dataPath+CHROME_LOGIN_DATA_FILE, err)
+			}
+		}
+
+		//-- Purge cookies ----------
+		if filter.includes(DataCookies) {
+			if err := c.purgeCookiesMatching(ctx, filter); err != nil {
+				return fileError(c.cookieFile, err)
+			}
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+// purgingHistory reports whether a purge staged since the last commit removes history, which top sites and shortcuts
+// are derived from.
+func (c *chromeProfile) purgingHistory() bool {
+	if c.purging {
+		return true
+	}
+
+	for _, filter := range c.purgeFilters {
+		if filter.includes(DataHistory) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// purgeHistoryMatching deletes the visits matching filter and the URLs they leave without any, or every matching URL
+// when the filter is untimed, along with their visit sources, segments and favicons. Search terms go with any URL that
+// loses a visit, and visits of other URLs sharing a removed segment are left without one. URLs keeping some of their
+// visits have their counters reduced, and the loaded URLs are brought in line with the rows.
+func (c *chromeProfile) purgeHistoryMatching(ctx context.Context, filter PurgeFilter) error {
+	var tx = c.transactions.begin(c.historyDatabase)
+
+	//-- Find matching URLs ----------
+	var matched = map[uint]*chromeURLPurge{}
+	{
+		var rows, err = tx.Raw(`SELECT id, url, visit_count, typed_count FROM urls`).Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id uint
+			var tally = &chromeURLPurge{}
+			if err := rows.Scan(&id, &tally.url, &tally.visitCount, &tally.typedCount); err != nil {
+				return err
+			} else if filter.matchesURL(tally.url) {
+				matched[id] = tally
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	if len(matched) == 0 {
+		return nil
+	}
+
+	//-- Split their visits into purged and kept ----------
+	var visits []interface{}
+	{
+		var rows, err = tx.Raw(`SELECT id, url, visit_time, transition FROM visits`).Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id, visitTime, transition int
+			var urlID uint
+			if err := rows.Scan(&id, &urlID, &visitTime, &transition); err != nil {
+				return err
+			}
+
+			var tally, ok = matched[urlID]
+			switch {
+			case !ok:
+				continue
+			case filter.within(fromWebKitTimestamp(int64(visitTime))):
+				visits = append(visits, id)
+				tally.purged++
+				if Transition(transition&0xff) == TransitionTyped {
+					tally.purgedTyped++
+				}
+			default:
+				tally.kept++
+				tally.lastKept = max(tally.lastKept, visitTime)
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	//-- Decide which URLs go and which are recounted ----------
+	var removed, searched []interface{}
+	var pages = map[string]bool{}
+	{
+		for id, tally := range matched {
+			if !filter.timed() || (tally.purged > 0 && tally.kept == 0) {
+				removed = append(removed, id)
+				pages[tally.url] = true
+			} else if tally.purged == 0 {
+				delete(matched, id)
+				continue
+			}
+
+			searched = append(searched, id) //NOTE: Search terms are kept per URL, any purged visit may have typed them
+		}
+	}
+
+	//-- Find segments of removed URLs and usage within the range ----------
+	var segments, usage []interface{}
+	{
+		var owners = map[int]uint{}
+
+		var rows, err = tx.Raw(`SELECT id, url_id FROM segments`).Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id int
+			var urlID uint
+			if err := rows.Scan(&id, &urlID); err != nil {
+				return err
+			} else if tally, ok := matched[urlID]; ok {
+				owners[id] = urlID
+				if pages[tally.url] {
+					segments = append(segments, id)
+				}
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if usageRows, err := tx.Raw(`SELECT id, segment_id, time_slot FROM segment_usage`).Rows(); err != nil {
+			return err
+		} else {
+			defer usageRows.Close()
+
+			for usageRows.Next() {
+				var id, segmentID int
+				var timeSlot int64
+				if err := usageRows.Scan(&id, &segmentID, &timeSlot); err != nil {
+					return err
+				} else if urlID, ok := owners[segmentID]; !ok {
+					continue
+				} else if pages[matched[urlID].url] || filter.within(fromWebKitTimestamp(timeSlot)) {
+					usage = append(usage, id)
+				}
+			}
+
+			if err := usageRows.Err(); err != nil {
+				return err
+			}
+		}
+	}
+
+	//-- Delete visits and what depends on them ----------
+	{
+		if err := execBatched(ctx, tx, `DELETE FROM visit_source WHERE id IN (%s)`, visits); err != nil {
+			return err
+		} else if err := execBatched(ctx, tx, `UPDATE visits SET from_visit = 0 WHERE from_visit IN (%s)`, visits); err != nil {
+			return err
+		} else if err := execBatched(ctx, tx, `DELETE FROM visits WHERE id IN (%s)`, visits); err != nil {
+			return err
+		}
+	}
+
+	//-- Delete URLs and what depends on them ----------
+	{
+		if err := execBatched(ctx, tx, `DELETE FROM segment_usage WHERE id IN (%s)`, usage); err != nil {
+			return err
+		} else if err := execBatched(ctx, tx, `UPDATE visits SET segment_id = 0 WHERE segment_id IN (%s)`, segments); err != nil {
+			return err
+		} else if err := execBatched(ctx, tx, `DELETE FROM segments WHERE id IN (%s)`, segments); err != nil {
+			return err
+		} else if err := execBatched(ctx, tx, `DELETE FROM keyword_search_terms WHERE url_id IN (%s)`, searched); err != nil {
+			return err
+		} else if err := execBatched(ctx, tx, `DELETE FROM urls WHERE id IN (%s)`, removed); err != nil {
+			return err
+		}
+
+		if err := c.purgeFaviconsMatching(ctx, pages); err != nil {
+			return fileError(c.dataPath+CHROME_FAVICONS_FILE, err)
+		}
+	}
+
+	//-- Recount URLs keeping some visits ----------
+	{
+		var statement, err = tx.CommonDB().Prepare(`UPDATE urls SET visit_count = ?, typed_count = ?, last_visit_time = ? WHERE id = ?`)
+		if err != nil {
+			return err
+		}
+		defer statement.Close()
+
+		for id, tally := range matched {
+			if pages[tally.url] {
+				continue
+			}
+
+			tally.visitCount = max(tally.visitCount-tally.purged, 0)
+			tally.typedCount = max(tally.typedCount-tally.purgedTyped, 0)
+
+			if _, err := statement.Exec(tally.visitCount, tally.typedCount, tally.lastKept, id); err != nil {
+				return err
+			}
+		}
+	}
+
+	//-- Bring loaded URLs in line ----------
+	{
+		var items = []*chromeHistoryURL{}
+
+		for _, item := range c.historyItems {
+			var tally, ok = matched[item.ID]
+			if !item.stored || !ok {
+				items = append(items, item)
+				continue
+			}
+
+			var lastVisit, lastTyped = chromeLatestVisits(item.Visits)
+			var typed = 0
+			for _, visit := range item.Visits {
+				if Transition(visit.Transition&0xff) == TransitionTyped {
+					typed++
+				}
+			}
+
+			if pages[item.URL] {
+				//-- Visits added since the purge make it a new row ----------
+				if len(item.Visits) == 0 {
+					delete(c.historyIndex, item.URL)
+					continue
+				}
+
+				item.ID = 0
+				item.stored = false
+				item.dirty = false
+				item.VisitCount = len(item.Visits)
+				item.TypedCount = typed
+				item.LastVisitTime = lastVisit
+				item.lastTyped = lastTyped
+			} else {
+				item.VisitCount = tally.visitCount + len(item.Visits)
+				item.TypedCount = tally.typedCount + typed
+				item.LastVisitTime = max(tally.lastKept, lastVisit)
+			}
+
+			items = append(items, item)
+		}
+
+		c.historyItems = items
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+// purgeDownloadsMatching deletes the downloads started within the filter's range whose tab or any URL of their
+// redirect chain matches, with their chains and slices.
+func (c *chromeProfile) purgeDownloadsMatching(ctx context.Context, filter PurgeFilter) error {
+	var tx = c.transactions.begin(c.historyDatabase)
+
+	var downloads []interface{}
+	{
+		var seen = map[int]bool{}
+
+		var rows, err = tx.Raw(`SELECT downloads.id, downloads.start_time, downloads.tab_url, downloads_url_chains.url FROM downloads LEFT JOIN downloads_url_chains ON downloads_url_chains.id = downloads.id`).Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id int
+			var startTime int64
+			var tabURL string
+			var chainURL sql.NullString
+			if err := rows.Scan(&id, &startTime, &tabURL, &chainURL); err != nil {
+				return err
+			} else if seen[id] || !filter.within(fromWebKitTimestamp(startTime)) {
+				continue
+			} else if filter.matchesURL(tabURL) || (chainURL.Valid && filter.matchesURL(chainURL.String)) {
+				seen[id] = true
+				downloads = append(downloads, id)
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	if err := execBatched(ctx, tx, `DELETE FROM downloads_slices WHERE download_id IN (%s)`, downloads); err != nil {
+		return err
+	} else if err := execBatched(ctx, tx, `DELETE FROM downloads_url_chains WHERE id IN (%s)`, downloads); err != nil {
+		return err
+	} else if err := execBatched(ctx, tx, `DELETE FROM downloads WHERE id IN (%s)`, downloads); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// purgeCredentialsMatching deletes the saved logins created within the filter's range for a matching origin, and the
+// matching password manager statistics.
+func (c *chromeProfile) purgeCredentialsMatching(ctx context.Context, filter PurgeFilter) error {
+	var tx = c.transactions.begin(c.credentialDatabase)
+
+	for _, query := range []struct{ table, selection string }{
+		{`logins`, `SELECT rowid, origin_url, date_created FROM logins`},
+		{`stats`, `SELECT rowid, origin_domain, update_time FROM stats`},
+	} {
+		var matched []interface{}
+
+		var rows, err = tx.Raw(query.selection).Rows()
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var id, moment int64
+			var origin string
+			if err := rows.Scan(&id, &origin, &moment); err != nil {
+				rows.Close()
+				return err
+			} else if filter.matchesURL(origin) && filter.within(fromWebKitTimestamp(moment)) {
+				matched = append(matched, id)
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return err
+		}
+		rows.Close()
+
+		if err := execBatched(ctx, tx, `DELETE FROM `+query.table+` WHERE rowid IN (%s)`, matched); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// prune removes the bookmarks below a folder that match filter by URL and date added, folders themselves are kept.
+func (c *chromeBookmark) prune(filter PurgeFilter) {
+	var children = []*chromeBookmark{}
+
+	for _, child := range c.Children {
+		if child.Type == `folder` {
+			child.prune(filter)
+		} else if added, _ := strconv.ParseInt(child.CreatedAt, 10, 64); filter.matchesURL(child.URL) && filter.within(fromWebKitTimestamp(added)) {
+			continue
+		}
+
+		children = append(children, child)
+	}
+
+	c.Children = children
+}
+
+// chromeLatestVisits returns the time of the latest visit and of the latest typed visit.
+func chromeLatestVisits(visits []*chromeHistoryVisit) (int, int) {
+	var last, lastTyped int
+
+	for _, visit := range visits {
+		last = max(last, visit.VisitTime)
+		if Transition(visit.Transition&0xff) == TransitionTyped {
+			lastTyped = max(lastTyped, visit.VisitTime)
+		}
+	}
+
+	return last, lastTyped
+}
@@ -0,0 +1,213 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+var (
+	CHROME_SEGMENT_PREFIXES = []string{`www.`, `m.`, `mobile.`, `touch.`}
+)
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+type chromeSegment struct {
+	//-- Primary Key ----------
+	ID uint `gorm:"column:id;primary_key"`
+
+	//-- User Variables ----------
+	Name  string `gorm:"column:name"`
+	URLID uint   `gorm:"column:url_id"`
+
+	//-- Relations ----------
+	history *chromeHistoryURL
+	usage   map[int64]int
+	stored  bool
+}
+
+func (chromeSegment) TableName() string {
+	return `segments`
+}
+
+type chromeSegmentUsage struct {
+	//-- Primary Key ----------
+	ID uint `gorm:"column:id;primary_key"`
+
+	//-- User Variables ----------
+	SegmentID  uint  `gorm:"column:segment_id"`
+	TimeSlot   int64 `gorm:"column:time_slot"`
+	VisitCount int   `gorm:"column:visit_count"`
+}
+
+func (chromeSegmentUsage) TableName() string {
+	return `segment_usage`
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+// assignSegments gives every pending visit that starts a segment the id of its URL's segment before the visits are
+// saved, reserving ids past the highest existing segment so the rows can be written once their URL ids are known.
+func (c *chromeProfile) assignSegments(tx *gorm.DB) ([]*chromeSegment, error) {
+	var segments []*chromeSegment
+	var byName = map[string]*chromeSegment{}
+
+	//-- Index existing segments ----------
+	var nextID uint
+	{
+		var existing []*chromeSegment
+		if result := tx.Find(&existing); result.Error != nil {
+			return nil, result.Error
+		}
+
+		for _, segment := range existing {
+			segment.stored = true
+			byName[segment.Name] = segment
+
+			if segment.ID >= nextID {
+				nextID = segment.ID + 1
+			}
+		}
+
+		if nextID == 0 {
+			nextID = 1
+		}
+	}
+
+	//-- Assign visits ----------
+	{
+		for _, history := range c.historyItems {
+			var name = chromeSegmentName(history.URL)
+
+			for _, visit := range history.Visits {
+				if visit.SegmentID != 0 || !chromeStartsSegment(visit.Transition) {
+					continue
+				}
+
+				var segment, ok = byName[name]
+				if !ok {
+					segment = &chromeSegment{ID: nextID, Name: name, history: history}
+					byName[name] = segment
+					nextID++
+				}
+
+				if segment.usage == nil {
+					segment.usage = map[int64]int{}
+					segments = append(segments, segment)
+				}
+
+				var slot = chromeTimeSlot(visit.VisitTime)
+				segment.usage[slot] = segment.usage[slot] + 1
+				visit.SegmentID = int(segment.ID)
+			}
+		}
+	}
+
+	//-- Return ---------
+	return segments, nil
+}
+
+// writeSegments stores new segments and folds their visits into the daily segment_usage counts. New segments and days
+// are written in batches, only days that already have a count are updated one by one.
+func (c *chromeProfile) writeSegments(ctx context.Context, tx *gorm.DB, segments []*chromeSegment) error {
+	type key struct {
+		segment uint
+		slot    int64
+	}
+
+	//-- Index existing daily usage ----------
+	var existing = map[key]*chromeSegmentUsage{}
+	{
+		var usages []*chromeSegmentUsage
+		if result := tx.Find(&usages); result.Error != nil {
+			return result.Error
+		}
+
+		for _, usage := range usages {
+			existing[key{usage.SegmentID, usage.TimeSlot}] = usage
+		}
+	}
+
+	var created, usages [][]interface{}
+	for _, segment := range segments {
+		//-- Create segment now its URL has an id ----------
+		if !segment.stored {
+			segment.URLID = segment.history.ID
+			created = append(created, []interface{}{segment.ID, segment.Name, segment.URLID})
+			segment.stored = true
+		}
+
+		//-- Add daily usage in slot order, a seeded commit writes the same rows every time ----------
+		var slots = make([]int64, 0, len(segment.usage))
+		for slot := range segment.usage {
+			slots = append(slots, slot)
+		}
+		sort.Slice(slots, func(i, j int) bool { return slots[i] < slots[j] })
+
+		for _, slot := range slots {
+			var count = segment.usage[slot]
+			if usage, ok := existing[key{segment.ID, slot}]; !ok {
+				usages = append(usages, []interface{}{segment.ID, slot, count})
+			} else if result := tx.Model(usage).Update(`visit_count`, usage.VisitCount+count); result.Error != nil {
+				return result.Error
+			}
+		}
+
+		segment.usage = nil
+	}
+
+	if err := insertRows(ctx, tx, `segments`, []string{`id`, `name`, `url_id`}, created); err != nil {
+		return err
+	}
+
+	return insertRows(ctx, tx, `segment_usage`, []string{`segment_id`, `time_slot`, `visit_count`}, usages)
+}
+
+// chromeSegmentName follows VisitSegmentDatabase::ComputeSegmentName: common mobile and www prefixes are stripped,
+// credentials, port, query and fragment dropped and https folded into http.
+func chromeSegmentName(raw string) string {
+	var parsed, err = url.Parse(raw)
+	if err != nil || parsed.Host == `` {
+		return raw
+	}
+
+	var host = parsed.Hostname()
+	for _, prefix := range CHROME_SEGMENT_PREFIXES {
+		if len(host) > len(prefix) && strings.HasPrefix(strings.ToLower(host), prefix) {
+			host = host[len(prefix):]
+			break
+		}
+	}
+
+	var segment = url.URL{Scheme: strings.ToLower(parsed.Scheme), Host: strings.ToLower(host), Path: parsed.Path, RawPath: parsed.RawPath}
+	if segment.Scheme == `https` {
+		segment.Scheme = `http`
+	}
+	if segment.Path == `` {
+		segment.Path = `/`
+	}
+
+	return segment.String()
+}
+
+// chromeStartsSegment follows HistoryBackend::UpdateSegments, only typed and bookmark navigations start a segment. Other
+// visits inherit the segment of the visit they came from, synthesized visits have none to inherit from.
+func chromeStartsSegment(transition int) bool {
+	var core = Transition(transition & 0xff)
+	return core == TransitionTyped || core == TransitionAutoBookmark
+}
+
+// chromeTimeSlot buckets a WebKit timestamp into its local midnight, as segment_usage counts visits per day.
+func chromeTimeSlot(timestamp int) int64 {
+	var moment = fromWebKitTimestamp(int64(timestamp)).Local()
+	var midnight = time.Date(moment.Year(), moment.Month(), moment.Day(), 0, 0, 0, 0, moment.Location())
+
+	return webKitTimestamp(midnight)
+}
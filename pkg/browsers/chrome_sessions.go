@@ -0,0 +1,435 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+var (
+	CHROME_SESSIONS_DIR        = `Sessions`
+	CHROME_SESSION_PREFIX      = `Session_`
+	CHROME_TABS_PREFIX         = `Tabs_`
+	CHROME_LEGACY_SESSIONS     = []string{`Current Session`, `Current Tabs`, `Last Session`, `Last Tabs`}
+	CHROME_SESSION_TABS        = 6 // Most tabs left open in the synthesized window
+	CHROME_SESSION_CLOSED_TABS = 4 // Most recently closed tabs offered by Ctrl+Shift+T
+	CHROME_SESSION_NAVIGATIONS = 3 // Most back/forward entries per tab
+
+	CHROME_WINDOW_BOUNDS = [][4]int32{{0, 0, 1280, 800}, {0, 0, 1366, 768}, {0, 0, 1440, 900}, {0, 0, 1920, 1080}, {80, 40, 1600, 900}}
+)
+
+// Session service command ids, components/sessions/core/session_service_commands.cc
+const (
+	chromeSessionSetTabWindow               = 0
+	chromeSessionSetTabIndexInWindow        = 2
+	chromeSessionUpdateTabNavigation        = 6
+	chromeSessionSetSelectedNavigationIdx   = 7
+	chromeSessionSetSelectedTabInIndex      = 8
+	chromeSessionSetWindowType              = 9
+	chromeSessionSetWindowBounds3           = 14
+	chromeSessionTabClosed                  = 16
+	chromeSessionSetActiveWindow            = 20
+	chromeSessionLastActiveTime             = 21
+	chromeTabRestoreUpdateTabNavigation     = 1
+	chromeTabRestoreSelectedNavigationInTab = 4
+)
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+type chromeSessionTab struct {
+	id          int32
+	navigations []chromeSessionNavigation
+}
+
+type chromeSessionNavigation struct {
+	URL        string
+	Title      string
+	Timestamp  int64
+	Transition int32
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+// purgeSessions removes the session and tab restore command files.
+func (c *chromeProfile) purgeSessions() error {
+	var paths, err = c.sessionFiles()
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		if err := c.staged.remove(path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sessionFiles lists the session and tab restore command files, both the Sessions directory used since Chrome 85 and
+// the fixed names written by older versions in the profile root.
+func (c *chromeProfile) sessionFiles() ([]string, error) {
+	var paths []string
+
+	for _, prefix := range []string{CHROME_SESSION_PREFIX, CHROME_TABS_PREFIX} {
+		if matches, err := filepath.Glob(filepath.Join(c.dataPath+CHROME_SESSIONS_DIR, prefix+`*`)); err != nil {
+			return nil, err
+		} else {
+			paths = append(paths, matches...)
+		}
+	}
+
+	for _, name := range CHROME_LEGACY_SESSIONS {
+		paths = append(paths, c.dataPath+name)
+	}
+
+	return paths, nil
+}
+
+// sessionsMatching reports whether a navigation in any session or tab restore file matches a history purge staged
+// since the last commit, only then are the files rebuilt. A navigation without a timestamp matches on its URL alone.
+func (c *chromeProfile) sessionsMatching() (bool, error) {
+	var filters []PurgeFilter
+	for _, filter := range c.purgeFilters {
+		if filter.includes(DataHistory) {
+			filters = append(filters, filter)
+		}
+	}
+
+	if len(filters) == 0 {
+		return false, nil
+	}
+
+	var paths, err = c.sessionFiles()
+	if err != nil {
+		return false, err
+	}
+
+	for _, path := range paths {
+		var navigation = uint8(chromeSessionUpdateTabNavigation)
+		if name := filepath.Base(path); strings.HasPrefix(name, CHROME_TABS_PREFIX) || strings.HasSuffix(name, ` Tabs`) {
+			navigation = chromeTabRestoreUpdateTabNavigation
+		}
+
+		var file, err = os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return false, err
+		}
+
+		var _, commands, _ = readSNSS(file) //NOTE: A truncated file is matched on the commands read before the break
+		file.Close()
+
+		for _, command := range commands {
+			if command.id != navigation {
+				continue
+			}
+
+			var _, _, entry, err = readChromeNavigation(command.payload)
+			if err != nil {
+				continue
+			}
+
+			for _, filter := range filters {
+				if filter.matchesURL(entry.URL) && (entry.LastActive.IsZero() || filter.within(entry.LastActive)) {
+					return true, nil
+				}
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// commitSessions writes a single window of open tabs and a short list of closed tabs from the most recent visits, so a
+// restored session picks up where the purged history leaves off. Visits are read back through the open history
+// transaction, a streamed run no longer holds them. The Sessions directory is created with the staged files.
+func (c *chromeProfile) commitSessions() error {
+	var navigations, err = c.recentNavigations((CHROME_SESSION_TABS + CHROME_SESSION_CLOSED_TABS) * CHROME_SESSION_NAVIGATIONS)
+	if err != nil {
+		return err
+	}
+
+	var tabs = chromeSessionTabs(c.random, navigations)
+	if len(tabs) == 0 {
+		return nil
+	}
+
+	var open = 1 + c.random.Intn(CHROME_SESSION_TABS)
+	if open > len(tabs) {
+		open = len(tabs)
+	}
+
+	var stamp = webKitTimestamp(time.Now())
+
+	//-- Write open window ----------
+	{
+		var path = filepath.Join(c.dataPath+CHROME_SESSIONS_DIR, fmt.Sprintf(`%s%d`, CHROME_SESSION_PREFIX, stamp))
+		if err := writeChromeSessionFile(&c.staged, path, chromeSessionCommands(c.random, tabs[:open])); err != nil {
+			return err
+		}
+	}
+
+	//-- Write recently closed tabs ----------
+	if open < len(tabs) {
+		var path = filepath.Join(c.dataPath+CHROME_SESSIONS_DIR, fmt.Sprintf(`%s%d`, CHROME_TABS_PREFIX, stamp))
+		if err := writeChromeSessionFile(&c.staged, path, chromeTabRestoreCommands(tabs[open:])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readSessions returns the selected entry of every tab still open in the newest session file.
+func (c *chromeProfile) readSessions() ([]SessionTab, error) {
+	var path = c.dataPath + `Current Session`
+	{
+		if matches, err := filepath.Glob(filepath.Join(c.dataPath+CHROME_SESSIONS_DIR, CHROME_SESSION_PREFIX+`*`)); err != nil {
+			return nil, err
+		} else if len(matches) > 0 {
+			sort.Strings(matches)
+			path = matches[len(matches)-1]
+		}
+	}
+
+	var file, err = os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var _, commands, readErr = readSNSS(file)
+	if readErr != nil && len(commands) == 0 {
+		return nil, readErr
+	}
+
+	//-- Replay commands ----------
+	var order []int32
+	var navigations = map[int32]map[int32]SessionTab{}
+	var selected = map[int32]int32{}
+	{
+		for _, command := range commands {
+			switch command.id {
+			case chromeSessionUpdateTabNavigation:
+				if tab, index, entry, err := readChromeNavigation(command.payload); err == nil {
+					if _, ok := navigations[tab]; !ok {
+						navigations[tab] = map[int32]SessionTab{}
+						order = append(order, tab)
+					}
+					navigations[tab][index] = entry
+				}
+
+			case chromeSessionSetSelectedNavigationIdx:
+				if len(command.payload) >= 8 {
+					selected[int32(binary.LittleEndian.Uint32(command.payload))] = int32(binary.LittleEndian.Uint32(command.payload[4:]))
+				}
+
+			case chromeSessionTabClosed:
+				if len(command.payload) >= 4 {
+					delete(navigations, int32(binary.LittleEndian.Uint32(command.payload)))
+				}
+			}
+		}
+	}
+
+	//-- Collect selected entries ----------
+	var tabs []SessionTab
+	{
+		for _, tab := range order {
+			if entries, ok := navigations[tab]; ok {
+				if entry, ok := entries[selected[tab]]; ok {
+					tabs = append(tabs, entry)
+				}
+			}
+		}
+	}
+
+	//-- Return ---------
+	return tabs, nil
+}
+
+// recentNavigations reads the newest main frame visits, newest first.
+func (c *chromeProfile) recentNavigations(limit int) ([]chromeSessionNavigation, error) {
+	var navigations []chromeSessionNavigation
+
+	var rows, err = c.transactions.begin(c.historyDatabase).Raw(`SELECT urls.url, urls.title, visits.visit_time, visits.transition FROM visits JOIN urls ON urls.id = visits.url WHERE visits.transition & 255 NOT IN (?, ?) ORDER BY visits.visit_time DESC LIMIT ?`, int(TransitionAutoSubframe), int(TransitionManualSubframe), limit).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var navigation chromeSessionNavigation
+		if err := rows.Scan(&navigation.URL, &navigation.Title, &navigation.Timestamp, &navigation.Transition); err != nil {
+			return nil, err
+		}
+
+		navigations = append(navigations, navigation)
+	}
+
+	return navigations, rows.Err()
+}
+
+// chromeSessionTabs splits the most recent main frame visits, newest first, into tabs of a few navigations each.
+func chromeSessionTabs(random random, navigations []chromeSessionNavigation) []*chromeSessionTab {
+	var tabs []*chromeSessionTab
+	for len(navigations) > 0 && len(tabs) < CHROME_SESSION_TABS+CHROME_SESSION_CLOSED_TABS {
+		var size = 1 + random.Intn(CHROME_SESSION_NAVIGATIONS)
+		if size > len(navigations) {
+			size = len(navigations)
+		}
+
+		var tab = &chromeSessionTab{id: int32(len(tabs) + 2)} //NOTE: Session id 1 belongs to the window
+		for index := size - 1; index >= 0; index-- {
+			tab.navigations = append(tab.navigations, navigations[index])
+		}
+
+		tabs = append(tabs, tab)
+		navigations = navigations[size:]
+	}
+
+	return tabs
+}
+
+// chromeSessionCommands mirrors SessionService::BuildCommandsForBrowser for a single normal window.
+func chromeSessionCommands(random random, tabs []*chromeSessionTab) []snssCommand {
+	var window = int32(1)
+	var bounds = CHROME_WINDOW_BOUNDS[random.Intn(len(CHROME_WINDOW_BOUNDS))]
+
+	var commands = []snssCommand{
+		{id: chromeSessionSetWindowBounds3, payload: snssStruct(window, bounds[0], bounds[1], bounds[2], bounds[3], 1)},
+		{id: chromeSessionSetWindowType, payload: snssStruct(window, 0)},
+	}
+
+	for index, tab := range tabs {
+		var selected = len(tab.navigations) - 1
+		var lastActive = make([]byte, 8)
+		binary.LittleEndian.PutUint64(lastActive, uint64(tab.navigations[selected].Timestamp))
+
+		commands = append(commands,
+			snssCommand{id: chromeSessionSetTabWindow, payload: snssStruct(window, tab.id)},
+			snssCommand{id: chromeSessionLastActiveTime, payload: append(snssStruct(tab.id, 0), lastActive...)},
+			snssCommand{id: chromeSessionSetTabIndexInWindow, payload: snssStruct(tab.id, int32(index))},
+		)
+
+		for position, navigation := range tab.navigations {
+			commands = append(commands, snssCommand{id: chromeSessionUpdateTabNavigation, payload: chromeNavigationPickle(tab.id, int32(position), navigation)})
+		}
+
+		commands = append(commands, snssCommand{id: chromeSessionSetSelectedNavigationIdx, payload: snssStruct(tab.id, int32(selected))})
+	}
+
+	return append(commands,
+		snssCommand{id: chromeSessionSetSelectedTabInIndex, payload: snssStruct(window, 0)},
+		snssCommand{id: chromeSessionSetActiveWindow, payload: snssStruct(window)},
+	)
+}
+
+// chromeTabRestoreCommands mirrors PersistentTabRestoreService, each closed tab its selected index then navigations.
+func chromeTabRestoreCommands(tabs []*chromeSessionTab) []snssCommand {
+	var commands []snssCommand
+
+	for _, tab := range tabs {
+		var selected = len(tab.navigations) - 1
+		var entry = new(pickle).writeInt32(tab.id).writeInt32(int32(selected)).writeInt64(tab.navigations[selected].Timestamp)
+
+		commands = append(commands, snssCommand{id: chromeTabRestoreSelectedNavigationInTab, payload: entry.bytes()})
+
+		for position, navigation := range tab.navigations {
+			commands = append(commands, snssCommand{id: chromeTabRestoreUpdateTabNavigation, payload: chromeNavigationPickle(tab.id, int32(position), navigation)})
+		}
+	}
+
+	return commands
+}
+
+// chromeNavigationPickle follows SerializedNavigationEntry::WriteToPickle up to the extended info map, the fields
+// after it are optional when Chrome reads the entry back.
+func chromeNavigationPickle(tab int32, index int32, navigation chromeSessionNavigation) []byte {
+	return new(pickle).
+		writeInt32(tab).
+		writeInt32(index).
+		writeString(navigation.URL).
+		writeString16(navigation.Title).
+		writeString(``). // encoded page state, rebuilt from the URL on restore
+		writeInt32(navigation.Transition).
+		writeInt32(0).               // type mask, no POST data
+		writeString(``).             // referrer
+		writeInt32(0).               // obsolete referrer policy
+		writeString(navigation.URL). // original request URL
+		writeBool(false).            // overriding user agent
+		writeInt64(navigation.Timestamp).
+		writeString16(``). // obsolete search terms
+		writeInt32(200).   // HTTP status code
+		writeInt32(0).     // referrer policy
+		writeInt32(0).     // extended info map size
+		bytes()
+}
+
+func readChromeNavigation(payload []byte) (int32, int32, SessionTab, error) {
+	var reader, err = newPickleReader(payload)
+	if err != nil {
+		return 0, 0, SessionTab{}, err
+	}
+
+	var tab, index int32
+	var entry SessionTab
+
+	if tab, err = reader.readInt32(); err != nil {
+		return 0, 0, entry, err
+	} else if index, err = reader.readInt32(); err != nil {
+		return 0, 0, entry, err
+	} else if entry.URL, err = reader.readString(); err != nil {
+		return 0, 0, entry, err
+	} else if entry.Title, err = reader.readString16(); err != nil {
+		return 0, 0, entry, err
+	}
+
+	//-- Skip to the timestamp ----------
+	{
+		for _, read := range []func() error{
+			func() error { _, err := reader.readString(); return err },
+			func() error { _, err := reader.readInt32(); return err },
+			func() error { _, err := reader.readInt32(); return err },
+			func() error { _, err := reader.readString(); return err },
+			func() error { _, err := reader.readInt32(); return err },
+			func() error { _, err := reader.readString(); return err },
+			func() error { _, err := reader.readInt32(); return err },
+		} {
+			if err := read(); err != nil {
+				return tab, index, entry, nil //NOTE: Entries from older versions end before the timestamp
+			}
+		}
+
+		if timestamp, err := reader.readInt64(); err == nil {
+			entry.LastActive = fromWebKitTimestamp(timestamp)
+		}
+	}
+
+	return tab, index, entry, nil
+}
+
+func writeChromeSessionFile(staged *stagedFiles, path string, commands []snssCommand) error {
+	var file, err = staged.create(path, 0600)
+	if err != nil {
+		return err
+	}
+
+	if err := writeSNSS(file, commands); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
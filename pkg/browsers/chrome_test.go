@@ -0,0 +1,504 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+// CHROME_TEST_HISTORY_SCHEMA holds the History tables a Chrome profile writes to, as Chrome creates them.
+var CHROME_TEST_HISTORY_SCHEMA = []string{
+	`CREATE TABLE meta(key LONGVARCHAR NOT NULL UNIQUE PRIMARY KEY, value LONGVARCHAR)`,
+	`CREATE TABLE urls(id INTEGER PRIMARY KEY AUTOINCREMENT,url LONGVARCHAR,title LONGVARCHAR,visit_count INTEGER DEFAULT 0 NOT NULL,typed_count INTEGER DEFAULT 0 NOT NULL,last_visit_time INTEGER NOT NULL,hidden INTEGER DEFAULT 0 NOT NULL)`,
+	`CREATE TABLE visits(id INTEGER PRIMARY KEY,url INTEGER NOT NULL,visit_time INTEGER NOT NULL,from_visit INTEGER,transition INTEGER DEFAULT 0 NOT NULL,segment_id INTEGER,visit_duration INTEGER DEFAULT 0 NOT NULL,incremented_omnibox_typed_score BOOLEAN DEFAULT FALSE NOT NULL)`,
+	`CREATE TABLE visit_source(id INTEGER PRIMARY KEY,source INTEGER NOT NULL)`,
+	`CREATE TABLE keyword_search_terms (keyword_id INTEGER NOT NULL,url_id INTEGER NOT NULL,lower_term LONGVARCHAR NOT NULL,term LONGVARCHAR NOT NULL)`,
+	`CREATE TABLE segments (id INTEGER PRIMARY KEY,name VARCHAR,url_id INTEGER NON NULL)`,
+	`CREATE TABLE segment_usage (id INTEGER PRIMARY KEY,segment_id INTEGER NOT NULL,time_slot INTEGER NOT NULL,visit_count INTEGER DEFAULT 0 NOT NULL)`,
+}
+
+// CHROME_TEST_LOGIN_SCHEMA holds the Login Data tables a Chrome profile loads and writes to.
+var CHROME_TEST_LOGIN_SCHEMA = []string{
+	`CREATE TABLE meta(key LONGVARCHAR NOT NULL UNIQUE PRIMARY KEY, value LONGVARCHAR)`,
+	`CREATE TABLE logins (origin_url VARCHAR NOT NULL, action_url VARCHAR, username_element VARCHAR, username_value VARCHAR, password_element VARCHAR, password_value BLOB, submit_element VARCHAR, signon_realm VARCHAR NOT NULL, preferred INTEGER NOT NULL, date_created INTEGER NOT NULL, blacklisted_by_user INTEGER NOT NULL, scheme INTEGER NOT NULL, password_type INTEGER, times_used INTEGER, form_data BLOB, date_synced INTEGER, display_name VARCHAR, icon_url VARCHAR, federation_url VARCHAR, skip_zero_click INTEGER, generation_upload_status INTEGER, possible_username_pairs BLOB, id INTEGER PRIMARY KEY AUTOINCREMENT, date_last_used INTEGER NOT NULL DEFAULT 0, moving_blocked_for BLOB, UNIQUE (origin_url, username_element, username_value, password_element, signon_realm))`,
+	`CREATE TABLE stats (origin_domain VARCHAR NOT NULL, username_value VARCHAR, dismissal_count INTEGER, update_time INTEGER NOT NULL, UNIQUE(origin_domain, username_value))`,
+}
+
+// CHROME_TEST_WEB_DATA_SCHEMA holds the Web Data tables every Chrome version has, CHROME_TEST_ADDRESS_SCHEMAS the address
+// tables of Chrome before and after version 117.
+var CHROME_TEST_WEB_DATA_SCHEMA = []string{
+	`CREATE TABLE meta(key LONGVARCHAR NOT NULL UNIQUE PRIMARY KEY, value LONGVARCHAR)`,
+	`CREATE TABLE autofill (name VARCHAR, value VARCHAR, value_lower VARCHAR, date_created INTEGER DEFAULT 0, date_last_used INTEGER DEFAULT 0, count INTEGER DEFAULT 1, PRIMARY KEY (name, value))`,
+	`CREATE TABLE keywords (id INTEGER PRIMARY KEY,short_name VARCHAR NOT NULL,keyword VARCHAR NOT NULL,favicon_url VARCHAR NOT NULL,url VARCHAR NOT NULL,safe_for_autoreplace INTEGER,originating_url VARCHAR,date_created INTEGER DEFAULT 0,usage_count INTEGER DEFAULT 0,input_encodings VARCHAR,suggest_url VARCHAR,prepopulate_id INTEGER DEFAULT 0,created_by_policy INTEGER DEFAULT 0,last_modified INTEGER DEFAULT 0,sync_guid VARCHAR)`,
+}
+
+var CHROME_TEST_ADDRESS_SCHEMAS = map[string][]string{
+	`autofill_profiles`: {
+		`CREATE TABLE autofill_profiles ( guid VARCHAR PRIMARY KEY, company_name VARCHAR, street_address VARCHAR, dependent_locality VARCHAR, city VARCHAR, state VARCHAR, zipcode VARCHAR, sorting_code VARCHAR, country_code VARCHAR, date_modified INTEGER NOT NULL DEFAULT 0, origin VARCHAR DEFAULT '', language_code VARCHAR, use_count INTEGER NOT NULL DEFAULT 0, use_date INTEGER NOT NULL DEFAULT 0)`,
+		`CREATE TABLE autofill_profile_names ( guid VARCHAR, first_name VARCHAR, middle_name VARCHAR, last_name VARCHAR, full_name VARCHAR)`,
+		`CREATE TABLE autofill_profile_emails ( guid VARCHAR, email VARCHAR)`,
+		`CREATE TABLE autofill_profile_phones ( guid VARCHAR, number VARCHAR)`,
+	},
+	`local_addresses`: {
+		`CREATE TABLE local_addresses (guid VARCHAR PRIMARY KEY, use_count INTEGER NOT NULL DEFAULT 0, use_date INTEGER NOT NULL DEFAULT 0, date_modified INTEGER NOT NULL DEFAULT 0, language_code VARCHAR, label VARCHAR, initial_creator_id INTEGER DEFAULT 0, last_modifier_id INTEGER DEFAULT 0)`,
+		`CREATE TABLE local_addresses_type_tokens (guid VARCHAR, type INTEGER, value VARCHAR, verification_status INTEGER DEFAULT 0, observations BLOB, PRIMARY KEY (guid, type))`,
+	},
+	`none`: nil,
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+// TestChromeBookmarksChecksum checks the checksum against one worked out apart from this package, over a tree with
+// nested folders, an untitled bookmark and titles outside the Basic Multilingual Plane.
+func TestChromeBookmarksChecksum(t *testing.T) {
+	var manifest = readChromeBookmarks(t, filepath.Join(`testdata`, `chrome`, CHROME_BOOKMARKS_FILE))
+
+	if manifest.Checksum != `0f9669239057609bb0014db0ad3b815d` {
+		t.Fatalf(`fixture holds checksum %s`, manifest.Checksum)
+	} else if computed := manifest.checksum(); computed != manifest.Checksum {
+		t.Fatalf(`computed checksum %s, want %s`, computed, manifest.Checksum)
+	}
+
+	manifest.Folders[`other`].Children[0].Name = `Renamed`
+	if manifest.checksum() == manifest.Checksum {
+		t.Fatalf(`renaming a bookmark left the checksum unchanged`)
+	}
+}
+
+// TestChromeBookmarksMismatchKept loads a Bookmarks file whose checksum no longer matches and commits without a purge,
+// the tree must survive with a corrected checksum as Chromium would leave it.
+func TestChromeBookmarksMismatchKept(t *testing.T) {
+	var profile = openChromeProfile(t)
+
+	var data, err = os.ReadFile(filepath.Join(`testdata`, `chrome`, CHROME_BOOKMARKS_FILE))
+	if err != nil {
+		t.Fatalf(`ReadFile: %s`, err)
+	}
+	data = []byte(strings.Replace(string(data), `0f9669239057609bb0014db0ad3b815d`, `00000000000000000000000000000000`, 1))
+	if err := os.WriteFile(profile.dataPath+CHROME_BOOKMARKS_FILE, data, 0600); err != nil {
+		t.Fatalf(`WriteFile: %s`, err)
+	} else if profile.bookmarkFile, err = os.Open(profile.dataPath + CHROME_BOOKMARKS_FILE); err != nil {
+		t.Fatalf(`Open: %s`, err)
+	}
+
+	if err := profile.load(); err != nil {
+		t.Fatalf(`load: %s`, err)
+	} else if err := profile.commit(context.Background()); err != nil {
+		t.Fatalf(`commit: %s`, err)
+	}
+
+	var manifest = readChromeBookmarks(t, profile.dataPath+CHROME_BOOKMARKS_FILE)
+	if manifest.Checksum != `0f9669239057609bb0014db0ad3b815d` {
+		t.Errorf(`rewritten checksum %s, want the one matching the tree`, manifest.Checksum)
+	} else if folder := manifest.Folders[`bookmark_bar`].folder(`Reading ✓`); folder == nil || len(folder.Children) != 1 {
+		t.Errorf(`rewritten file lost the nested folder`)
+	}
+}
+
+func TestChromePurgeHistoryMatching(t *testing.T) {
+	var profile = openChromeProfile(t)
+	var now = time.Now()
+	var times = strings.NewReplacer(
+		`{old}`, fmt.Sprint(webKitTimestamp(now.Add(-48*time.Hour))),
+		`{recent}`, fmt.Sprint(webKitTimestamp(now.Add(-time.Hour))),
+	)
+
+	//-- Seed a search page losing one visit, a page losing all and another site sharing its segment ----------
+	for _, statement := range []string{
+		`INSERT INTO urls (id, url, visit_count, last_visit_time) VALUES (1, 'https://search.example.com/?q=term', 2, {recent}), (2, 'https://gone.example.com/', 1, {recent}), (3, 'https://other.example.org/', 1, {recent}), (4, 'https://kept.example.com/', 1, {old})`,
+		`INSERT INTO visits (id, url, visit_time, segment_id) VALUES (1, 1, {old}, 0), (2, 1, {recent}, 0), (3, 2, {recent}, 10), (4, 3, {recent}, 10), (5, 4, {old}, 0)`,
+		`INSERT INTO keyword_search_terms (keyword_id, url_id, lower_term, term) VALUES (1, 1, 'term', 'term'), (1, 2, 'gone', 'gone'), (1, 4, 'kept', 'kept')`,
+		`INSERT INTO segments (id, name, url_id) VALUES (10, 'http://gone.example.com/', 2)`,
+	} {
+		if result := profile.historyDatabase.Exec(times.Replace(statement)); result.Error != nil {
+			t.Fatalf(`INSERT: %s`, result.Error)
+		}
+	}
+
+	var filter = PurgeFilter{Domains: []string{`*.example.com`}, After: now.Add(-24 * time.Hour)}
+	if err := profile.purgeHistoryMatching(context.Background(), filter); err != nil {
+		t.Fatalf(`purgeHistoryMatching: %s`, err)
+	}
+
+	var tx = profile.transactions.begin(profile.historyDatabase)
+	var cases = []struct {
+		query string
+		want  string
+	}{
+		{`SELECT GROUP_CONCAT(id) FROM (SELECT id FROM urls ORDER BY id)`, `1,3,4`},
+		{`SELECT GROUP_CONCAT(id) FROM (SELECT id FROM visits ORDER BY id)`, `1,4,5`},
+		{`SELECT GROUP_CONCAT(url_id) FROM (SELECT url_id FROM keyword_search_terms ORDER BY url_id)`, `4`},
+		{`SELECT COUNT(*) FROM segments`, `0`},
+		{`SELECT segment_id FROM visits WHERE id = 4`, `0`},
+		{`SELECT visit_count FROM urls WHERE id = 1`, `1`},
+	}
+
+	for _, test := range cases {
+		var got string
+		if err := tx.Raw(test.query).Row().Scan(&got); err != nil {
+			t.Errorf(`%s: %s`, test.query, err)
+		} else if got != test.want {
+			t.Errorf(`%s gave '%s', want '%s'`, test.query, got, test.want)
+		}
+	}
+}
+
+// TestChromePurgeMatchingDerived keeps the top sites, shortcuts and session files a selective purge does not match, and
+// rebuilds the sessions only once one of their tabs does.
+func TestChromePurgeMatchingDerived(t *testing.T) {
+	var ctx = context.Background()
+	var profile = openChromeProfile(t)
+	var now = time.Now().Add(-time.Hour)
+
+	//-- Open Top Sites and Shortcuts holding a kept and a matching URL ----------
+	{
+		var err error
+		if profile.topSitesDatabase, err = openDatabase(profile.dataPath + CHROME_TOP_SITES_FILE); err != nil {
+			t.Fatalf(`openDatabase: %s`, err)
+		} else if profile.shortcutDatabase, err = openDatabase(profile.dataPath + CHROME_SHORTCUTS_FILE); err != nil {
+			t.Fatalf(`openDatabase: %s`, err)
+		}
+		t.Cleanup(func() {
+			profile.transactions.rollback()
+			profile.closeTopSites()
+		})
+
+		for _, statement := range []string{
+			`CREATE TABLE top_sites(url LONGVARCHAR PRIMARY KEY,url_rank INTEGER,title LONGVARCHAR,redirects LONGVARCHAR)`,
+			`INSERT INTO top_sites VALUES ('https://kept.example.org/', 0, 'Kept', 'https://kept.example.org/'), ('https://gone.example.com/', 1, 'Gone', 'https://gone.example.com/')`,
+		} {
+			if result := profile.topSitesDatabase.Exec(statement); result.Error != nil {
+				t.Fatalf(`%s: %s`, statement, result.Error)
+			}
+		}
+
+		for _, statement := range []string{
+			`CREATE TABLE omni_box_shortcuts (id VARCHAR PRIMARY KEY, text VARCHAR, fill_into_edit VARCHAR, url VARCHAR, contents VARCHAR, contents_class VARCHAR, description VARCHAR, description_class VARCHAR, transition INTEGER, type INTEGER, keyword VARCHAR, last_access_time INTEGER, number_of_hits INTEGER)`,
+			`INSERT INTO omni_box_shortcuts (id, text, url) VALUES ('1', 'kep', 'https://kept.example.org/'), ('2', 'gon', 'https://gone.example.com/')`,
+		} {
+			if result := profile.shortcutDatabase.Exec(statement); result.Error != nil {
+				t.Fatalf(`%s: %s`, statement, result.Error)
+			}
+		}
+	}
+
+	var session = func(name string, urls ...string) string {
+		var tab = &chromeSessionTab{id: 2}
+		for _, raw := range urls {
+			tab.navigations = append(tab.navigations, chromeSessionNavigation{URL: raw, Timestamp: webKitTimestamp(now)})
+		}
+
+		var path = profile.dataPath + CHROME_SESSIONS_DIR + `/` + CHROME_SESSION_PREFIX + name
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatalf(`MkdirAll: %s`, err)
+		} else if err := writeChromeSessionFile(&profile.staged, path, chromeSessionCommands(profile.random, []*chromeSessionTab{tab})); err != nil {
+			t.Fatalf(`writeChromeSessionFile: %s`, err)
+		} else if err := profile.staged.commit(); err != nil {
+			t.Fatalf(`commit: %s`, err)
+		}
+
+		return path
+	}
+
+	var purge = func() {
+		profile.purgeMatching(PurgeFilter{Domains: []string{`gone.example.com`}, Types: []DataType{DataHistory}})
+		if err := profile.commit(ctx); err != nil {
+			t.Fatalf(`commit: %s`, err)
+		}
+	}
+
+	//-- Sessions without a matching tab are left alone ----------
+	{
+		var kept = session(`1`, `https://kept.example.org/`)
+		var before, _ = os.ReadFile(kept)
+
+		purge()
+
+		if after, err := os.ReadFile(kept); err != nil || !bytes.Equal(before, after) {
+			t.Errorf(`session without a matching tab was rewritten: %v`, err)
+		}
+	}
+
+	for _, test := range []struct {
+		orm   *gorm.DB
+		query string
+	}{
+		{profile.topSitesDatabase, `SELECT GROUP_CONCAT(url) FROM top_sites`},
+		{profile.shortcutDatabase, `SELECT GROUP_CONCAT(url) FROM omni_box_shortcuts`},
+	} {
+		var got string
+		if err := test.orm.Raw(test.query).Row().Scan(&got); err != nil {
+			t.Errorf(`%s: %s`, test.query, err)
+		} else if got != `https://kept.example.org/` {
+			t.Errorf(`%s gave '%s', want only the kept URL`, test.query, got)
+		}
+	}
+
+	//-- Sessions with a matching tab are rebuilt from the remaining history ----------
+	{
+		if err := profile.AddHistory(ctx, History{Name: `Kept`, URL: `https://kept.example.org/`, Timeline: []Visit{{Time: now}}}); err != nil {
+			t.Fatalf(`AddHistory: %s`, err)
+		} else if err := profile.commit(ctx); err != nil {
+			t.Fatalf(`commit: %s`, err)
+		}
+
+		var matching = session(`2`, `https://kept.example.org/`, `https://gone.example.com/`)
+
+		purge()
+
+		expectFile(t, matching, ``)
+		if tabs, err := profile.readSessions(); err != nil {
+			t.Fatalf(`readSessions: %s`, err)
+		} else if len(tabs) != 1 || tabs[0].URL != `https://kept.example.org/` {
+			t.Errorf(`rebuilt session holds %+v, want the kept URL`, tabs)
+		}
+	}
+}
+
+// TestChromeWebDataAddresses purges and writes an address into each Web Data layout, a layout without address tables
+// must purge cleanly and refuse the address.
+func TestChromeWebDataAddresses(t *testing.T) {
+	var cases = []struct {
+		layout string
+		query  string
+		want   string
+	}{
+		{`autofill_profiles`, `SELECT p.city || ',' || n.full_name || ',' || e.email FROM autofill_profiles p JOIN autofill_profile_names n USING (guid) JOIN autofill_profile_emails e USING (guid)`, `Springfield,Ada Lovelace,ada@example.com`},
+		{`local_addresses`, `SELECT GROUP_CONCAT(type || '=' || value) FROM (SELECT type, value FROM local_addresses_type_tokens JOIN local_addresses USING (guid) ORDER BY type)`, `3=Ada,5=Lovelace,7=Ada Lovelace,9=ada@example.com,33=Springfield,36=US`},
+		{`none`, ``, ``},
+	}
+
+	for _, test := range cases {
+		t.Run(test.layout, func(t *testing.T) {
+			var profile = openChromeProfile(t)
+
+			if orm, err := openDatabase(profile.dataPath + CHROME_WEB_DATA_FILE); err != nil {
+				t.Fatalf(`openDatabase: %s`, err)
+			} else {
+				for _, statement := range append(append([]string{}, CHROME_TEST_WEB_DATA_SCHEMA...), CHROME_TEST_ADDRESS_SCHEMAS[test.layout]...) {
+					if result := orm.Exec(statement); result.Error != nil {
+						t.Fatalf(`%s: %s`, statement, result.Error)
+					}
+				}
+				orm.Close()
+			}
+
+			if err := profile.openWebData(); err != nil {
+				t.Fatalf(`openWebData: %s`, err)
+			}
+			t.Cleanup(func() { profile.closeWebData() })
+
+			var address = Address{FirstName: `Ada`, LastName: `Lovelace`, Email: `ada@example.com`, City: `Springfield`, Country: `US`, CreateWindow: time.Hour}
+			if err := profile.purgeWebData(); err != nil {
+				t.Fatalf(`purgeWebData: %s`, err)
+			} else if err := profile.AddAddress(context.Background(), address); test.query == `` {
+				if !errors.Is(err, ErrUnsupported) {
+					t.Fatalf(`AddAddress gave %v, want ErrUnsupported`, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf(`AddAddress: %s`, err)
+			} else if err := profile.commitWebData(); err != nil {
+				t.Fatalf(`commitWebData: %s`, err)
+			}
+
+			var got string
+			if err := profile.transactions.begin(profile.webDatabase).Raw(test.query).Row().Scan(&got); err != nil {
+				t.Fatalf(`%s: %s`, test.query, err)
+			} else if got != test.want {
+				t.Fatalf(`%s gave '%s', want '%s'`, test.query, got, test.want)
+			}
+		})
+	}
+}
+
+// TestChromeMissingDatabases checks a profile without Web Data or Cookies refuses their items up front, so they can go
+// elsewhere.
+func TestChromeMissingDatabases(t *testing.T) {
+	var profile = openChromeProfile(t)
+	var ctx = context.Background()
+
+	if err := profile.openWebData(); err != nil {
+		t.Fatalf(`openWebData: %s`, err)
+	} else if err := profile.openCookies(); err != nil {
+		t.Fatalf(`openCookies: %s`, err)
+	}
+
+	for name, err := range map[string]error{
+		`AddFormEntry`:    profile.AddFormEntry(ctx, FormEntry{Name: `email`, Value: `ada@example.com`}),
+		`AddAddress`:      profile.AddAddress(ctx, Address{FirstName: `Ada`}),
+		`AddSearchEngine`: profile.AddSearchEngine(ctx, SearchEngine{Name: `Example`, Keyword: `ex`, URL: `https://example.com/?q={searchTerms}`}),
+		`AddCookie`:       profile.AddCookie(ctx, Cookie{Host: `example.com`, Name: `id`, Value: `1`, CreateWindow: time.Hour}),
+	} {
+		if !errors.Is(err, ErrUnsupported) {
+			t.Errorf(`%s gave %v, want ErrUnsupported`, name, err)
+		}
+	}
+}
+
+// TestChromeTopSitesAndShortcuts ranks a history with more visited and typed URLs than either list holds.
+func TestChromeTopSitesAndShortcuts(t *testing.T) {
+	var history []*chromeHistoryURL
+	for index := 0; index < 2*CHROME_SHORTCUTS+10; index++ {
+		history = append(history, &chromeHistoryURL{
+			URL:           fmt.Sprintf(`https://www.site%02d.example.com/page?id=%d`, index, index),
+			Title:         fmt.Sprintf(`Site %d`, index),
+			VisitCount:    index + 1,
+			TypedCount:    index % 2 * index,
+			LastVisitTime: 1000 + index,
+		})
+	}
+	history = append(history, &chromeHistoryURL{URL: `https://hidden.example.com/`, VisitCount: 1000, Hidden: 1})
+
+	//-- Top sites hold the most visited shown URLs in rank order ----------
+	{
+		var sites = chromeTopSites(history)
+		if len(sites) != CHROME_TOP_SITES {
+			t.Fatalf(`%d top sites, want %d`, len(sites), CHROME_TOP_SITES)
+		}
+
+		for rank, site := range sites {
+			var want = history[len(history)-2-rank]
+			if site.URLRank != rank || site.URL != want.URL || site.Title != want.Title || site.Redirects != want.URL {
+				t.Errorf(`top site %d is %+v, want %s`, rank, site, want.URL)
+			}
+		}
+	}
+
+	//-- Shortcuts hold the most typed URLs, keyed by a prefix of the host ----------
+	{
+		var shortcuts = chromeShortcuts(newRandom(`Chrome`, `Default`), history)
+
+		var typed int
+		for _, item := range history {
+			if item.TypedCount > 0 {
+				typed++
+			}
+		}
+		if typed <= CHROME_SHORTCUTS || len(shortcuts) != CHROME_SHORTCUTS {
+			t.Fatalf(`%d shortcuts from %d typed URLs, want %d`, len(shortcuts), typed, CHROME_SHORTCUTS)
+		}
+
+		for index, shortcut := range shortcuts {
+			var host = strings.SplitN(shortcut.FillIntoEdit, `/`, 2)[0]
+			if index > 0 && shortcut.NumberOfHits > shortcuts[index-1].NumberOfHits {
+				t.Errorf(`shortcut %d has more hits than the one before it`, index)
+			} else if !strings.HasPrefix(shortcut.FillIntoEdit, `site`) || !strings.Contains(shortcut.FillIntoEdit, `/page?id=`) {
+				t.Errorf(`shortcut %d fills in '%s'`, index, shortcut.FillIntoEdit)
+			} else if len(shortcut.Text) < 3 || !strings.HasPrefix(host, shortcut.Text) {
+				t.Errorf(`shortcut %d is typed as '%s', not a prefix of '%s'`, index, shortcut.Text, host)
+			} else if shortcut.Transition != int(TransitionTyped) || shortcut.Type != CHROME_HISTORY_URL_MATCH {
+				t.Errorf(`shortcut %d has transition %d and type %d`, index, shortcut.Transition, shortcut.Type)
+			}
+		}
+
+		if most := history[len(history)-2]; shortcuts[0].URL != most.URL || shortcuts[0].NumberOfHits != most.TypedCount {
+			t.Errorf(`first shortcut is %s with %d hits, want the most typed URL`, shortcuts[0].URL, shortcuts[0].NumberOfHits)
+		}
+	}
+}
+
+// BenchmarkChromeCommit times committing a million visits spread over ten thousand urls into an empty History database,
+// staging them is left out of the measurement.
+func BenchmarkChromeCommit(b *testing.B) {
+	var ctx = context.Background()
+	var urls, visits = 10000, 100
+
+	for iteration := 0; iteration < b.N; iteration++ {
+		b.StopTimer()
+		var profile = openChromeProfile(b)
+		for index := 0; index < urls; index++ {
+			var item = History{
+				Name:        fmt.Sprintf(`Page %d`, index),
+				URL:         fmt.Sprintf(`https://site%d.example.com/page/%d`, index%500, index),
+				Visits:      visits,
+				VisitWindow: 90 * 24 * time.Hour,
+			}
+
+			if err := profile.AddHistory(ctx, item); err != nil {
+				b.Fatalf(`AddHistory: %s`, err)
+			}
+		}
+		b.StartTimer()
+
+		if err := profile.commit(ctx); err != nil {
+			b.Fatalf(`commit: %s`, err)
+		}
+
+		b.StopTimer()
+		var stored int
+		if err := profile.historyDatabase.Raw(`SELECT COUNT(*) FROM visits`).Row().Scan(&stored); err != nil {
+			b.Fatalf(`query: %s`, err)
+		} else if stored != urls*visits {
+			b.Fatalf(`History holds %d visits, want %d`, stored, urls*visits)
+		}
+		b.StartTimer()
+	}
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+func readChromeBookmarks(t *testing.T, path string) *chromeBookmarksManifest {
+	t.Helper()
+
+	var data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatalf(`ReadFile: %s`, err)
+	}
+
+	var manifest = new(chromeBookmarksManifest)
+	if err := json.Unmarshal(data, manifest); err != nil {
+		t.Fatalf(`Unmarshal: %s`, err)
+	}
+
+	return manifest
+}
+
+// openChromeProfile opens a Chrome profile over a new History database and an empty Login Data database in a temporary
+// directory, the remaining databases are left out as a profile missing them would be.
+func openChromeProfile(tb testing.TB) *chromeProfile {
+	tb.Helper()
+
+	var directory = tb.TempDir() + `/`
+	var profile = &chromeProfile{
+		name:     `Default`,
+		dataPath: directory,
+		random:   newRandom(`Chrome`, `Default`),
+	}
+	profile.bookmarkManifest = new(chromeBookmarksManifest).init(profile.random)
+
+	var err error
+	if profile.historyDatabase, err = openDatabase(filepath.Join(directory, CHROME_HISTORY_FILE)); err != nil {
+		tb.Fatalf(`openDatabase: %s`, err)
+	} else if profile.credentialDatabase, err = openDatabase(filepath.Join(directory, CHROME_LOGIN_DATA_FILE)); err != nil {
+		tb.Fatalf(`openDatabase: %s`, err)
+	}
+	tb.Cleanup(func() {
+		profile.transactions.rollback()
+		profile.historyDatabase.Close()
+		profile.credentialDatabase.Close()
+	})
+
+	for _, statement := range CHROME_TEST_HISTORY_SCHEMA {
+		if result := profile.historyDatabase.Exec(statement); result.Error != nil {
+			tb.Fatalf(`%s: %s`, statement, result.Error)
+		}
+	}
+	for _, statement := range CHROME_TEST_LOGIN_SCHEMA {
+		if result := profile.credentialDatabase.Exec(statement); result.Error != nil {
+			tb.Fatalf(`%s: %s`, statement, result.Error)
+		}
+	}
+
+	return profile
+}
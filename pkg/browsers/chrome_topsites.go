@@ -0,0 +1,278 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+var (
+	CHROME_TOP_SITES_FILE = `Top Sites`
+	CHROME_SHORTCUTS_FILE = `Shortcuts`
+	CHROME_TOP_SITES      = 10
+	CHROME_SHORTCUTS      = 25 // Shortcuts only pay off for the few URLs typed again and again
+
+	CHROME_HISTORY_URL_MATCH = 1 // AutocompleteMatchType::HISTORY_URL
+)
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+type chromeTopSite struct {
+	URL       string `gorm:"column:url;primary_key"`
+	URLRank   int    `gorm:"column:url_rank"`
+	Title     string `gorm:"column:title"`
+	Redirects string `gorm:"column:redirects"`
+}
+
+type chromeShortcut struct {
+	//-- Primary Key ----------
+	ID string `gorm:"column:id;primary_key"`
+
+	//-- User Variables ----------
+	Text         string `gorm:"column:text"`
+	FillIntoEdit string `gorm:"column:fill_into_edit"`
+	URL          string `gorm:"column:url"`
+	Contents     string `gorm:"column:contents"`
+	Description  string `gorm:"column:description"`
+
+	//-- System Variables ----------
+	ContentsClass    string `gorm:"column:contents_class"`
+	DescriptionClass string `gorm:"column:description_class"`
+	Transition       int    `gorm:"column:transition"`
+	Type             int    `gorm:"column:type"`
+	Keyword          string `gorm:"column:keyword"`
+	LastAccessTime   int    `gorm:"column:last_access_time"`
+	NumberOfHits     int    `gorm:"column:number_of_hits"`
+}
+
+func (chromeShortcut) TableName() string {
+	return `omni_box_shortcuts`
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+func (c *chromeProfile) openTopSites() error {
+	if orm, err := openOptionalDatabase(c.dataPath + CHROME_TOP_SITES_FILE); err != nil {
+		return fileError(c.dataPath+CHROME_TOP_SITES_FILE, err)
+	} else {
+		c.topSitesDatabase = orm
+	}
+
+	if orm, err := openOptionalDatabase(c.dataPath + CHROME_SHORTCUTS_FILE); err != nil {
+		return fileError(c.dataPath+CHROME_SHORTCUTS_FILE, err)
+	} else {
+		c.shortcutDatabase = orm
+	}
+
+	return nil
+}
+
+func (c *chromeProfile) closeTopSites() error {
+	if c.topSitesDatabase != nil {
+		if err := c.topSitesDatabase.Close(); err != nil {
+			return fileError(c.dataPath+CHROME_TOP_SITES_FILE, err)
+		}
+	}
+
+	if c.shortcutDatabase != nil {
+		if err := c.shortcutDatabase.Close(); err != nil {
+			return fileError(c.dataPath+CHROME_SHORTCUTS_FILE, err)
+		}
+	}
+
+	return nil
+}
+
+// commitTopSites rewrites the new tab page and omnibox shortcut tables, both are derived from history so they are
+// regenerated rather than appended to, which also empties them after a purge. A selective history purge leaves them to
+// purgeTopSitesMatching instead, keeping the rows it does not match.
+func (c *chromeProfile) commitTopSites() error {
+	if !c.purging && c.purgingHistory() {
+		return nil
+	}
+
+	return c.writeTopSites(c.historyItems)
+}
+
+func (c *chromeProfile) writeTopSites(history []*chromeHistoryURL) error {
+	//-- Rewrite most visited sites ----------
+	if c.topSitesDatabase != nil {
+		var tx = c.transactions.begin(c.topSitesDatabase)
+		var table = chromeTopSitesTable(tx)
+
+		if result := tx.Exec(`DELETE FROM ` + table); result.Error != nil {
+			return fileError(c.dataPath+CHROME_TOP_SITES_FILE, result.Error)
+		}
+
+		for _, site := range chromeTopSites(history) {
+			if result := tx.Table(table).Create(site); result.Error != nil {
+				return fileError(c.dataPath+CHROME_TOP_SITES_FILE, result.Error)
+			}
+		}
+	}
+
+	//-- Rewrite omnibox shortcuts ----------
+	if c.shortcutDatabase != nil {
+		var tx = c.transactions.begin(c.shortcutDatabase)
+
+		if result := tx.Exec(`DELETE FROM omni_box_shortcuts`); result.Error != nil {
+			return fileError(c.dataPath+CHROME_SHORTCUTS_FILE, result.Error)
+		}
+
+		for _, shortcut := range chromeShortcuts(c.random, history) {
+			if result := tx.Create(shortcut); result.Error != nil {
+				return fileError(c.dataPath+CHROME_SHORTCUTS_FILE, result.Error)
+			}
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+// purgeTopSitesMatching deletes the top sites and omnibox shortcuts whose URL matches filter. Neither keeps the times
+// of the visits behind it, so a timed filter removes them on the URL alone.
+func (c *chromeProfile) purgeTopSitesMatching(ctx context.Context, filter PurgeFilter) error {
+	//-- Purge matching most visited sites ----------
+	if c.topSitesDatabase != nil {
+		var tx = c.transactions.begin(c.topSitesDatabase)
+		var table = chromeTopSitesTable(tx)
+
+		if matched, err := chromeMatchingURLs(tx, table, filter); err != nil {
+			return fileError(c.dataPath+CHROME_TOP_SITES_FILE, err)
+		} else if err := execBatched(ctx, tx, `DELETE FROM `+table+` WHERE url IN (%s)`, matched); err != nil {
+			return fileError(c.dataPath+CHROME_TOP_SITES_FILE, err)
+		}
+	}
+
+	//-- Purge matching omnibox shortcuts ----------
+	if c.shortcutDatabase != nil {
+		var tx = c.transactions.begin(c.shortcutDatabase)
+
+		if matched, err := chromeMatchingURLs(tx, `omni_box_shortcuts`, filter); err != nil {
+			return fileError(c.dataPath+CHROME_SHORTCUTS_FILE, err)
+		} else if err := execBatched(ctx, tx, `DELETE FROM omni_box_shortcuts WHERE url IN (%s)`, matched); err != nil {
+			return fileError(c.dataPath+CHROME_SHORTCUTS_FILE, err)
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func chromeTopSitesTable(tx *gorm.DB) string {
+	if !tx.HasTable(`top_sites`) {
+		return `thumbnails` //NOTE: Top Sites schema versions before 4 keep the same columns here
+	}
+
+	return `top_sites`
+}
+
+// chromeMatchingURLs returns the distinct urls of table that match filter.
+func chromeMatchingURLs(tx *gorm.DB, table string, filter PurgeFilter) ([]interface{}, error) {
+	var rows, err = tx.Raw(`SELECT DISTINCT url FROM ` + table).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matched []interface{}
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			return nil, err
+		} else if filter.matchesURL(raw) {
+			matched = append(matched, raw)
+		}
+	}
+
+	return matched, rows.Err()
+}
+
+func chromeTopSites(history []*chromeHistoryURL) []*chromeTopSite {
+	var ranked = make([]*chromeHistoryURL, 0, len(history))
+	for _, item := range history {
+		if item.VisitCount > 0 && item.Hidden == 0 {
+			ranked = append(ranked, item)
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].VisitCount > ranked[j].VisitCount })
+
+	if len(ranked) > CHROME_TOP_SITES {
+		ranked = ranked[:CHROME_TOP_SITES]
+	}
+
+	var sites []*chromeTopSite
+	for rank, item := range ranked {
+		sites = append(sites, &chromeTopSite{URL: item.URL, URLRank: rank, Title: item.Title, Redirects: item.URL})
+	}
+
+	return sites
+}
+
+// chromeShortcuts turns the most typed URLs into the omnibox shortcuts Chrome learns from, keyed by the host prefix the
+// user would have typed before accepting the suggestion.
+func chromeShortcuts(random random, history []*chromeHistoryURL) []*chromeShortcut {
+	var ranked = make([]*chromeHistoryURL, 0, len(history))
+	for _, item := range history {
+		if item.TypedCount > 0 {
+			ranked = append(ranked, item)
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].TypedCount > ranked[j].TypedCount })
+
+	if len(ranked) > CHROME_SHORTCUTS {
+		ranked = ranked[:CHROME_SHORTCUTS]
+	}
+
+	var shortcuts []*chromeShortcut
+	for _, item := range ranked {
+		var hits, lastTyped = item.TypedCount, item.lastTyped
+		if lastTyped == 0 {
+			lastTyped = item.LastVisitTime //NOTE: Rows loaded from disk carry counts but not visits
+		}
+
+		var display = chromeDisplayURL(item.URL)
+		var typed = display
+		if host := strings.SplitN(display, `/`, 2)[0]; len(host) > 3 {
+			typed = host[:3+random.Intn(len(host)-2)]
+		}
+
+		shortcuts = append(shortcuts, &chromeShortcut{
+			ID:               strings.ToUpper(random.guid()),
+			Text:             typed,
+			FillIntoEdit:     display,
+			URL:              item.URL,
+			Contents:         display,
+			ContentsClass:    `0,1`,
+			Description:      item.Title,
+			DescriptionClass: `0,0`,
+			Transition:       int(TransitionTyped),
+			Type:             CHROME_HISTORY_URL_MATCH,
+			LastAccessTime:   lastTyped,
+			NumberOfHits:     hits,
+		})
+	}
+
+	return shortcuts
+}
+
+func chromeDisplayURL(raw string) string {
+	if parsed, err := url.Parse(raw); err != nil || parsed.Host == `` {
+		return raw
+	} else {
+		var display = strings.TrimPrefix(parsed.Host, `www.`) + parsed.EscapedPath()
+		if parsed.RawQuery != `` {
+			display = display + `?` + parsed.RawQuery
+		}
+		return strings.TrimSuffix(display, `/`)
+	}
+}
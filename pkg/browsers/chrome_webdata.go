@@ -0,0 +1,405 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+var (
+	CHROME_WEB_DATA_FILE = `Web Data`
+)
+
+// Address field types and verification statuses, components/autofill/core/browser/field_types.h
+const (
+	chromeAddressFirstName = 3
+	chromeAddressLastName  = 5
+	chromeAddressFullName  = 7
+	chromeAddressEmail     = 9
+	chromeAddressPhone     = 14
+	chromeAddressCity      = 33
+	chromeAddressState     = 34
+	chromeAddressZip       = 35
+	chromeAddressCountry   = 36
+	chromeAddressCompany   = 60
+	chromeAddressStreet    = 77
+
+	chromeAddressFormatted = 2
+	chromeAddressObserved  = 3
+)
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+type chromeAutofill struct {
+	//-- Primary Key ----------
+	Name  string `gorm:"column:name;primary_key"`
+	Value string `gorm:"column:value;primary_key"`
+
+	//-- User Variables ----------
+	ValueLower   string `gorm:"column:value_lower"`
+	DateCreated  int64  `gorm:"column:date_created"`
+	DateLastUsed int64  `gorm:"column:date_last_used"`
+	Count        int    `gorm:"column:count"`
+}
+
+func (chromeAutofill) TableName() string {
+	return `autofill`
+}
+
+type chromeAutofillProfile struct {
+	//-- Primary Key ----------
+	GUID string `gorm:"column:guid;primary_key"`
+
+	//-- User Variables ----------
+	CompanyName   string `gorm:"column:company_name"`
+	StreetAddress string `gorm:"column:street_address"`
+	City          string `gorm:"column:city"`
+	State         string `gorm:"column:state"`
+	Zipcode       string `gorm:"column:zipcode"`
+	CountryCode   string `gorm:"column:country_code"`
+
+	//-- System Variables ----------
+	DateModified int64  `gorm:"column:date_modified"`
+	Origin       string `gorm:"column:origin"`
+	LanguageCode string `gorm:"column:language_code"`
+	UseCount     int    `gorm:"column:use_count"`
+	UseDate      int64  `gorm:"column:use_date"`
+}
+
+func (chromeAutofillProfile) TableName() string {
+	return `autofill_profiles`
+}
+
+type chromeAutofillName struct {
+	GUID      string `gorm:"column:guid"`
+	FirstName string `gorm:"column:first_name"`
+	LastName  string `gorm:"column:last_name"`
+	FullName  string `gorm:"column:full_name"`
+}
+
+func (chromeAutofillName) TableName() string {
+	return `autofill_profile_names`
+}
+
+type chromeAutofillEmail struct {
+	GUID  string `gorm:"column:guid"`
+	Email string `gorm:"column:email"`
+}
+
+func (chromeAutofillEmail) TableName() string {
+	return `autofill_profile_emails`
+}
+
+type chromeAutofillPhone struct {
+	GUID   string `gorm:"column:guid"`
+	Number string `gorm:"column:number"`
+}
+
+func (chromeAutofillPhone) TableName() string {
+	return `autofill_profile_phones`
+}
+
+type chromeLocalAddress struct {
+	//-- Primary Key ----------
+	GUID string `gorm:"column:guid;primary_key"`
+
+	//-- System Variables ----------
+	UseCount     int    `gorm:"column:use_count"`
+	UseDate      int64  `gorm:"column:use_date"`
+	DateModified int64  `gorm:"column:date_modified"`
+	LanguageCode string `gorm:"column:language_code"`
+}
+
+func (chromeLocalAddress) TableName() string {
+	return `local_addresses`
+}
+
+type chromeAddressToken struct {
+	GUID               string `gorm:"column:guid"`
+	Type               int    `gorm:"column:type"`
+	Value              string `gorm:"column:value"`
+	VerificationStatus int    `gorm:"column:verification_status"`
+}
+
+func (chromeAddressToken) TableName() string {
+	return `local_addresses_type_tokens`
+}
+
+type chromeKeyword struct {
+	//-- Primary Key ----------
+	ID uint `gorm:"column:id;primary_key"`
+
+	//-- User Variables ----------
+	ShortName  string `gorm:"column:short_name"`
+	Keyword    string `gorm:"column:keyword"`
+	FaviconURL string `gorm:"column:favicon_url"`
+	URL        string `gorm:"column:url"`
+
+	//-- System Variables ----------
+	SafeForAutoreplace int    `gorm:"column:safe_for_autoreplace"`
+	DateCreated        int64  `gorm:"column:date_created"`
+	UsageCount         int    `gorm:"column:usage_count"`
+	InputEncodings     string `gorm:"column:input_encodings"`
+	PrepopulateID      int    `gorm:"column:prepopulate_id"`
+	LastModified       int64  `gorm:"column:last_modified"`
+	SyncGUID           string `gorm:"column:sync_guid"`
+}
+
+func (chromeKeyword) TableName() string {
+	return `keywords`
+}
+
+// chromeAddress holds the rows of one address in whichever layout the profile's Web Data uses.
+type chromeAddress struct {
+	rows []interface{}
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+func (c *chromeProfile) AddFormEntry(ctx context.Context, item FormEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	} else if err := c.requireWebData(); err != nil {
+		return err
+	}
+
+	//-- Merge repeated values, (name, value) is the table's primary key ----------
+	{
+		var uses = item.Uses
+		if uses < 1 {
+			uses = 1
+		}
+
+		for _, existing := range c.formItems {
+			if existing.Name == item.Name && existing.Value == item.Value {
+				existing.Count = existing.Count + uses
+				return nil
+			}
+		}
+
+		var created = c.random.unixTimestamp(item.CreateWindow)
+		c.formItems = append(c.formItems, &chromeAutofill{
+			Name:         item.Name,
+			Value:        item.Value,
+			ValueLower:   strings.ToLower(item.Value),
+			DateCreated:  created,
+			DateLastUsed: created + c.random.Int63n(time.Now().Unix()-created+1),
+			Count:        uses,
+		})
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (c *chromeProfile) AddAddress(ctx context.Context, item Address) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	} else if err := c.requireWebData(); err != nil {
+		return err
+	}
+
+	//-- Create address entry ----------
+	{
+		var guid = c.random.guid()
+		var modified = c.random.unixTimestamp(item.CreateWindow)
+		var used = modified + c.random.Int63n(time.Now().Unix()-modified+1)
+		var fullName = strings.TrimSpace(item.FirstName + ` ` + item.LastName)
+
+		switch c.addressTable {
+		case `autofill_profiles`:
+			c.addressItems = append(c.addressItems, &chromeAddress{rows: []interface{}{
+				&chromeAutofillProfile{
+					GUID:          guid,
+					CompanyName:   item.Company,
+					StreetAddress: item.Street,
+					City:          item.City,
+					State:         item.State,
+					Zipcode:       item.Zip,
+					CountryCode:   item.Country,
+					DateModified:  modified,
+					LanguageCode:  `en`,
+					UseCount:      item.Uses,
+					UseDate:       used,
+				},
+				&chromeAutofillName{GUID: guid, FirstName: item.FirstName, LastName: item.LastName, FullName: fullName},
+				&chromeAutofillEmail{GUID: guid, Email: item.Email},
+				&chromeAutofillPhone{GUID: guid, Number: item.Phone},
+			}})
+
+		case `local_addresses`: //NOTE: Chrome 117 keeps one row per filled field type instead of a column each
+			var address = &chromeAddress{rows: []interface{}{
+				&chromeLocalAddress{GUID: guid, UseCount: item.Uses, UseDate: used, DateModified: modified, LanguageCode: `en`},
+			}}
+
+			for _, token := range []chromeAddressToken{
+				{Type: chromeAddressFirstName, Value: item.FirstName, VerificationStatus: chromeAddressObserved},
+				{Type: chromeAddressLastName, Value: item.LastName, VerificationStatus: chromeAddressObserved},
+				{Type: chromeAddressFullName, Value: fullName, VerificationStatus: chromeAddressFormatted},
+				{Type: chromeAddressEmail, Value: item.Email},
+				{Type: chromeAddressPhone, Value: item.Phone},
+				{Type: chromeAddressCompany, Value: item.Company},
+				{Type: chromeAddressStreet, Value: item.Street, VerificationStatus: chromeAddressObserved},
+				{Type: chromeAddressCity, Value: item.City, VerificationStatus: chromeAddressObserved},
+				{Type: chromeAddressState, Value: item.State, VerificationStatus: chromeAddressObserved},
+				{Type: chromeAddressZip, Value: item.Zip, VerificationStatus: chromeAddressObserved},
+				{Type: chromeAddressCountry, Value: item.Country, VerificationStatus: chromeAddressObserved},
+			} {
+				if token.Value != `` {
+					token.GUID = guid
+					address.rows = append(address.rows, &token)
+				}
+			}
+
+			c.addressItems = append(c.addressItems, address)
+
+		default:
+			return fileError(c.dataPath+CHROME_WEB_DATA_FILE, fmt.Errorf(`%w, no address table this version of Chrome is known to read`, ErrUnsupported))
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (c *chromeProfile) AddSearchEngine(ctx context.Context, item SearchEngine) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	} else if err := c.requireWebData(); err != nil {
+		return err
+	}
+
+	//-- Create keyword entry ----------
+	{
+		var created = c.random.webKitTimestamp(item.CreateWindow)
+
+		c.keywordItems = append(c.keywordItems, &chromeKeyword{
+			ShortName:          item.Name,
+			Keyword:            item.Keyword,
+			URL:                item.URL,
+			SafeForAutoreplace: 0,
+			DateCreated:        created,
+			UsageCount:         item.Uses,
+			InputEncodings:     `UTF-8`,
+			LastModified:       created,
+			SyncGUID:           c.random.guid(),
+		})
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+func (c *chromeProfile) openWebData() error {
+	if orm, err := openOptionalDatabase(c.dataPath + CHROME_WEB_DATA_FILE); err != nil {
+		return err
+	} else {
+		c.webDatabase = orm
+	}
+
+	//-- Find where addresses are kept, Chrome 117 replaced autofill_profiles ----------
+	if c.webDatabase != nil {
+		for _, table := range []string{`local_addresses`, `autofill_profiles`} {
+			if c.webDatabase.HasTable(table) {
+				c.addressTable = table
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// requireWebData refuses items for a profile Chrome has not created Web Data for yet, so they go to another profile.
+func (c *chromeProfile) requireWebData() error {
+	if c.webDatabase == nil {
+		return fileError(c.dataPath+CHROME_WEB_DATA_FILE, fmt.Errorf(`%w, start Chrome once to create the database`, ErrUnsupported))
+	}
+
+	return nil
+}
+
+func (c *chromeProfile) closeWebData() error {
+	if c.webDatabase != nil {
+		return c.webDatabase.Close()
+	}
+
+	return nil
+}
+
+func (c *chromeProfile) purgeWebData() error {
+	if c.webDatabase == nil {
+		return nil
+	}
+
+	var tx = c.transactions.begin(c.webDatabase)
+
+	//-- Purge autofill values and addresses ----------
+	{
+		for _, table := range []string{`autofill`, `autofill_profiles`, `autofill_profile_names`, `autofill_profile_emails`, `autofill_profile_phones`, `local_addresses`, `local_addresses_type_tokens`} {
+			if !tx.HasTable(table) {
+				continue
+			} else if result := tx.Exec(fmt.Sprintf(`DELETE FROM %s`, table)); result.Error != nil {
+				return result.Error
+			}
+		}
+	}
+
+	//-- Purge user search engines, prepopulated engines are part of a fresh install ----------
+	{
+		if result := tx.Exec(`DELETE FROM keywords WHERE prepopulate_id = 0`); result.Error != nil {
+			return result.Error
+		}
+	}
+
+	return nil
+}
+
+func (c *chromeProfile) commitWebData() error {
+	if c.webDatabase == nil {
+		return nil
+	}
+
+	var tx = c.transactions.begin(c.webDatabase)
+
+	//-- Commit form values, merging into rows a selective purge kept ----------
+	{
+		for _, item := range c.formItems {
+			var result = tx.Exec(`UPDATE autofill SET count = count + ?, date_last_used = MAX(date_last_used, ?) WHERE name = ? AND value = ?`, item.Count, item.DateLastUsed, item.Name, item.Value)
+			if result.Error != nil {
+				return result.Error
+			} else if result.RowsAffected > 0 {
+				continue
+			}
+
+			if result := tx.Create(item); result.Error != nil {
+				return result.Error
+			}
+		}
+	}
+
+	//-- Commit addresses ----------
+	{
+		for _, item := range c.addressItems {
+			for _, row := range item.rows {
+				if result := tx.Create(row); result.Error != nil {
+					return result.Error
+				}
+			}
+		}
+	}
+
+	//-- Commit search engines ----------
+	{
+		for _, item := range c.keywordItems {
+			if result := tx.Create(item); result.Error != nil {
+				return result.Error
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,864 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+var (
+	EPIPHANY_HISTORY_FILE   = `ephy-history.db`
+	EPIPHANY_BOOKMARKS_FILE = `bookmarks.gvdb`
+	EPIPHANY_SESSION_FILE   = `session_state.xml`
+	EPIPHANY_TYPED_ONE_IN_X = 8
+
+	EPIPHANY_TAGS_TABLE         = `tags`
+	EPIPHANY_BOOKMARKS_TABLE    = `bookmarks`
+	EPIPHANY_BOOKMARK_SIGNATURE = `(xssdbas)` // Time added, title, sync id, server modified time, uploaded and tags
+	EPIPHANY_FAVORITES_TAG      = `Favorites`
+	EPIPHANY_FAVORITE_ONE_IN_X  = 4
+	EPIPHANY_UNTAGGED_TITLE     = `Untagged`
+
+	EPIPHANY_NATIVE_PROFILE    = `Default`
+	EPIPHANY_FLATPAK_PROFILE   = `Flatpak`
+	EPIPHANY_LINUX_DATA_PATH   = fmt.Sprintf(`%s/.local/share/epiphany/`, os.Getenv(`HOME`))
+	EPIPHANY_FLATPAK_DATA_PATH = fmt.Sprintf(`%s/.var/app/org.gnome.Epiphany/data/epiphany/`, os.Getenv(`HOME`))
+
+	EPIPHANY_HISTORY_SCHEMA = []string{
+		`CREATE TABLE hosts (id INTEGER PRIMARY KEY,url LONGVARCAR,title LONGVARCAR,visit_count INTEGER DEFAULT 0 NOT NULL,zoom_level REAL DEFAULT 0.0)`,
+		`CREATE TABLE urls (id INTEGER PRIMARY KEY,host INTEGER NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,url LONGVARCAR,title LONGVARCAR,sync_id LONGVARCAR,visit_count INTEGER DEFAULT 0 NOT NULL,typed_count INTEGER DEFAULT 0 NOT NULL,last_visit_time INTEGER,thumbnail_update_time INTEGER DEFAULT 0,hidden_from_overview INTEGER DEFAULT 0)`,
+		`CREATE TABLE visits (id INTEGER PRIMARY KEY,url INTEGER NOT NULL REFERENCES urls(id) ON DELETE CASCADE,visit_time INTEGER NOT NULL,visit_type INTEGER NOT NULL,referring_visit INTEGER)`,
+	}
+)
+
+// Page visit types, lib/history/ephy-history-types.h
+const (
+	epiphanyVisitLink           = 1
+	epiphanyVisitTyped          = 2
+	epiphanyVisitManualSubframe = 3
+	epiphanyVisitBookmark       = 5
+)
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+type epiphany struct {
+	profiles []*epiphanyProfile
+}
+
+type epiphanyProfile struct {
+	name     string
+	dataPath string
+
+	historyDatabase *gorm.DB
+	historyMissing  bool // ephy-history.db is only created by a commit with history to write
+	transactions    transactions
+	staged          stagedFiles
+	random          random
+	purging         bool
+
+	hostIndex     map[string]*epiphanyHost
+	hostItems     []*epiphanyHost
+	historyIndex  map[string]*epiphanyURL
+	historyItems  []*epiphanyURL
+	bookmarkItems []*epiphanyBookmark
+	tags          []string
+}
+
+type epiphanyHost struct {
+	//-- Primary Key ----------
+	ID uint `gorm:"column:id;primary_key"`
+
+	//-- User Variables ----------
+	URL        string `gorm:"column:url"`
+	Title      string `gorm:"column:title"`
+	VisitCount int    `gorm:"column:visit_count"`
+
+	//-- System Variables ----------
+	ZoomLevel float64 `gorm:"column:zoom_level"`
+}
+
+func (epiphanyHost) TableName() string {
+	return `hosts`
+}
+
+type epiphanyURL struct {
+	//-- Primary Key ----------
+	ID uint `gorm:"column:id;primary_key"`
+
+	//-- User Variables ----------
+	HostID        uint   `gorm:"column:host"`
+	URL           string `gorm:"column:url"`
+	Title         string `gorm:"column:title"`
+	VisitCount    int    `gorm:"column:visit_count"`
+	TypedCount    int    `gorm:"column:typed_count"`
+	LastVisitTime int64  `gorm:"column:last_visit_time"`
+
+	//-- Relations ----------
+	Visits []*epiphanyVisit `gorm:"foreignkey:URLID"`
+
+	//-- System Variables ----------
+	SyncID              *string `gorm:"column:sync_id"`
+	ThumbnailUpdateTime int64   `gorm:"column:thumbnail_update_time"`
+	HiddenFromOverview  int     `gorm:"column:hidden_from_overview"`
+
+	host *epiphanyHost
+}
+
+func (epiphanyURL) TableName() string {
+	return `urls`
+}
+
+type epiphanyVisit struct {
+	//-- Primary Key ----------
+	ID uint `gorm:"column:id;primary_key"`
+
+	//-- User Variables ----------
+	URLID     uint  `gorm:"column:url"`
+	VisitTime int64 `gorm:"column:visit_time"`
+	VisitType int   `gorm:"column:visit_type"`
+
+	//-- System Variables ----------
+	ReferringVisit *uint `gorm:"column:referring_visit"`
+}
+
+func (epiphanyVisit) TableName() string {
+	return `visits`
+}
+
+// epiphanyBookmark is one entry of the bookmarks table in bookmarks.gvdb, which is keyed by URL and has tags in place
+// of folders.
+type epiphanyBookmark struct {
+	url      string
+	title    string
+	id       string
+	added    int64
+	modified float64
+	uploaded bool
+	tags     []string
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+func (e *epiphany) Metadata() Metadata {
+	var metadata = Metadata{Name: `Epiphany`, Engine: `WebKit`}
+	for _, profile := range e.profiles {
+		metadata.Profiles = append(metadata.Profiles, profile.name)
+	}
+
+	return metadata
+}
+
+func (e *epiphany) Profiles() []Profile {
+	var profiles []Profile
+	for _, profile := range e.profiles {
+		profiles = append(profiles, profile)
+	}
+
+	return profiles
+}
+
+func (e *epiphanyProfile) Name() string {
+	return e.name
+}
+
+func (e *epiphanyProfile) Path() string {
+	return e.dataPath
+}
+
+func (e *epiphanyProfile) Browser() string {
+	return `Epiphany`
+}
+
+func (e *epiphanyProfile) AddHistory(ctx context.Context, item History) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	//-- Find or create host and url ----------
+	var entry *epiphanyURL
+	{
+		var host = e.host(item.URL)
+
+		if e.historyIndex == nil {
+			e.historyIndex = map[string]*epiphanyURL{}
+		}
+		entry = e.historyIndex[item.URL]
+
+		if entry == nil {
+			entry = &epiphanyURL{URL: item.URL, Title: item.Name, host: host}
+			e.historyItems = append(e.historyItems, entry)
+			e.historyIndex[item.URL] = entry
+		}
+	}
+
+	//-- Add visits ----------
+	{
+		var visits []*epiphanyVisit
+		if len(item.Timeline) > 0 {
+			for _, recorded := range item.Timeline {
+				visits = append(visits, &epiphanyVisit{VisitTime: epiphanyTimestamp(recorded.Time), VisitType: epiphanyVisitType(recorded.Transition)})
+			}
+		} else {
+			for i := 0; i < item.Visits; i++ {
+				var visit = &epiphanyVisit{VisitTime: e.random.unixTimestamp(item.VisitWindow), VisitType: epiphanyVisitLink}
+				if e.random.Intn(EPIPHANY_TYPED_ONE_IN_X) == 0 {
+					visit.VisitType = epiphanyVisitTyped
+				}
+
+				visits = append(visits, visit)
+			}
+		}
+
+		//-- Derive counters from visits ----------
+		for _, visit := range visits {
+			if visit.VisitTime > entry.LastVisitTime {
+				entry.LastVisitTime = visit.VisitTime
+			}
+
+			if visit.VisitType == epiphanyVisitTyped {
+				entry.TypedCount++
+			}
+
+			entry.VisitCount++
+			entry.host.VisitCount++
+		}
+
+		entry.Visits = append(entry.Visits, visits...)
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (e *epiphanyProfile) AddBookmark(ctx context.Context, item Bookmark) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	//-- Create bookmark, folders become tags ----------
+	var bookmark = &epiphanyBookmark{
+		url:   item.URL,
+		title: item.Name,
+		id:    firefoxGUID(e.random), //NOTE: Sync ids share the twelve character form of Firefox Sync's
+		tags:  append([]string{}, item.Folder...),
+	}
+	{
+		if item.CreatedAt.IsZero() {
+			bookmark.added = e.random.prTimestamp(item.CreateWindow)
+		} else {
+			bookmark.added = prTimestamp(item.CreatedAt) //NOTE: Unlike history, time_added is GLib's real time in microseconds
+		}
+
+		if e.random.Intn(EPIPHANY_FAVORITE_ONE_IN_X) == 0 {
+			bookmark.tags = append(bookmark.tags, EPIPHANY_FAVORITES_TAG)
+		}
+
+		for _, tag := range bookmark.tags {
+			e.addTag(tag)
+		}
+	}
+
+	//-- Insert, urls are unique keys so a repeat replaces the earlier bookmark ----------
+	{
+		for index, existing := range e.bookmarkItems {
+			if existing.url == bookmark.url {
+				e.bookmarkItems = append(e.bookmarkItems[:index], e.bookmarkItems[index+1:]...)
+				break
+			}
+		}
+
+		e.bookmarkItems = append(e.bookmarkItems, bookmark)
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (e *epiphanyProfile) AddCredential(ctx context.Context, item Credential) error {
+	//TODO: Passwords are kept by libsecret in the user's keyring
+	return ErrUnsupported
+}
+
+func (e *epiphanyProfile) AddFormEntry(ctx context.Context, item FormEntry) error {
+	//TODO: Form values belong in WebKit's WebsiteData/FormData database
+	return ErrUnsupported
+}
+
+func (e *epiphanyProfile) AddCookie(ctx context.Context, item Cookie) error {
+	//TODO: Cookies belong in WebKit's cookies.sqlite, which shares Firefox's moz_cookies schema
+	return ErrUnsupported
+}
+
+func (e *epiphanyProfile) AddDownload(ctx context.Context, item Download) error {
+	//TODO: Epiphany only remembers downloads for the current session
+	return ErrUnsupported
+}
+
+func (e *epiphanyProfile) AddAddress(ctx context.Context, item Address) error {
+	//TODO: Epiphany has no address autofill
+	return ErrUnsupported
+}
+
+func (e *epiphanyProfile) AddSearchEngine(ctx context.Context, item SearchEngine) error {
+	//TODO: Search engines belong in the org.gnome.Epiphany search-engine-providers GSettings key
+	return ErrUnsupported
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+func (e *epiphany) Open(ctx context.Context) error {
+	//-- Detect native and Flatpak installs ----------
+	var profiles []*epiphanyProfile
+	{
+		for name, path := range map[string]string{EPIPHANY_NATIVE_PROFILE: EPIPHANY_LINUX_DATA_PATH, EPIPHANY_FLATPAK_PROFILE: EPIPHANY_FLATPAK_DATA_PATH} {
+			if info, err := os.Stat(path); err == nil && info.IsDir() {
+				profiles = append(profiles, &epiphanyProfile{name: name, dataPath: path, random: newRandom(`Epiphany`, name)})
+			}
+		}
+
+		sort.Slice(profiles, func(i, j int) bool { return profiles[i].name < profiles[j].name })
+	}
+
+	//-- Connect to detected profiles, those that fail are reported while the rest stay usable ----------
+	var errs Errors
+	{
+		for _, profile := range profiles {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if err := profile.open(); err != nil {
+				errs = append(errs, profileError(profile, `open`, err))
+			} else {
+				e.profiles = append(e.profiles, profile)
+			}
+		}
+
+		if len(e.profiles) < 1 && len(errs) < 1 {
+			return &Error{Browser: `Epiphany`, Op: `open`, Err: ErrNotFound}
+		}
+	}
+
+	//-- Return ---------
+	return errs.errorOrNil()
+}
+
+func (e *epiphanyProfile) open() error {
+	//-- Open history database, a missing or empty one is left to the first commit that writes history ----------
+	{
+		if info, err := os.Stat(e.dataPath + EPIPHANY_HISTORY_FILE); os.IsNotExist(err) || (err == nil && info.Size() == 0) {
+			e.historyMissing = true
+		} else if err != nil {
+			return fileError(e.dataPath+EPIPHANY_HISTORY_FILE, err)
+		} else if err := e.openHistory(); err != nil {
+			return err
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (e *epiphanyProfile) openHistory() error {
+	if orm, err := openDatabase(e.dataPath + EPIPHANY_HISTORY_FILE); err != nil {
+		return fileError(e.dataPath+EPIPHANY_HISTORY_FILE, err)
+	} else if err := orm.DB().Ping(); err != nil {
+		orm.Close()
+		return fileError(e.dataPath+EPIPHANY_HISTORY_FILE, err)
+	} else {
+		e.historyDatabase = orm
+	}
+
+	return nil
+}
+
+// createHistory lays out ephy-history.db within the commit's transaction, Epiphany only creates tables it finds missing
+// and a failed commit leaves at most an empty file the next open treats as missing.
+func (e *epiphanyProfile) createHistory(tx *gorm.DB) error {
+	for _, statement := range EPIPHANY_HISTORY_SCHEMA {
+		if result := tx.Exec(statement); result.Error != nil {
+			return result.Error
+		}
+	}
+
+	return nil
+}
+
+func (e *epiphany) Load(ctx context.Context) error {
+	//-- Load each profile ----------
+	{
+		var errs = eachProfile(ctx, `load`, e.Profiles(), func(index int) error {
+			if err := ctx.Err(); err != nil {
+				return profileError(e.profiles[index], `load`, err)
+			}
+
+			return profileError(e.profiles[index], `load`, e.profiles[index].load())
+		})
+
+		if len(errs) > 0 {
+			return errs
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (e *epiphanyProfile) load() error {
+	//-- Load hosts and urls ----------
+	{
+		e.hostItems = []*epiphanyHost{}
+		e.historyItems = []*epiphanyURL{}
+
+		if e.historyDatabase != nil {
+			if result := e.historyDatabase.Find(&e.hostItems); result.Error != nil {
+				return fileError(e.dataPath+EPIPHANY_HISTORY_FILE, result.Error)
+			} else if result := e.historyDatabase.Find(&e.historyItems); result.Error != nil {
+				return fileError(e.dataPath+EPIPHANY_HISTORY_FILE, result.Error)
+			}
+		}
+
+		var hosts = map[uint]*epiphanyHost{}
+		e.hostIndex = map[string]*epiphanyHost{}
+		for _, host := range e.hostItems {
+			hosts[host.ID] = host
+			e.hostIndex[host.URL] = host
+		}
+
+		e.historyIndex = map[string]*epiphanyURL{}
+		for _, item := range e.historyItems {
+			e.historyIndex[item.URL] = item
+
+			if item.host = hosts[item.HostID]; item.host == nil {
+				item.host = e.host(item.URL)
+			}
+		}
+	}
+
+	//-- Open/Parse bookmark database ----------
+	{
+		e.bookmarkItems = []*epiphanyBookmark{}
+		e.tags = []string{EPIPHANY_FAVORITES_TAG}
+
+		if file, err := os.Open(e.dataPath + EPIPHANY_BOOKMARKS_FILE); os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return fileError(e.dataPath+EPIPHANY_BOOKMARKS_FILE, err)
+		} else {
+			defer file.Close()
+
+			if root, err := readGVDB(file); err != nil {
+				return fileError(e.dataPath+EPIPHANY_BOOKMARKS_FILE, err)
+			} else if err := e.readBookmarks(root); err != nil {
+				return fileError(e.dataPath+EPIPHANY_BOOKMARKS_FILE, err)
+			}
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (e *epiphanyProfile) readBookmarks(root gvdbTable) error {
+	//-- Tags are bare keys ----------
+	{
+		if item := root[EPIPHANY_TAGS_TABLE]; item != nil {
+			for tag := range item.table {
+				e.addTag(tag)
+			}
+		}
+	}
+
+	//-- Bookmarks are keyed by url ----------
+	{
+		var item = root[EPIPHANY_BOOKMARKS_TABLE]
+		if item == nil {
+			return nil
+		}
+
+		for address, entry := range item.table {
+			if entry.value == nil || entry.value.signature != EPIPHANY_BOOKMARK_SIGNATURE {
+				return fmt.Errorf(`%w: unexpected value for bookmark '%s'`, ErrSchemaUnsupported, address)
+			}
+
+			var fields = entry.value.value.([]interface{})
+			var bookmark = &epiphanyBookmark{
+				url:      address,
+				added:    fields[0].(int64),
+				title:    fields[1].(string),
+				id:       fields[2].(string),
+				modified: fields[3].(float64),
+				uploaded: fields[4].(bool),
+			}
+
+			for _, tag := range fields[5].([]interface{}) {
+				bookmark.tags = append(bookmark.tags, tag.(string))
+			}
+
+			e.bookmarkItems = append(e.bookmarkItems, bookmark)
+		}
+
+		sort.Slice(e.bookmarkItems, func(i, j int) bool { return e.bookmarkItems[i].added < e.bookmarkItems[j].added })
+	}
+
+	return nil
+}
+
+func (e *epiphany) Inspect() ([]Report, error) {
+	//-- Inspect each profile ----------
+	var reports []Report
+	{
+		var errs Errors
+		for _, profile := range e.profiles {
+			if report, err := profile.inspect(); err != nil {
+				errs = append(errs, profileError(profile, `inspect`, err))
+			} else {
+				reports = append(reports, report)
+			}
+		}
+
+		if len(errs) > 0 {
+			return reports, errs
+		}
+	}
+
+	//-- Return ---------
+	return reports, nil
+}
+
+func (e *epiphanyProfile) inspect() (Report, error) {
+	var report = Report{
+		Browser:   `Epiphany`,
+		Profile:   e.name,
+		Path:      e.dataPath,
+		URLs:      len(e.historyItems),
+		Bookmarks: len(e.bookmarkItems),
+	}
+
+	//-- Summarise loaded history ----------
+	{
+		var domains = map[string]int{}
+		for _, item := range e.historyItems {
+			domains[reportDomain(item.URL)] += item.VisitCount
+		}
+
+		report.rankDomains(domains)
+	}
+
+	//-- Summarise individual visits ----------
+	{
+		if e.historyDatabase != nil {
+			if err := e.inspectVisits(&report); err != nil {
+				return report, fileError(e.dataPath+EPIPHANY_HISTORY_FILE, err)
+			}
+		}
+	}
+
+	//-- Summarise bookmarks by tag, a bookmark shows under each of its tags ----------
+	{
+		var tagged = map[string]*BookmarkNode{}
+		var untagged = &BookmarkNode{Name: EPIPHANY_UNTAGGED_TITLE}
+
+		for _, tag := range e.tags {
+			tagged[tag] = &BookmarkNode{Name: tag}
+			report.BookmarkTree = append(report.BookmarkTree, tagged[tag])
+		}
+
+		for _, bookmark := range e.bookmarkItems {
+			var leaf = &BookmarkNode{Name: bookmark.title, URL: bookmark.url}
+			if len(bookmark.tags) == 0 {
+				untagged.Children = append(untagged.Children, leaf)
+			}
+
+			for _, tag := range bookmark.tags {
+				if node := tagged[tag]; node != nil {
+					node.Children = append(node.Children, leaf)
+				}
+			}
+		}
+
+		report.BookmarkTree = append(report.BookmarkTree, untagged)
+	}
+
+	//-- Return ---------
+	return report, nil
+}
+
+func (e *epiphanyProfile) inspectVisits(report *Report) error {
+	var rows, err = e.historyDatabase.Raw(`SELECT visit_time FROM visits`).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var timestamp int64
+		if err := rows.Scan(&timestamp); err != nil {
+			return err
+		}
+
+		report.addVisit(time.Unix(timestamp, 0))
+	}
+
+	return rows.Err()
+}
+
+func (e *epiphany) Close() error {
+	//-- Close detected profiles ----------
+	{
+		var errs Errors
+		for _, profile := range e.profiles {
+			if profile.historyDatabase == nil {
+				continue
+			} else if err := profile.historyDatabase.Close(); err != nil {
+				errs = append(errs, profileError(profile, `close`, fileError(profile.dataPath+EPIPHANY_HISTORY_FILE, err)))
+			}
+		}
+
+		if len(errs) > 0 {
+			return errs
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (e *epiphany) Purge(ctx context.Context) error {
+	//-- Purge detected profiles ----------
+	{
+		var errs = eachProfile(ctx, `purge`, e.Profiles(), func(index int) error {
+			if err := ctx.Err(); err != nil {
+				return profileError(e.profiles[index], `purge`, err)
+			}
+
+			e.profiles[index].purge()
+			return nil
+		})
+
+		if len(errs) > 0 {
+			return errs
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+// purge drops every pending and loaded item, the files themselves are emptied at the start of the next commit.
+func (e *epiphanyProfile) purge() {
+	e.purging = true
+
+	e.hostItems = []*epiphanyHost{}
+	e.hostIndex = map[string]*epiphanyHost{}
+	e.historyItems = []*epiphanyURL{}
+	e.historyIndex = map[string]*epiphanyURL{}
+	e.bookmarkItems = []*epiphanyBookmark{}
+	e.tags = []string{EPIPHANY_FAVORITES_TAG}
+}
+
+func (e *epiphany) Commit(ctx context.Context) error {
+	//-- Commit detected profiles ----------
+	{
+		var errs = eachProfile(ctx, `commit`, e.Profiles(), func(index int) error {
+			var profile = e.profiles[index]
+			if err := profile.commit(ctx); err != nil {
+				profile.transactions.rollback()
+				profile.staged.rollback()
+				return profileError(profile, `commit`, err)
+			}
+
+			return nil
+		})
+
+		if len(errs) > 0 {
+			return errs
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+// commit writes the staged purge and items to the history database in one transaction, the session and bookmark files
+// are staged beside their targets first and only moved into place once it holds.
+func (e *epiphanyProfile) commit(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	//-- Create missing history database once there is history to write ----------
+	{
+		if e.historyMissing && len(e.historyItems) > 0 {
+			if e.historyDatabase == nil {
+				if err := e.openHistory(); err != nil {
+					return err
+				}
+			}
+
+			if err := e.createHistory(e.transactions.begin(e.historyDatabase)); err != nil {
+				return fileError(e.dataPath+EPIPHANY_HISTORY_FILE, err)
+			}
+		}
+	}
+
+	//-- Purge history database ----------
+	{
+		if e.purging && e.historyDatabase != nil {
+			var tx = e.transactions.begin(e.historyDatabase)
+			for _, table := range []string{`visits`, `urls`, `hosts`} {
+				if result := tx.Exec(`DELETE FROM ` + table); result.Error != nil {
+					return fileError(e.dataPath+EPIPHANY_HISTORY_FILE, result.Error)
+				}
+			}
+		}
+	}
+
+	//-- Commit pending hosts then the urls that reference them ----------
+	{
+		if e.historyDatabase != nil {
+			var tx = e.transactions.begin(e.historyDatabase)
+			for _, host := range e.hostItems {
+				if result := tx.Save(host); result.Error != nil {
+					return fileError(e.dataPath+EPIPHANY_HISTORY_FILE, result.Error)
+				}
+			}
+
+			for _, item := range e.historyItems {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+
+				item.HostID = item.host.ID
+				if result := tx.Save(item); result.Error != nil {
+					return fileError(e.dataPath+EPIPHANY_HISTORY_FILE, result.Error)
+				}
+			}
+		}
+	}
+
+	//-- Purge saved session ----------
+	{
+		if e.purging {
+			if err := e.staged.remove(e.dataPath + EPIPHANY_SESSION_FILE); err != nil {
+				return fileError(e.dataPath+EPIPHANY_SESSION_FILE, err)
+			}
+		}
+	}
+
+	//-- Stage pending bookmarks ----------
+	{
+		if err := e.writeBookmarks(); err != nil {
+			return fileError(e.dataPath+EPIPHANY_BOOKMARKS_FILE, err)
+		}
+	}
+
+	//-- Commit database transaction, the last point a cancellation leaves the profile untouched ----------
+	{
+		if err := ctx.Err(); err != nil {
+			return err
+		} else if err := e.transactions.commit(); err != nil {
+			return fileError(e.dataPath+EPIPHANY_HISTORY_FILE, err)
+		}
+	}
+
+	//-- Move staged files into place ----------
+	{
+		if err := e.staged.commit(); err != nil {
+			return err
+		}
+	}
+
+	e.purging = false
+	e.historyMissing = e.historyMissing && len(e.historyItems) == 0
+
+	//-- Return ---------
+	return nil
+}
+
+func (e *epiphanyProfile) writeBookmarks() error {
+	var tags = gvdbTable{}
+	for _, tag := range e.tags {
+		tags[tag] = &gvdbItem{}
+	}
+
+	var bookmarks = gvdbTable{}
+	for _, bookmark := range e.bookmarkItems {
+		var labels = []interface{}{}
+		for _, tag := range bookmark.tags {
+			labels = append(labels, tag)
+		}
+
+		bookmarks[bookmark.url] = &gvdbItem{value: &gvariant{
+			signature: EPIPHANY_BOOKMARK_SIGNATURE,
+			value:     []interface{}{bookmark.added, bookmark.title, bookmark.id, bookmark.modified, bookmark.uploaded, labels},
+		}}
+	}
+
+	var file, err = e.staged.create(e.dataPath+EPIPHANY_BOOKMARKS_FILE, 0666)
+	if err != nil {
+		return fileError(e.dataPath+EPIPHANY_BOOKMARKS_FILE, err)
+	}
+
+	if err := writeGVDB(file, gvdbTable{EPIPHANY_TAGS_TABLE: {table: tags}, EPIPHANY_BOOKMARKS_TABLE: {table: bookmarks}}); err != nil {
+		file.Close()
+		return fileError(e.dataPath+EPIPHANY_BOOKMARKS_FILE, err)
+	}
+
+	return file.Close()
+}
+
+// host finds or creates the hosts row a url is grouped under, keyed by scheme and authority.
+func (e *epiphanyProfile) host(raw string) *epiphanyHost {
+	var key, title = raw, raw
+	if parsed, err := url.Parse(raw); err == nil && parsed.Host != `` {
+		key, title = parsed.Scheme+`://`+parsed.Host, parsed.Hostname()
+	}
+
+	if e.hostIndex == nil {
+		e.hostIndex = map[string]*epiphanyHost{}
+	}
+	if existing, ok := e.hostIndex[key]; ok {
+		return existing
+	}
+
+	var host = &epiphanyHost{URL: key, Title: title}
+	e.hostItems = append(e.hostItems, host)
+	e.hostIndex[key] = host
+
+	return host
+}
+
+func (e *epiphanyProfile) addTag(tag string) {
+	for _, existing := range e.tags {
+		if existing == tag {
+			return
+		}
+	}
+
+	e.tags = append(e.tags, tag)
+}
+
+func epiphanyVisitType(transition Transition) int {
+	switch transition {
+	case TransitionTyped, TransitionGenerated, TransitionKeyword, TransitionKeywordGenerated:
+		return epiphanyVisitTyped
+	case TransitionAutoBookmark:
+		return epiphanyVisitBookmark
+	case TransitionManualSubframe:
+		return epiphanyVisitManualSubframe
+	default:
+		return epiphanyVisitLink
+	}
+}
+
+// epiphanyTimestamp is the visit_time and last_visit_time of ephy-history-service, whole seconds since the Unix epoch.
+func epiphanyTimestamp(moment time.Time) int64 {
+	return moment.Unix()
+}
@@ -0,0 +1,79 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+func TestEpiphanyHistoryCommit(t *testing.T) {
+	var ctx = context.Background()
+	var directory = t.TempDir() + `/`
+
+	//-- Inspecting or committing without history leaves ephy-history.db uncreated ----------
+	{
+		var profile = openEpiphanyProfile(t, directory)
+
+		if report, err := profile.inspect(); err != nil {
+			t.Fatalf(`inspect: %s`, err)
+		} else if report.URLs != 0 {
+			t.Fatalf(`inspect found %d urls in a new profile`, report.URLs)
+		} else if err := profile.commit(ctx); err != nil {
+			t.Fatalf(`commit: %s`, err)
+		}
+
+		expectFile(t, directory+EPIPHANY_HISTORY_FILE, ``)
+	}
+
+	//-- Create ephy-history.db with the first history ----------
+	{
+		var profile = openEpiphanyProfile(t, directory)
+
+		if err := profile.AddHistory(ctx, History{Name: `Example`, URL: `https://example.com/`, Visits: 3, VisitWindow: time.Hour}); err != nil {
+			t.Fatalf(`AddHistory: %s`, err)
+		} else if err := profile.commit(ctx); err != nil {
+			t.Fatalf(`commit: %s`, err)
+		}
+	}
+
+	//-- Reload what was written ----------
+	{
+		var profile = openEpiphanyProfile(t, directory)
+
+		if len(profile.historyItems) != 1 || len(profile.hostItems) != 1 {
+			t.Fatalf(`loaded %d urls and %d hosts, want 1 of each`, len(profile.historyItems), len(profile.hostItems))
+		} else if profile.historyItems[0].VisitCount != 3 {
+			t.Errorf(`loaded a visit count of %d, want 3`, profile.historyItems[0].VisitCount)
+		}
+
+		if report, err := profile.inspect(); err != nil {
+			t.Fatalf(`inspect: %s`, err)
+		} else if report.Visits != 3 {
+			t.Errorf(`inspect found %d visits, want 3`, report.Visits)
+		}
+	}
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+func openEpiphanyProfile(tb testing.TB, directory string) *epiphanyProfile {
+	tb.Helper()
+
+	var profile = &epiphanyProfile{name: EPIPHANY_NATIVE_PROFILE, dataPath: directory, random: newRandom(`Epiphany`, EPIPHANY_NATIVE_PROFILE)}
+	if err := profile.open(); err != nil {
+		tb.Fatalf(`open: %s`, err)
+	}
+	tb.Cleanup(func() {
+		if profile.historyDatabase != nil {
+			profile.historyDatabase.Close()
+		}
+	})
+
+	if err := profile.load(); err != nil {
+		tb.Fatalf(`load: %s`, err)
+	}
+
+	return profile
+}
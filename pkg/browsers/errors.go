@@ -0,0 +1,155 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"errors"
+	"io/fs"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+var (
+	// ErrProfileLocked reports a database held by a running browser, close the browser and try again.
+	ErrProfileLocked = errors.New(`profile is locked by a running browser`)
+
+	// ErrSchemaUnsupported reports a file laid out differently than any browser version this package writes for.
+	ErrSchemaUnsupported = errors.New(`unsupported schema`)
+
+	// ErrNotFound reports a missing browser, profile or data file.
+	ErrNotFound = errors.New(`not found`)
+
+	// ErrUnsupported reports an item or operation a browser does not implement, such as purging only some of its items.
+	ErrUnsupported = errors.New(`not supported by this browser`)
+)
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+// Error identifies the browser, profile, file and operation a failure happened in, any of which may be empty when
+// unknown. It matches ErrNotFound, ErrProfileLocked and ErrSchemaUnsupported for the file system and SQLite errors
+// that mean the same.
+type Error struct {
+	Browser string
+	Profile string
+	File    string
+	Op      string
+	Err     error
+}
+
+// Errors collects the failures of an operation applied to several browsers or profiles, errors.Is and errors.As look
+// through each of them.
+type Errors []error
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+func (e *Error) Error() string {
+	var parts []string
+
+	if e.Browser != `` && e.Profile != `` {
+		parts = append(parts, e.Browser+` profile '`+e.Profile+`'`)
+	} else if e.Browser != `` {
+		parts = append(parts, e.Browser)
+	}
+
+	if e.Op != `` {
+		parts = append(parts, e.Op)
+	}
+
+	if e.File != `` {
+		parts = append(parts, e.File)
+	}
+
+	return strings.Join(append(parts, e.Err.Error()), `: `)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	var sqliteError sqlite3.Error
+	var isSQLite = errors.As(e.Err, &sqliteError)
+
+	switch target {
+	case ErrNotFound:
+		return errors.Is(e.Err, fs.ErrNotExist)
+	case ErrProfileLocked:
+		return isSQLite && (sqliteError.Code == sqlite3.ErrBusy || sqliteError.Code == sqlite3.ErrLocked)
+	case ErrSchemaUnsupported:
+		return isSQLite && sqliteError.Code == sqlite3.ErrError && strings.HasPrefix(sqliteError.Error(), `no such `)
+	}
+
+	return false
+}
+
+func (e Errors) Error() string {
+	var messages []string
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+func (e Errors) Unwrap() []error {
+	return e
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+// add appends an error, flattening nested collections and skipping nil.
+func (e Errors) add(err error) Errors {
+	var nested Errors
+	if err == nil {
+		return e
+	} else if errors.As(err, &nested) {
+		return append(e, nested...)
+	}
+
+	return append(e, err)
+}
+
+// errorOrNil returns the collection as an error, or a nil interface rather than an empty collection.
+func (e Errors) errorOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+// fileError records the file an error came from, leaving the browser, profile and operation to be filled on the way up.
+// An error that already names its file is passed through, the innermost file is the most precise.
+func fileError(file string, err error) error {
+	var located *Error
+	if err == nil {
+		return nil
+	} else if errors.As(err, &located) && located.File != `` {
+		return err
+	}
+
+	return &Error{File: file, Err: err}
+}
+
+// profileError records the profile and operation an error came from, filling in an Error raised further down rather
+// than wrapping it twice.
+func profileError(profile Profile, operation string, err error) error {
+	var located *Error
+	if err == nil {
+		return nil
+	} else if !errors.As(err, &located) {
+		located = &Error{Err: err}
+		err = located
+	}
+
+	if located.Browser == `` {
+		located.Browser = profile.Browser()
+	}
+	if located.Profile == `` {
+		located.Profile = profile.Name()
+	}
+	if located.Op == `` {
+		located.Op = operation
+	}
+
+	return err
+}
@@ -0,0 +1,121 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+// TestErrorIs matches the sentinels against errors raised by SQLite and the file system, not ones built to match.
+func TestErrorIs(t *testing.T) {
+	var path = filepath.Join(t.TempDir(), `History`)
+
+	//-- Hold the write lock as a running browser would ----------
+	var busy error
+	{
+		var holder, contender = openTestSQLite(t, path), openTestSQLite(t, path)
+
+		if _, err := holder.Exec(`CREATE TABLE urls (url TEXT)`); err != nil {
+			t.Fatalf(`Exec: %s`, err)
+		}
+
+		var tx, err = holder.Begin()
+		if err != nil {
+			t.Fatalf(`Begin: %s`, err)
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.Exec(`INSERT INTO urls (url) VALUES ('https://example.com/')`); err != nil {
+			t.Fatalf(`Exec: %s`, err)
+		}
+
+		if _, busy = contender.Exec(`INSERT INTO urls (url) VALUES ('https://example.org/')`); busy == nil {
+			t.Fatalf(`second writer was not refused`)
+		}
+	}
+
+	var missingTable, syntax error
+	{
+		var database = openTestSQLite(t, filepath.Join(t.TempDir(), `Cookies`))
+		if _, missingTable = database.Exec(`DELETE FROM cookies`); missingTable == nil {
+			t.Fatalf(`deleting from a missing table succeeded`)
+		} else if _, syntax = database.Exec(`DELETE cookies`); syntax == nil {
+			t.Fatalf(`malformed statement succeeded`)
+		}
+	}
+
+	var _, missingFile = os.Open(filepath.Join(t.TempDir(), `Bookmarks`))
+
+	for _, test := range []struct {
+		name string
+		err  error
+		want error
+	}{
+		{`busy`, busy, ErrProfileLocked},
+		{`locked`, sqlite3.Error{Code: sqlite3.ErrLocked}, ErrProfileLocked},
+		{`no such table`, missingTable, ErrSchemaUnsupported},
+		{`syntax`, syntax, nil},
+		{`missing file`, missingFile, ErrNotFound},
+	} {
+		var located = fileError(path, test.err)
+
+		for _, sentinel := range []error{ErrProfileLocked, ErrSchemaUnsupported, ErrNotFound} {
+			if matched := errors.Is(located, sentinel); matched != (sentinel == test.want) {
+				t.Errorf(`%s: errors.Is(%s, %s) = %t`, test.name, located, sentinel, matched)
+			}
+		}
+	}
+}
+
+// TestErrorsIs reaches through collections, including those flattened into one another, to the located errors inside.
+func TestErrorsIs(t *testing.T) {
+	var locked = fileError(`Cookies`, sqlite3.Error{Code: sqlite3.ErrBusy})
+	var missing = fileError(`Bookmarks`, os.ErrNotExist)
+
+	var errs = Errors{}.add(nil).add(Errors{locked}).add(missing)
+	if len(errs) != 2 {
+		t.Fatalf(`collected %d errors, want 2 with nil skipped and the nested collection flattened`, len(errs))
+	}
+
+	var err = errs.errorOrNil()
+	for _, sentinel := range []error{ErrProfileLocked, ErrNotFound} {
+		if !errors.Is(err, sentinel) {
+			t.Errorf(`errors.Is(%s) = false`, sentinel)
+		}
+	}
+
+	if errors.Is(err, ErrSchemaUnsupported) {
+		t.Errorf(`errors.Is(%s) = true`, ErrSchemaUnsupported)
+	}
+
+	var located *Error
+	if !errors.As(err, &located) || located.File != `Cookies` {
+		t.Errorf(`errors.As found %v, want the Cookies error`, located)
+	}
+
+	if Errors(nil).errorOrNil() != nil {
+		t.Errorf(`an empty collection is not a nil error`)
+	}
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+// openTestSQLite opens a database that fails at once on a held lock instead of waiting out the default busy timeout.
+func openTestSQLite(tb testing.TB, path string) *sql.DB {
+	tb.Helper()
+
+	var database, err = sql.Open(`sqlite3`, `file:`+path+`?_busy_timeout=0`)
+	if err != nil {
+		tb.Fatalf(`Open: %s`, err)
+	}
+	database.SetMaxOpenConns(1)
+	tb.Cleanup(func() { database.Close() })
+
+	return database
+}
@@ -0,0 +1,642 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+var (
+	FALKON_HISTORY_FILE   = `browsedata.db`
+	FALKON_BOOKMARKS_FILE = `bookmarks.json`
+	FALKON_VERSION_FILE   = `version`
+	FALKON_SESSION_FILES  = []string{`session.dat`, `session.dat.old`, `session.dat.old1`}
+
+	FALKON_BOOKMARKS_VERSION = 1
+	FALKON_BOOKMARKS_BAR     = `bookmark_bar`
+	FALKON_BOOKMARKS_MENU    = `bookmark_menu`
+	FALKON_BOOKMARKS_OTHER   = `other`
+	FALKON_BOOKMARK_ROOTS    = []string{FALKON_BOOKMARKS_BAR, FALKON_BOOKMARKS_MENU, FALKON_BOOKMARKS_OTHER}
+	FALKON_ROOT_NAMES        = map[string]string{
+		FALKON_BOOKMARKS_BAR:   `Bookmarks Toolbar`,
+		FALKON_BOOKMARKS_MENU:  `Bookmarks Menu`,
+		FALKON_BOOKMARKS_OTHER: `Unsorted Bookmarks`,
+	}
+
+	FALKON_LINUX_DATA_PATH   = fmt.Sprintf(`%s/.config/falkon/profiles/`, os.Getenv(`HOME`))
+	FALKON_FLATPAK_DATA_PATH = fmt.Sprintf(`%s/.var/app/org.kde.falkon/config/falkon/profiles/`, os.Getenv(`HOME`))
+)
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+type falkon struct {
+	profiles []*falkonProfile
+}
+
+type falkonProfile struct {
+	name     string
+	dataPath string
+
+	historyDatabase *gorm.DB
+	transactions    transactions
+	staged          stagedFiles
+	random          random
+	purging         bool
+
+	historyIndex     map[string]*falkonHistory
+	historyItems     []*falkonHistory
+	bookmarkManifest *falkonBookmarks
+}
+
+// falkonHistory is Falkon's only record of browsing, one row per url with its visit count and latest visit.
+type falkonHistory struct {
+	//-- Primary Key ----------
+	ID uint `gorm:"column:id;primary_key"`
+
+	//-- User Variables ----------
+	URL   string `gorm:"column:url"`
+	Title string `gorm:"column:title"`
+	Date  int64  `gorm:"column:date"`
+	Count int    `gorm:"column:count"`
+}
+
+func (falkonHistory) TableName() string {
+	return `history`
+}
+
+type falkonBookmarks struct {
+	Roots   map[string]*falkonBookmark `json:"roots"`
+	Version int                        `json:"version"`
+}
+
+type falkonBookmark struct {
+	Type            string            `json:"type"`
+	Name            string            `json:"name"`
+	URL             string            `json:"url"`
+	Description     string            `json:"description"`
+	Keyword         string            `json:"keyword"`
+	VisitCount      int               `json:"visit_count"`
+	Expanded        bool              `json:"expanded"`
+	ExpandedSidebar bool              `json:"expanded_sidebar"`
+	Children        []*falkonBookmark `json:"children"`
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+func (f *falkon) Metadata() Metadata {
+	var metadata = Metadata{Name: `Falkon`, Engine: `Blink`} //NOTE: QtWebEngine embeds Chromium
+	for _, profile := range f.profiles {
+		metadata.Profiles = append(metadata.Profiles, profile.name)
+
+		if metadata.Version == `` {
+			metadata.Version = readVersionFile(profile.dataPath + FALKON_VERSION_FILE)
+		}
+	}
+
+	return metadata
+}
+
+func (f *falkon) Profiles() []Profile {
+	var profiles []Profile
+	for _, profile := range f.profiles {
+		profiles = append(profiles, profile)
+	}
+
+	return profiles
+}
+
+func (f *falkonProfile) Name() string {
+	return f.name
+}
+
+func (f *falkonProfile) Path() string {
+	return f.dataPath
+}
+
+func (f *falkonProfile) Browser() string {
+	return `Falkon`
+}
+
+func (f *falkonProfile) AddHistory(ctx context.Context, item History) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	//-- Find or create entry, Falkon keeps a single row per url ----------
+	var entry *falkonHistory
+	{
+		if f.historyIndex == nil {
+			f.historyIndex = map[string]*falkonHistory{}
+		}
+		entry = f.historyIndex[item.URL]
+
+		if entry == nil {
+			entry = &falkonHistory{URL: item.URL, Title: item.Name}
+			f.historyItems = append(f.historyItems, entry)
+			f.historyIndex[item.URL] = entry
+		}
+	}
+
+	//-- Fold visits into the counter and latest visit ----------
+	{
+		var visits []time.Time
+		if len(item.Timeline) > 0 {
+			for _, recorded := range item.Timeline {
+				visits = append(visits, recorded.Time)
+			}
+		} else {
+			for i := 0; i < item.Visits; i++ {
+				visits = append(visits, time.Unix(f.random.unixTimestamp(item.VisitWindow), f.random.Int63n(int64(time.Second))))
+			}
+		}
+
+		for _, visit := range visits {
+			if milliseconds := falkonTimestamp(visit); milliseconds > entry.Date {
+				entry.Date = milliseconds
+			}
+
+			entry.Count++
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (f *falkonProfile) AddBookmark(ctx context.Context, item Bookmark) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	//-- Select root, foldered bookmarks are kept together on the toolbar ----------
+	var parent *falkonBookmark
+	{
+		if len(item.Folder) > 0 {
+			parent = f.bookmarkManifest.Roots[FALKON_BOOKMARKS_BAR]
+		} else {
+			var roots = []string{FALKON_BOOKMARKS_BAR, FALKON_BOOKMARKS_OTHER}
+			parent = f.bookmarkManifest.Roots[roots[f.random.Intn(len(roots))]]
+		}
+	}
+
+	//-- Walk or create sub-folders ----------
+	{
+		for _, name := range item.Folder {
+			var folder = parent.folder(name)
+			if folder == nil {
+				folder = &falkonBookmark{Type: `folder`, Name: name, Children: []*falkonBookmark{}}
+				parent.Children = append(parent.Children, folder)
+			}
+
+			parent = folder
+		}
+	}
+
+	//-- Insert ----------
+	{
+		parent.Children = append(parent.Children, &falkonBookmark{Type: `url`, Name: item.Name, URL: item.URL})
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (f *falkonProfile) AddCredential(ctx context.Context, item Credential) error {
+	//TODO: Passwords belong in the autofill table of browsedata.db, encrypted with the profile's master key when set
+	return ErrUnsupported
+}
+
+func (f *falkonProfile) AddFormEntry(ctx context.Context, item FormEntry) error {
+	//TODO: Form values are kept by QtWebEngine, which Falkon does not expose
+	return ErrUnsupported
+}
+
+func (f *falkonProfile) AddCookie(ctx context.Context, item Cookie) error {
+	//TODO: Cookies belong in QtWebEngine's Cookies database, which shares Chrome's schema
+	return ErrUnsupported
+}
+
+func (f *falkonProfile) AddDownload(ctx context.Context, item Download) error {
+	//TODO: Falkon only remembers downloads for the current session
+	return ErrUnsupported
+}
+
+func (f *falkonProfile) AddAddress(ctx context.Context, item Address) error {
+	//TODO: Falkon has no address autofill
+	return ErrUnsupported
+}
+
+func (f *falkonProfile) AddSearchEngine(ctx context.Context, item SearchEngine) error {
+	//TODO: Search engines belong in the search_engines table of browsedata.db
+	return ErrUnsupported
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+func (f *falkon) Open(ctx context.Context) error {
+	//-- Detect profiles of native and Flatpak installs ----------
+	var profiles []*falkonProfile
+	{
+		for _, root := range []string{FALKON_LINUX_DATA_PATH, FALKON_FLATPAK_DATA_PATH} {
+			var entries, err = os.ReadDir(root)
+			if err != nil {
+				continue
+			}
+
+			for _, entry := range entries {
+				if entry.IsDir() {
+					profiles = append(profiles, &falkonProfile{name: entry.Name(), dataPath: filepath.Join(root, entry.Name()) + string(filepath.Separator), random: newRandom(`Falkon`, entry.Name())})
+				}
+			}
+		}
+	}
+
+	//-- Connect to detected profiles, those that fail are reported while the rest stay usable ----------
+	var errs Errors
+	{
+		for _, profile := range profiles {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if err := profile.open(); err != nil {
+				errs = append(errs, profileError(profile, `open`, err))
+			} else {
+				f.profiles = append(f.profiles, profile)
+			}
+		}
+
+		if len(f.profiles) < 1 && len(errs) < 1 {
+			return &Error{Browser: `Falkon`, Op: `open`, Err: ErrNotFound}
+		}
+	}
+
+	//-- Return ---------
+	return errs.errorOrNil()
+}
+
+func (f *falkonProfile) open() error {
+	//-- Open browse data, Falkon seeds a profile from a template so a missing one is left alone ----------
+	{
+		if _, err := os.Stat(f.dataPath + FALKON_HISTORY_FILE); err != nil {
+			return fileError(f.dataPath+FALKON_HISTORY_FILE, err)
+		} else if orm, err := openDatabase(f.dataPath + FALKON_HISTORY_FILE); err != nil {
+			return fileError(f.dataPath+FALKON_HISTORY_FILE, err)
+		} else if err := orm.DB().Ping(); err != nil {
+			return fileError(f.dataPath+FALKON_HISTORY_FILE, err)
+		} else {
+			f.historyDatabase = orm
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (f *falkon) Load(ctx context.Context) error {
+	//-- Load each profile ----------
+	{
+		var errs = eachProfile(ctx, `load`, f.Profiles(), func(index int) error {
+			if err := ctx.Err(); err != nil {
+				return profileError(f.profiles[index], `load`, err)
+			}
+
+			return profileError(f.profiles[index], `load`, f.profiles[index].load())
+		})
+
+		if len(errs) > 0 {
+			return errs
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (f *falkonProfile) load() error {
+	//-- Load history ----------
+	{
+		f.historyItems = []*falkonHistory{}
+
+		if result := f.historyDatabase.Find(&f.historyItems); result.Error != nil {
+			return fileError(f.dataPath+FALKON_HISTORY_FILE, result.Error)
+		}
+
+		f.historyIndex = map[string]*falkonHistory{}
+		for _, item := range f.historyItems {
+			f.historyIndex[item.URL] = item
+		}
+	}
+
+	//-- Open/Parse bookmark manifest ----------
+	{
+		f.bookmarkManifest = newFalkonBookmarks()
+
+		if data, err := os.ReadFile(f.dataPath + FALKON_BOOKMARKS_FILE); os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return fileError(f.dataPath+FALKON_BOOKMARKS_FILE, err)
+		} else if err := json.Unmarshal(data, f.bookmarkManifest); err != nil {
+			return fileError(f.dataPath+FALKON_BOOKMARKS_FILE, err)
+		}
+
+		for _, name := range FALKON_BOOKMARK_ROOTS {
+			if f.bookmarkManifest.Roots[name] == nil {
+				f.bookmarkManifest.Roots[name] = &falkonBookmark{Type: `folder`, Name: FALKON_ROOT_NAMES[name], Children: []*falkonBookmark{}}
+			}
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (f *falkon) Inspect() ([]Report, error) {
+	//-- Inspect each profile ----------
+	var reports []Report
+	{
+		for _, profile := range f.profiles {
+			reports = append(reports, profile.inspect())
+		}
+	}
+
+	//-- Return ---------
+	return reports, nil
+}
+
+func (f *falkonProfile) inspect() Report {
+	var report = Report{
+		Browser: `Falkon`,
+		Profile: f.name,
+		Path:    f.dataPath,
+		URLs:    len(f.historyItems),
+	}
+
+	//-- Summarise history, only the latest visit of each url is known ----------
+	{
+		var domains = map[string]int{}
+		for _, item := range f.historyItems {
+			domains[reportDomain(item.URL)] += item.Count
+			report.addVisit(fromFalkonTimestamp(item.Date))
+		}
+
+		report.rankDomains(domains)
+	}
+
+	//-- Summarise bookmark tree ----------
+	{
+		for _, name := range FALKON_BOOKMARK_ROOTS {
+			var node = f.bookmarkManifest.Roots[name].report()
+			node.Name = FALKON_ROOT_NAMES[name]
+
+			report.Bookmarks = report.Bookmarks + node.count()
+			report.BookmarkTree = append(report.BookmarkTree, node)
+		}
+	}
+
+	//-- Return ---------
+	return report
+}
+
+func (f *falkon) Close() error {
+	//-- Close detected profiles ----------
+	{
+		var errs Errors
+		for _, profile := range f.profiles {
+			if err := profile.historyDatabase.Close(); err != nil {
+				errs = append(errs, profileError(profile, `close`, fileError(profile.dataPath+FALKON_HISTORY_FILE, err)))
+			}
+		}
+
+		if len(errs) > 0 {
+			return errs
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (f *falkon) Purge(ctx context.Context) error {
+	//-- Purge detected profiles ----------
+	{
+		var errs = eachProfile(ctx, `purge`, f.Profiles(), func(index int) error {
+			if err := ctx.Err(); err != nil {
+				return profileError(f.profiles[index], `purge`, err)
+			}
+
+			f.profiles[index].purge()
+			return nil
+		})
+
+		if len(errs) > 0 {
+			return errs
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+// purge drops every pending and loaded item, the files themselves are emptied at the start of the next commit.
+func (f *falkonProfile) purge() {
+	f.purging = true
+
+	f.historyItems = []*falkonHistory{}
+	f.historyIndex = map[string]*falkonHistory{}
+	f.bookmarkManifest = newFalkonBookmarks()
+}
+
+func (f *falkon) Commit(ctx context.Context) error {
+	//-- Commit detected profiles ----------
+	{
+		var errs = eachProfile(ctx, `commit`, f.Profiles(), func(index int) error {
+			var profile = f.profiles[index]
+			if err := profile.commit(ctx); err != nil {
+				profile.transactions.rollback()
+				profile.staged.rollback()
+				return profileError(profile, `commit`, err)
+			}
+
+			return nil
+		})
+
+		if len(errs) > 0 {
+			return errs
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+// commit writes the staged purge and items to the history database in one transaction, the session and bookmark files
+// are staged beside their targets first and only moved into place once it holds.
+func (f *falkonProfile) commit(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var tx = f.transactions.begin(f.historyDatabase)
+
+	//-- Purge history and the icons cached for it ----------
+	{
+		if f.purging {
+			for _, table := range []string{`history`, `icons`} {
+				if !tx.HasTable(table) {
+					continue
+				} else if result := tx.Exec(`DELETE FROM ` + table); result.Error != nil {
+					return fileError(f.dataPath+FALKON_HISTORY_FILE, result.Error)
+				}
+			}
+		}
+	}
+
+	//-- Commit pending history ----------
+	{
+		for _, item := range f.historyItems {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if result := tx.Save(item); result.Error != nil {
+				return fileError(f.dataPath+FALKON_HISTORY_FILE, result.Error)
+			}
+		}
+	}
+
+	//-- Purge saved sessions ----------
+	{
+		if f.purging {
+			for _, name := range FALKON_SESSION_FILES {
+				if err := f.staged.remove(f.dataPath + name); err != nil {
+					return fileError(f.dataPath+name, err)
+				}
+			}
+		}
+	}
+
+	//-- Stage pending bookmarks ----------
+	{
+		if err := f.writeBookmarks(); err != nil {
+			return fileError(f.dataPath+FALKON_BOOKMARKS_FILE, err)
+		}
+	}
+
+	//-- Commit database transaction, the last point a cancellation leaves the profile untouched ----------
+	{
+		if err := ctx.Err(); err != nil {
+			return err
+		} else if err := f.transactions.commit(); err != nil {
+			return fileError(f.dataPath+FALKON_HISTORY_FILE, err)
+		}
+	}
+
+	//-- Move staged files into place ----------
+	{
+		if err := f.staged.commit(); err != nil {
+			return err
+		}
+	}
+
+	f.purging = false
+
+	//-- Return ---------
+	return nil
+}
+
+// writeBookmarks saves the manifest indented by four spaces as Qt's QJsonDocument does.
+func (f *falkonProfile) writeBookmarks() error {
+	var data, err = json.MarshalIndent(f.bookmarkManifest, ``, `    `)
+	if err != nil {
+		return fileError(f.dataPath+FALKON_BOOKMARKS_FILE, err)
+	}
+
+	var file io.WriteCloser
+	if created, err := f.staged.create(f.dataPath+FALKON_BOOKMARKS_FILE, 0644); err != nil {
+		return fileError(f.dataPath+FALKON_BOOKMARKS_FILE, err)
+	} else {
+		file = created
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fileError(f.dataPath+FALKON_BOOKMARKS_FILE, err)
+	}
+
+	return fileError(f.dataPath+FALKON_BOOKMARKS_FILE, file.Close())
+}
+
+func newFalkonBookmarks() *falkonBookmarks {
+	var manifest = &falkonBookmarks{Roots: map[string]*falkonBookmark{}, Version: FALKON_BOOKMARKS_VERSION}
+	for _, name := range FALKON_BOOKMARK_ROOTS {
+		manifest.Roots[name] = &falkonBookmark{Type: `folder`, Name: FALKON_ROOT_NAMES[name], Children: []*falkonBookmark{}}
+	}
+
+	return manifest
+}
+
+// MarshalJSON writes only the keys Falkon stores for the item's type, a map keeps them sorted like a QVariantMap.
+func (b *falkonBookmark) MarshalJSON() ([]byte, error) {
+	var fields = map[string]interface{}{`type`: b.Type}
+
+	switch b.Type {
+	case `url`:
+		fields[`name`] = b.Name
+		fields[`url`] = b.URL
+		fields[`description`] = b.Description
+		fields[`keyword`] = b.Keyword
+		fields[`visit_count`] = b.VisitCount
+	case `folder`:
+		var children = b.Children
+		if children == nil {
+			children = []*falkonBookmark{}
+		}
+
+		fields[`name`] = b.Name
+		fields[`description`] = b.Description
+		fields[`expanded`] = b.Expanded
+		fields[`expanded_sidebar`] = b.ExpandedSidebar
+		fields[`children`] = children
+	}
+
+	return json.Marshal(fields)
+}
+
+func (b *falkonBookmark) folder(name string) *falkonBookmark {
+	for _, child := range b.Children {
+		if child.Type == `folder` && child.Name == name {
+			return child
+		}
+	}
+
+	return nil
+}
+
+func (b *falkonBookmark) report() *BookmarkNode {
+	var node = &BookmarkNode{Name: b.Name, URL: b.URL}
+
+	for _, child := range b.Children {
+		if child.Type == `url` || child.Type == `folder` {
+			node.Children = append(node.Children, child.report())
+		}
+	}
+
+	return node
+}
+
+// falkonTimestamp is Qt's milliseconds since the Unix epoch.
+func falkonTimestamp(moment time.Time) int64 {
+	return moment.UnixNano() / int64(time.Millisecond)
+}
+
+func fromFalkonTimestamp(timestamp int64) time.Time {
+	return time.Unix(timestamp/1000, (timestamp%1000)*int64(time.Millisecond))
+}
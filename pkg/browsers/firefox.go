@@ -3,9 +3,18 @@ package browsers
 
 //-- Imports -----------------------------------------------------------------------------------------------------------
 import (
+	"bufio"
+	"context"
+	"encoding/base64"
 	"fmt"
+	"math"
+	"net/url"
 	"os"
+	"path/filepath"
 	"runtime"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/jinzhu/gorm"
 	_ "github.com/mattn/go-sqlite3"
@@ -13,37 +22,320 @@ import (
 
 //-- Constants ---------------------------------------------------------------------------------------------------------
 var (
-	FIREFOX_DEFAULT_PROFILE   = `default` //TODO: Firefox assignes a random 4 character predix to this, need to emulate or detect an existing profile
-	FIREFOX_LINUX_DATA_PATH   = fmt.Sprintf(`%s/.mozilla/firefox/%s/`, os.Getenv(`HOME`), FIREFOX_DEFAULT_PROFILE)
-	FIREFOX_DARWIN_DATA_PATH  = fmt.Sprintf(`%s/Library/Application Support/Firefox/Profiles/%s/`, os.Getenv(`HOME`), FIREFOX_DEFAULT_PROFILE)
-	FIREFOX_WINDOWS_DATA_PATH = fmt.Sprintf(`%s\Mozilla\Firefox\Profiles\%s\`, os.Getenv(`LOCALAPPDATA`), FIREFOX_DEFAULT_PROFILE)
+	FIREFOX_PROFILES_FILE  = `profiles.ini`
+	FIREFOX_VERSION_FILE   = `compatibility.ini`
+	FIREFOX_PLACES_FILE    = `places.sqlite`
+	FIREFOX_TYPED_ONE_IN_X = 8
+
+	FIREFOX_ROOT_GUID    = `root________`
+	FIREFOX_MENU_GUID    = `menu________`
+	FIREFOX_TOOLBAR_GUID = `toolbar_____`
+	FIREFOX_UNFILED_GUID = `unfiled_____`
+	FIREFOX_MOBILE_GUID  = `mobile______`
+	FIREFOX_TAGS_GUID    = `tags________`
+
+	FIREFOX_BOOKMARK_ROOTS = []string{FIREFOX_MENU_GUID, FIREFOX_TOOLBAR_GUID, FIREFOX_UNFILED_GUID, FIREFOX_MOBILE_GUID}
+	FIREFOX_ROOT_NAMES     = map[string]string{
+		FIREFOX_MENU_GUID:    `Bookmarks Menu`,
+		FIREFOX_TOOLBAR_GUID: `Bookmarks Toolbar`,
+		FIREFOX_UNFILED_GUID: `Other Bookmarks`,
+		FIREFOX_MOBILE_GUID:  `Mobile Bookmarks`,
+	}
+
+	FIREFOX_LINUX_DATA_PATH   = fmt.Sprintf(`%s/.mozilla/firefox/`, os.Getenv(`HOME`))
+	FIREFOX_DARWIN_DATA_PATH  = fmt.Sprintf(`%s/Library/Application Support/Firefox/`, os.Getenv(`HOME`))
+	FIREFOX_WINDOWS_DATA_PATH = fmt.Sprintf(`%s\Mozilla\Firefox\`, os.Getenv(`APPDATA`))
+)
+
+// Bookmark item types and history visit types, toolkit/components/places/nsINavBookmarksService.idl and
+// nsINavHistoryService.idl
+const (
+	firefoxBookmarkURL    = 1
+	firefoxBookmarkFolder = 2
+
+	firefoxVisitLink       = 1
+	firefoxVisitTyped      = 2
+	firefoxVisitBookmark   = 3
+	firefoxVisitEmbed      = 4
+	firefoxVisitFramedLink = 8
+	firefoxVisitReload     = 9
 )
 
 //-- Structs -----------------------------------------------------------------------------------------------------------
 type firefox struct {
-	dataPath    string
-	history     *gorm.DB
-	credentials *gorm.DB
-	bookmarks   *chromeBookmarksManifest
+	dataPath string
+	profiles []*firefoxProfile
+}
+
+type firefoxProfile struct {
+	name     string
+	dataPath string
+
+	placesDatabase *gorm.DB
+	cookieDatabase *gorm.DB
+	formDatabase   *gorm.DB
+	transactions   transactions
+	staged         stagedFiles
+	random         random
+	purging        bool
+
+	historyIndex    map[string]*firefoxPlace
+	historyItems    []*firefoxPlace
+	credentialItems []*firefoxLogin
+	cookieItems     []*firefoxCookie
+	formItems       []*firefoxFormEntry
+	downloadItems   []*firefoxPendingDownload
+	bookmarkItems   []*firefoxPendingBookmark
+	loginManifest   *firefoxLogins
+}
+
+type firefoxPlace struct {
+	//-- Primary Key ----------
+	ID uint `gorm:"column:id;primary_key"`
+
+	//-- User Variables ----------
+	URL           string `gorm:"column:url"`
+	Title         string `gorm:"column:title"`
+	VisitCount    int    `gorm:"column:visit_count"`
+	LastVisitDate *int64 `gorm:"column:last_visit_date"`
+
+	//-- Relations ----------
+	Visits []*firefoxVisit `gorm:"foreignkey:PlaceID"`
+
+	//-- System Variables ----------
+	RevHost      string `gorm:"column:rev_host"`
+	Hidden       int    `gorm:"column:hidden"`
+	Typed        int    `gorm:"column:typed"`
+	Frecency     int    `gorm:"column:frecency"`
+	GUID         string `gorm:"column:guid"`
+	ForeignCount int    `gorm:"column:foreign_count"`
+	URLHash      int64  `gorm:"column:url_hash"`
+	OriginID     uint   `gorm:"column:origin_id"`
+}
+
+func (firefoxPlace) TableName() string {
+	return `moz_places`
+}
+
+type firefoxVisit struct {
+	//-- Primary Key ----------
+	ID uint `gorm:"column:id;primary_key"`
+
+	//-- User Variables ----------
+	PlaceID   uint  `gorm:"column:place_id"`
+	VisitDate int64 `gorm:"column:visit_date"`
+
+	//-- System Variables ----------
+	FromVisit int `gorm:"column:from_visit"`
+	VisitType int `gorm:"column:visit_type"`
+}
+
+func (firefoxVisit) TableName() string {
+	return `moz_historyvisits`
+}
+
+type firefoxOrigin struct {
+	//-- Primary Key ----------
+	ID uint `gorm:"column:id;primary_key"`
+
+	//-- User Variables ----------
+	Prefix   string `gorm:"column:prefix"`
+	Host     string `gorm:"column:host"`
+	Frecency int    `gorm:"column:frecency"`
+}
+
+func (firefoxOrigin) TableName() string {
+	return `moz_origins`
+}
+
+type firefoxBookmark struct {
+	//-- Primary Key ----------
+	ID uint `gorm:"column:id;primary_key"`
+
+	//-- User Variables ----------
+	Type     int    `gorm:"column:type"`
+	FK       *uint  `gorm:"column:fk"`
+	Parent   uint   `gorm:"column:parent"`
+	Position int    `gorm:"column:position"`
+	Title    string `gorm:"column:title"`
+
+	//-- System Variables ----------
+	DateAdded         int64  `gorm:"column:dateAdded"`
+	LastModified      int64  `gorm:"column:lastModified"`
+	GUID              string `gorm:"column:guid"`
+	SyncStatus        int    `gorm:"column:syncStatus"`
+	SyncChangeCounter int    `gorm:"column:syncChangeCounter"`
+}
+
+func (firefoxBookmark) TableName() string {
+	return `moz_bookmarks`
+}
+
+type firefoxPendingBookmark struct {
+	item    Bookmark
+	root    string
+	created int64
 }
 
 //-- Exported Functions ------------------------------------------------------------------------------------------------
-func (f *firefox) InjectHistory(item History) error {
-	return nil
+func (f *firefox) Metadata() Metadata {
+	var metadata = Metadata{Name: `Firefox`, Engine: `Gecko`}
+	for _, profile := range f.profiles {
+		metadata.Profiles = append(metadata.Profiles, profile.name)
+
+		//-- LastVersion carries the build id after an underscore, 115.0_20230710165010/20230710165010 ----------
+		for _, line := range strings.Split(readVersionFile(profile.dataPath+FIREFOX_VERSION_FILE), "\n") {
+			if value := strings.TrimPrefix(strings.TrimSpace(line), `LastVersion=`); value != strings.TrimSpace(line) && metadata.Version == `` {
+				metadata.Version = strings.SplitN(value, `_`, 2)[0]
+			}
+		}
+	}
+
+	return metadata
 }
 
-func (f *firefox) InjectCredential(item Credential) error {
+func (f *firefox) Profiles() []Profile {
+	var profiles []Profile
+	for _, profile := range f.profiles {
+		profiles = append(profiles, profile)
+	}
+
+	return profiles
+}
+
+func (f *firefoxProfile) Name() string {
+	return f.name
+}
+
+func (f *firefoxProfile) Path() string {
+	return f.dataPath
+}
+
+func (f *firefoxProfile) Browser() string {
+	return `Firefox`
+}
+
+func (f *firefoxProfile) AddHistory(ctx context.Context, item History) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	//-- Find or create place, urls are unique in moz_places ----------
+	var entry *firefoxPlace
+	{
+		if f.historyIndex == nil {
+			f.historyIndex = map[string]*firefoxPlace{}
+		}
+		entry = f.historyIndex[item.URL]
+
+		if entry == nil {
+			entry = &firefoxPlace{
+				URL:     item.URL,
+				Title:   item.Name,
+				RevHost: firefoxRevHost(item.URL),
+				GUID:    firefoxGUID(f.random),
+				URLHash: firefoxURLHash(item.URL),
+				Hidden:  1,
+			}
+
+			f.historyItems = append(f.historyItems, entry)
+			f.historyIndex[item.URL] = entry
+		} else if item.Name != `` {
+			entry.Title = item.Name
+		}
+	}
+
+	//-- Add visits ----------
+	{
+		var visits []*firefoxVisit
+		if len(item.Timeline) > 0 {
+			for _, recorded := range item.Timeline {
+				visits = append(visits, &firefoxVisit{
+					VisitDate: prTimestamp(recorded.Time),
+					VisitType: firefoxVisitType(recorded.Transition),
+				})
+			}
+		} else {
+			for i := 0; i < item.Visits; i++ {
+				var visit = &firefoxVisit{VisitDate: f.random.prTimestamp(item.VisitWindow), VisitType: firefoxVisitLink}
+				if f.random.Intn(FIREFOX_TYPED_ONE_IN_X) == 0 {
+					visit.VisitType = firefoxVisitTyped
+				}
+
+				visits = append(visits, visit)
+			}
+		}
+
+		//-- Derive counters from visits ----------
+		for _, visit := range visits {
+			if entry.LastVisitDate == nil || visit.VisitDate > *entry.LastVisitDate {
+				var last = visit.VisitDate
+				entry.LastVisitDate = &last
+			}
+
+			switch visit.VisitType {
+			case firefoxVisitTyped:
+				entry.Typed = 1
+				entry.Hidden = 0
+			case firefoxVisitEmbed, firefoxVisitFramedLink:
+			default:
+				entry.Hidden = 0
+			}
+
+			//NOTE: Places' visit_count trigger leaves out embed, download, framed link and reload visits
+			if visit.VisitType != firefoxVisitEmbed && visit.VisitType != firefoxVisitFramedLink && visit.VisitType != firefoxVisitReload {
+				entry.VisitCount++
+			}
+		}
+
+		entry.Visits = append(entry.Visits, visits...)
+		entry.Frecency = firefoxFrecency(entry)
+	}
+
+	//-- Return ---------
 	return nil
 }
 
-func (f *firefox) InjectBookmark(item Bookmark) error {
+func (f *firefoxProfile) AddBookmark(ctx context.Context, item Bookmark) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	//-- Queue bookmark, foldered bookmarks are kept together on the toolbar ----------
+	{
+		var pending = &firefoxPendingBookmark{item: item, root: FIREFOX_TOOLBAR_GUID}
+
+		if len(item.Folder) == 0 {
+			var roots = []string{FIREFOX_TOOLBAR_GUID, FIREFOX_UNFILED_GUID}
+			pending.root = roots[f.random.Intn(len(roots))]
+		}
+
+		if item.CreatedAt.IsZero() {
+			pending.created = f.random.prTimestamp(item.CreateWindow)
+		} else {
+			pending.created = prTimestamp(item.CreatedAt)
+		}
+
+		f.bookmarkItems = append(f.bookmarkItems, pending)
+	}
+
+	//-- Return ---------
 	return nil
 }
 
-//-- Internal Functions ------------------------------------------------------------------------------------------------
-func (f *firefox) open() error {
+func (f *firefoxProfile) AddAddress(ctx context.Context, item Address) error {
+	//TODO: Addresses belong in autofill-profiles.json
+	return ErrUnsupported
+}
+
+func (f *firefoxProfile) AddSearchEngine(ctx context.Context, item SearchEngine) error {
+	//TODO: Search engines belong in search.json.mozlz4
+	return ErrUnsupported
+}
 
-	// Determine OS-Specific Data Path
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+func (f *firefox) Open(ctx context.Context) error {
+	//-- Determine OS-specific Data Path ----------
 	{
 		switch runtime.GOOS {
 		case `linux`:
@@ -55,13 +347,782 @@ func (f *firefox) open() error {
 		}
 	}
 
+	//-- Parse `profiles.ini` ----------
+	var profiles []*firefoxProfile
+	{
+		if parsed, err := readFirefoxProfiles(f.dataPath); err != nil {
+			return &Error{Browser: `Firefox`, Op: `open`, File: f.dataPath + FIREFOX_PROFILES_FILE, Err: err}
+		} else {
+			profiles = parsed
+		}
+	}
+
+	//-- Connect to detected profiles, those that fail are reported while the rest stay usable ----------
+	var errs Errors
+	{
+		for _, profile := range profiles {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if err := profile.open(); err != nil {
+				errs = append(errs, profileError(profile, `open`, err))
+			} else {
+				f.profiles = append(f.profiles, profile)
+			}
+		}
+
+		if len(f.profiles) < 1 && len(errs) < 1 {
+			return &Error{Browser: `Firefox`, Op: `open`, Err: ErrNotFound}
+		}
+	}
+
+	//-- Return ---------
+	return errs.errorOrNil()
+}
+
+func (f *firefoxProfile) open() error {
+	//-- Open places database ----------
+	{
+		if _, err := os.Stat(f.dataPath + FIREFOX_PLACES_FILE); err != nil {
+			return fileError(f.dataPath+FIREFOX_PLACES_FILE, err)
+		} else if orm, err := openDatabase(f.dataPath + FIREFOX_PLACES_FILE); err != nil {
+			return fileError(f.dataPath+FIREFOX_PLACES_FILE, err)
+		} else if err := orm.DB().Ping(); err != nil {
+			return fileError(f.dataPath+FIREFOX_PLACES_FILE, err)
+		} else {
+			f.placesDatabase = orm
+		}
+	}
+
+	//-- Open cookie and form history databases ----------
+	{
+		if err := f.openCookies(); err != nil {
+			return fileError(f.dataPath+FIREFOX_COOKIES_FILE, err)
+		} else if err := f.openFormHistory(); err != nil {
+			return fileError(f.dataPath+FIREFOX_FORM_HISTORY_FILE, err)
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (f *firefox) Load(ctx context.Context) error {
+	//-- Load each profile ----------
+	{
+		var errs = eachProfile(ctx, `load`, f.Profiles(), func(index int) error {
+			if err := ctx.Err(); err != nil {
+				return profileError(f.profiles[index], `load`, err)
+			}
+
+			return profileError(f.profiles[index], `load`, f.profiles[index].load())
+		})
+
+		if len(errs) > 0 {
+			return errs
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (f *firefoxProfile) load() error {
+	//-- Load places ----------
+	{
+		f.historyItems = []*firefoxPlace{}
+
+		if result := f.placesDatabase.Find(&f.historyItems); result.Error != nil {
+			return fileError(f.dataPath+FIREFOX_PLACES_FILE, result.Error)
+		}
+
+		f.historyIndex = map[string]*firefoxPlace{}
+		for _, item := range f.historyItems {
+			f.historyIndex[item.URL] = item
+		}
+	}
+
+	//-- Load logins ----------
+	{
+		if err := f.loadLogins(); err != nil {
+			return fileError(f.dataPath+FIREFOX_LOGINS_FILE, err)
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (f *firefox) Inspect() ([]Report, error) {
+	//-- Inspect each profile ----------
+	var reports []Report
+	{
+		var errs Errors
+		for _, profile := range f.profiles {
+			if report, err := profile.inspect(); err != nil {
+				errs = append(errs, profileError(profile, `inspect`, err))
+			} else {
+				reports = append(reports, report)
+			}
+		}
+
+		if len(errs) > 0 {
+			return reports, errs
+		}
+	}
+
+	//-- Return ---------
+	return reports, nil
+}
+
+func (f *firefoxProfile) inspect() (Report, error) {
+	var report = Report{
+		Browser:     `Firefox`,
+		Profile:     f.name,
+		Path:        f.dataPath,
+		Credentials: len(f.loginManifest.Logins),
+	}
+
+	//-- Summarise loaded places ----------
+	{
+		var domains = map[string]int{}
+		for _, item := range f.historyItems {
+			if item.VisitCount > 0 {
+				domains[reportDomain(item.URL)] += item.VisitCount
+				report.URLs++
+			}
+		}
+
+		report.rankDomains(domains)
+	}
+
+	//-- Summarise individual visits ----------
+	{
+		if rows, err := f.placesDatabase.Raw(`SELECT visit_date FROM moz_historyvisits`).Rows(); err != nil {
+			return report, fileError(f.dataPath+FIREFOX_PLACES_FILE, err)
+		} else {
+			defer rows.Close()
+
+			for rows.Next() {
+				var timestamp int64
+				if err := rows.Scan(&timestamp); err != nil {
+					return report, fileError(f.dataPath+FIREFOX_PLACES_FILE, err)
+				}
+
+				report.addVisit(fromPRTimestamp(timestamp))
+			}
+
+			if err := rows.Err(); err != nil {
+				return report, fileError(f.dataPath+FIREFOX_PLACES_FILE, err)
+			}
+		}
+	}
+
+	//-- Summarise open tabs ----------
+	{
+		if tabs, err := f.readSessions(); err != nil {
+			return report, fileError(f.dataPath+FIREFOX_SESSION_FILE, err)
+		} else {
+			report.OpenTabs = tabs
+		}
+	}
+
+	//-- Summarise bookmark tree ----------
+	{
+		var bookmarks []*firefoxBookmark
+		if result := f.placesDatabase.Order(`parent, position`).Find(&bookmarks); result.Error != nil {
+			return report, fileError(f.dataPath+FIREFOX_PLACES_FILE, result.Error)
+		}
+
+		var places = map[uint]string{}
+		for _, item := range f.historyItems {
+			places[item.ID] = item.URL
+		}
+
+		var children = map[uint][]*firefoxBookmark{}
+		for _, bookmark := range bookmarks {
+			children[bookmark.Parent] = append(children[bookmark.Parent], bookmark)
+		}
+
+		for _, guid := range FIREFOX_BOOKMARK_ROOTS {
+			for _, bookmark := range bookmarks {
+				if bookmark.GUID == guid {
+					var node = firefoxBookmarkReport(bookmark, children, places)
+					node.Name = FIREFOX_ROOT_NAMES[guid]
+
+					report.Bookmarks = report.Bookmarks + node.count()
+					report.BookmarkTree = append(report.BookmarkTree, node)
+				}
+			}
+		}
+	}
+
+	//-- Return ---------
+	return report, nil
+}
+
+func (f *firefox) Close() error {
+	//-- Close detected profiles ----------
+	{
+		var errs Errors
+		for _, profile := range f.profiles {
+			if err := profile.close(); err != nil {
+				errs = append(errs, profileError(profile, `close`, err))
+			}
+		}
+
+		if len(errs) > 0 {
+			return errs
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (f *firefoxProfile) close() error {
+	//-- Close places database ----------
+	{
+		if err := f.placesDatabase.Close(); err != nil {
+			return fileError(f.dataPath+FIREFOX_PLACES_FILE, err)
+		}
+	}
+
+	//-- Close cookie and form history databases ----------
+	{
+		if err := f.closeCookies(); err != nil {
+			return fileError(f.dataPath+FIREFOX_COOKIES_FILE, err)
+		} else if err := f.closeFormHistory(); err != nil {
+			return fileError(f.dataPath+FIREFOX_FORM_HISTORY_FILE, err)
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (f *firefox) Purge(ctx context.Context) error {
+	//-- Purge detected profiles ----------
+	{
+		var errs = eachProfile(ctx, `purge`, f.Profiles(), func(index int) error {
+			if err := ctx.Err(); err != nil {
+				return profileError(f.profiles[index], `purge`, err)
+			}
+
+			f.profiles[index].purge()
+			return nil
+		})
+
+		if len(errs) > 0 {
+			return errs
+		}
+	}
+
+	//-- Return ---------
 	return nil
 }
 
-func (f *firefox) close() error {
+// purge drops every pending and loaded item, the files themselves are emptied at the start of the next commit.
+func (f *firefoxProfile) purge() {
+	f.purging = true
+
+	f.historyItems = []*firefoxPlace{}
+	f.historyIndex = map[string]*firefoxPlace{}
+	f.bookmarkItems = []*firefoxPendingBookmark{}
+	f.downloadItems = []*firefoxPendingDownload{}
+	f.credentialItems = []*firefoxLogin{}
+	f.cookieItems = []*firefoxCookie{}
+	f.formItems = []*firefoxFormEntry{}
+	f.loginManifest = newFirefoxLogins()
+}
+
+// purgeDatabases empties every database in the profile's open transactions.
+func (f *firefoxProfile) purgeDatabases() error {
+	//-- Purge places database ----------
+	{
+		var tx = f.transactions.begin(f.placesDatabase)
+
+		//-- Purge bookmarks, the built in roots are part of a fresh profile ----------
+		{
+			if result := tx.Exec(`DELETE FROM moz_bookmarks WHERE guid NOT IN (?)`, append([]string{FIREFOX_ROOT_GUID, FIREFOX_TAGS_GUID}, FIREFOX_BOOKMARK_ROOTS...)); result.Error != nil {
+				return fileError(f.dataPath+FIREFOX_PLACES_FILE, result.Error)
+			}
+		}
+
+		//-- Purge visits, places and anything hanging off them ----------
+		{
+			for _, table := range []string{`moz_historyvisits`, `moz_inputhistory`, `moz_annos`, `moz_items_annos`, `moz_keywords`, `moz_places_metadata`, `moz_bookmarks_deleted`, `moz_places`, `moz_origins`} {
+				if !tx.HasTable(table) {
+					continue
+				} else if result := tx.Exec(`DELETE FROM ` + table); result.Error != nil {
+					return fileError(f.dataPath+FIREFOX_PLACES_FILE, result.Error)
+				}
+			}
+		}
+	}
+
+	//-- Purge cookies and form history ----------
+	{
+		if err := f.purgeCookies(); err != nil {
+			return fileError(f.dataPath+FIREFOX_COOKIES_FILE, err)
+		} else if err := f.purgeFormHistory(); err != nil {
+			return fileError(f.dataPath+FIREFOX_FORM_HISTORY_FILE, err)
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (f *firefox) Commit(ctx context.Context) error {
+	//-- Commit detected profiles ----------
+	{
+		var errs = eachProfile(ctx, `commit`, f.Profiles(), func(index int) error {
+			var profile = f.profiles[index]
+			if err := profile.commit(ctx); err != nil {
+				profile.transactions.rollback()
+				profile.staged.rollback()
+				return profileError(profile, `commit`, err)
+			}
+
+			return nil
+		})
+
+		if len(errs) > 0 {
+			return errs
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+// commit writes the staged purge and items to every database in one transaction each, committed in the order they were
+// begun. The logins and session files are staged beside their targets and only moved into place once those hold.
+func (f *firefoxProfile) commit(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	//-- Purge databases ----------
+	{
+		if f.purging {
+			if err := f.purgeDatabases(); err != nil {
+				return err
+			}
+		}
+	}
+
+	//-- Commit pending places, downloads and bookmarks ----------
+	{
+		var tx = f.transactions.begin(f.placesDatabase)
+
+		if err := f.assignOrigins(tx, f.historyItems); err != nil {
+			return fileError(f.dataPath+FIREFOX_PLACES_FILE, err)
+		}
+
+		for _, place := range f.historyItems {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if result := tx.Save(place); result.Error != nil {
+				return fileError(f.dataPath+FIREFOX_PLACES_FILE, result.Error)
+			}
+		}
+
+		if err := f.writeDownloads(tx); err != nil {
+			return fileError(f.dataPath+FIREFOX_PLACES_FILE, err)
+		}
+
+		if err := f.writeBookmarks(tx); err != nil {
+			return fileError(f.dataPath+FIREFOX_PLACES_FILE, err)
+		}
+	}
+
+	//-- Commit pending cookies and form history ----------
+	{
+		if err := f.commitCookies(); err != nil {
+			return fileError(f.dataPath+FIREFOX_COOKIES_FILE, err)
+		} else if err := f.commitFormHistory(); err != nil {
+			return fileError(f.dataPath+FIREFOX_FORM_HISTORY_FILE, err)
+		}
+	}
+
+	//-- Purge saved logins and session store ----------
+	{
+		if f.purging {
+			if err := f.purgeLogins(); err != nil {
+				return fileError(f.dataPath+FIREFOX_LOGINS_FILE, err)
+			} else if err := f.purgeSessions(); err != nil {
+				return fileError(f.dataPath+FIREFOX_SESSION_FILE, err)
+			}
+		}
+	}
+
+	//-- Stage pending logins ----------
+	{
+		if err := f.commitLogins(); err != nil {
+			return fileError(f.dataPath+FIREFOX_LOGINS_FILE, err)
+		}
+	}
+
+	//-- Restore open tabs from the latest visits once the session store is purged ----------
+	{
+		if f.purging {
+			if err := f.commitSessions(); err != nil {
+				return fileError(f.dataPath+FIREFOX_SESSION_FILE, err)
+			}
+		}
+	}
+
+	//-- Commit database transactions, the last point a cancellation leaves the profile untouched ----------
+	{
+		if err := ctx.Err(); err != nil {
+			return err
+		} else if err := f.transactions.commit(); err != nil {
+			return err
+		}
+
+		f.downloadItems = []*firefoxPendingDownload{}
+		f.bookmarkItems = []*firefoxPendingBookmark{}
+		f.cookieItems = []*firefoxCookie{}
+		f.formItems = []*firefoxFormEntry{}
+	}
+
+	//-- Move staged files into place ----------
+	{
+		if err := f.staged.commit(); err != nil {
+			return err
+		}
+	}
+
+	f.purging = false
+
+	//-- Return ---------
+	return nil
+}
+
+// assignOrigins links places to their moz_origins row, which Firefox otherwise maintains with temporary triggers that
+// only exist while the browser has the database open.
+func (f *firefoxProfile) assignOrigins(tx *gorm.DB, places []*firefoxPlace) error {
+	var origins = map[string]*firefoxOrigin{}
+
+	for _, place := range places {
+		if place.OriginID != 0 {
+			continue
+		}
+
+		var prefix, host = firefoxOriginKey(place.URL)
+		var origin, ok = origins[prefix+host]
+		if !ok {
+			origin = new(firefoxOrigin)
+			if result := tx.Where(`prefix = ? AND host = ?`, prefix, host).First(origin); result.RecordNotFound() {
+				origin = &firefoxOrigin{Prefix: prefix, Host: host}
+				if result := tx.Create(origin); result.Error != nil {
+					return fileError(f.dataPath+FIREFOX_PLACES_FILE, result.Error)
+				}
+			} else if result.Error != nil {
+				return fileError(f.dataPath+FIREFOX_PLACES_FILE, result.Error)
+			}
+			origins[prefix+host] = origin
+		}
+
+		origin.Frecency = origin.Frecency + place.Frecency
+		place.OriginID = origin.ID
+	}
+
+	for _, origin := range origins {
+		if result := tx.Model(origin).Update(`frecency`, origin.Frecency); result.Error != nil {
+			return fileError(f.dataPath+FIREFOX_PLACES_FILE, result.Error)
+		}
+	}
+
 	return nil
 }
 
-func (f *firefox) purge() error {
+// writeBookmarks inserts queued bookmarks below their root, creating folders and bookmark-only places as needed and
+// keeping each place's foreign_count in step with the bookmarks pointing at it.
+func (f *firefoxProfile) writeBookmarks(tx *gorm.DB) error {
+	if len(f.bookmarkItems) == 0 {
+		return nil
+	}
+
+	//-- Index places by URL ----------
+	var places = map[string]*firefoxPlace{}
+	{
+		for _, place := range f.historyItems {
+			places[place.URL] = place
+		}
+	}
+
+	for _, pending := range f.bookmarkItems {
+		//-- Find root ----------
+		var parent = new(firefoxBookmark)
+		if result := tx.Where(`guid = ?`, pending.root).First(parent); result.Error != nil {
+			return fileError(f.dataPath+FIREFOX_PLACES_FILE, fmt.Errorf(`%w: missing bookmark root %s`, ErrSchemaUnsupported, pending.root))
+		}
+
+		//-- Walk or create sub-folders ----------
+		for _, name := range pending.item.Folder {
+			var folder = new(firefoxBookmark)
+			if result := tx.Where(`parent = ? AND type = ? AND title = ?`, parent.ID, firefoxBookmarkFolder, name).First(folder); result.RecordNotFound() {
+				if created, err := f.insertBookmark(tx, parent, &firefoxBookmark{Type: firefoxBookmarkFolder, Title: name, DateAdded: pending.created}); err != nil {
+					return fileError(f.dataPath+FIREFOX_PLACES_FILE, err)
+				} else {
+					folder = created
+				}
+			} else if result.Error != nil {
+				return fileError(f.dataPath+FIREFOX_PLACES_FILE, result.Error)
+			} else if pending.created < folder.DateAdded {
+				if result := tx.Model(folder).Update(`dateAdded`, pending.created); result.Error != nil {
+					return fileError(f.dataPath+FIREFOX_PLACES_FILE, result.Error)
+				}
+			}
+
+			parent = folder
+		}
+
+		//-- Find or create place ----------
+		var place, ok = places[pending.item.URL]
+		if !ok {
+			place = &firefoxPlace{
+				URL:     pending.item.URL,
+				Title:   pending.item.Name,
+				RevHost: firefoxRevHost(pending.item.URL),
+				GUID:    firefoxGUID(f.random),
+				URLHash: firefoxURLHash(pending.item.URL),
+			}
+
+			if err := f.assignOrigins(tx, []*firefoxPlace{place}); err != nil {
+				return fileError(f.dataPath+FIREFOX_PLACES_FILE, err)
+			} else if result := tx.Create(place); result.Error != nil {
+				return fileError(f.dataPath+FIREFOX_PLACES_FILE, result.Error)
+			}
+
+			places[place.URL] = place
+		}
+
+		//-- Insert bookmark ----------
+		{
+			var fk = place.ID
+			if _, err := f.insertBookmark(tx, parent, &firefoxBookmark{Type: firefoxBookmarkURL, FK: &fk, Title: pending.item.Name, DateAdded: pending.created}); err != nil {
+				return fileError(f.dataPath+FIREFOX_PLACES_FILE, err)
+			}
+
+			place.ForeignCount++
+			if result := tx.Model(place).Update(`foreign_count`, place.ForeignCount); result.Error != nil {
+				return fileError(f.dataPath+FIREFOX_PLACES_FILE, result.Error)
+			}
+		}
+	}
+
 	return nil
 }
+
+func (f *firefoxProfile) insertBookmark(tx *gorm.DB, parent *firefoxBookmark, bookmark *firefoxBookmark) (*firefoxBookmark, error) {
+	var position int
+	if result := tx.Model(&firefoxBookmark{}).Where(`parent = ?`, parent.ID).Count(&position); result.Error != nil {
+		return nil, fileError(f.dataPath+FIREFOX_PLACES_FILE, result.Error)
+	}
+
+	bookmark.Parent = parent.ID
+	bookmark.Position = position
+	bookmark.LastModified = bookmark.DateAdded
+	bookmark.GUID = firefoxGUID(f.random)
+	bookmark.SyncChangeCounter = 1
+
+	if result := tx.Create(bookmark); result.Error != nil {
+		return nil, fileError(f.dataPath+FIREFOX_PLACES_FILE, result.Error)
+	}
+
+	if bookmark.DateAdded > parent.LastModified {
+		parent.LastModified = bookmark.DateAdded
+		if result := tx.Model(parent).Update(`lastModified`, parent.LastModified); result.Error != nil {
+			return nil, fileError(f.dataPath+FIREFOX_PLACES_FILE, result.Error)
+		}
+	}
+
+	return bookmark, nil
+}
+
+// readFirefoxProfiles lists the [ProfileN] sections of profiles.ini, resolving relative paths against the data path.
+func readFirefoxProfiles(dataPath string) ([]*firefoxProfile, error) {
+	var file, err = os.Open(dataPath + FIREFOX_PROFILES_FILE)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var profiles []*firefoxProfile
+	var sections []map[string]string
+	{
+		var scanner = bufio.NewScanner(file)
+		var section map[string]string
+
+		for scanner.Scan() {
+			var line = strings.TrimSpace(scanner.Text())
+
+			if strings.HasPrefix(line, `[`) && strings.HasSuffix(line, `]`) {
+				section = nil
+				if strings.HasPrefix(line, `[Profile`) {
+					section = map[string]string{}
+					sections = append(sections, section)
+				}
+			} else if parts := strings.SplitN(line, `=`, 2); section != nil && len(parts) == 2 {
+				section[parts[0]] = parts[1]
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, section := range sections {
+		var path = filepath.FromSlash(section[`Path`])
+		if section[`IsRelative`] != `0` {
+			path = filepath.Join(dataPath, path)
+		}
+
+		profiles = append(profiles, &firefoxProfile{name: section[`Name`], dataPath: path + string(filepath.Separator), random: newRandom(`Firefox`, section[`Name`])})
+	}
+
+	return profiles, nil
+}
+
+func firefoxVisitType(transition Transition) int {
+	switch transition {
+	case TransitionTyped, TransitionGenerated, TransitionKeyword, TransitionKeywordGenerated:
+		return firefoxVisitTyped
+	case TransitionAutoBookmark:
+		return firefoxVisitBookmark
+	case TransitionAutoSubframe:
+		return firefoxVisitEmbed
+	case TransitionManualSubframe:
+		return firefoxVisitFramedLink
+	case TransitionReload:
+		return firefoxVisitReload
+	default:
+		return firefoxVisitLink
+	}
+}
+
+// firefoxFrecency follows the classic Places frecency: the ten most recent visits are scored by type bonus and age
+// bucket, then scaled up to the full visit count.
+func firefoxFrecency(place *firefoxPlace) int {
+	var visits = append([]*firefoxVisit{}, place.Visits...)
+	sort.Slice(visits, func(i, j int) bool { return visits[i].VisitDate > visits[j].VisitDate })
+
+	if len(visits) > 10 {
+		visits = visits[:10]
+	}
+
+	var points = 0.0
+	for _, visit := range visits {
+		var bonus float64
+		switch visit.VisitType {
+		case firefoxVisitTyped:
+			bonus = 2000
+		case firefoxVisitLink:
+			bonus = 100
+		case firefoxVisitBookmark:
+			bonus = 75
+		}
+
+		var age = time.Since(fromPRTimestamp(visit.VisitDate)).Hours() / 24
+		var weight float64
+		switch {
+		case age <= 4:
+			weight = 100
+		case age <= 14:
+			weight = 70
+		case age <= 31:
+			weight = 50
+		case age <= 90:
+			weight = 30
+		default:
+			weight = 10
+		}
+
+		points = points + bonus/100*weight
+	}
+
+	if len(visits) == 0 || points == 0 {
+		return 0
+	}
+
+	return int(math.Ceil(float64(place.VisitCount) * points / float64(len(visits))))
+}
+
+// firefoxURLHash matches the hash() SQL function Places uses for url_hash: a 16 bit scheme hash above a 32 bit hash
+// of the first 1500 bytes of the URL.
+func firefoxURLHash(raw string) int64 {
+	var scheme = raw
+	if index := strings.IndexByte(raw, ':'); index >= 0 {
+		scheme = raw[:index]
+	}
+
+	var spec = raw
+	if len(spec) > 1500 {
+		spec = spec[:1500]
+	}
+
+	return int64(uint64(mozillaHashString(scheme)&0xffff)<<32 + uint64(mozillaHashString(spec)))
+}
+
+func mozillaHashString(value string) uint32 {
+	var hash uint32
+	for index := 0; index < len(value); index++ {
+		hash = 0x9e3779b9 * (((hash << 5) | (hash >> 27)) ^ uint32(value[index]))
+	}
+
+	return hash
+}
+
+func firefoxRevHost(raw string) string {
+	var parsed, err = url.Parse(raw)
+	if err != nil {
+		return `.`
+	}
+
+	var host = []rune(strings.ToLower(parsed.Hostname()))
+	for i, j := 0, len(host)-1; i < j; i, j = i+1, j-1 {
+		host[i], host[j] = host[j], host[i]
+	}
+
+	return string(host) + `.`
+}
+
+func firefoxOriginKey(raw string) (string, string) {
+	if parsed, err := url.Parse(raw); err != nil || parsed.Host == `` {
+		return strings.SplitN(raw, `:`, 2)[0] + `:`, ``
+	} else {
+		return parsed.Scheme + `://`, strings.ToLower(parsed.Host)
+	}
+}
+
+// firefoxGUID creates the twelve character url-safe base64 identifiers Places uses for places and bookmarks.
+func firefoxGUID(random random) string {
+	var bytes = make([]byte, 9)
+	random.Read(bytes)
+
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+func firefoxBookmarkReport(bookmark *firefoxBookmark, children map[uint][]*firefoxBookmark, places map[uint]string) *BookmarkNode {
+	var node = &BookmarkNode{Name: bookmark.Title}
+
+	if bookmark.Type == firefoxBookmarkURL && bookmark.FK != nil {
+		node.URL = places[*bookmark.FK]
+	}
+
+	for _, child := range children[bookmark.ID] {
+		if child.Type == firefoxBookmarkURL || child.Type == firefoxBookmarkFolder {
+			node.Children = append(node.Children, firefoxBookmarkReport(child, children, places))
+		}
+	}
+
+	return node
+}
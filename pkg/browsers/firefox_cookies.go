@@ -0,0 +1,172 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+var (
+	FIREFOX_COOKIES_FILE = `cookies.sqlite`
+
+	FIREFOX_COOKIE_OPTIONAL_COLUMNS = []string{`rawSameSite`, `schemeMap`, `isPartitionedAttributeSet`}
+)
+
+// Same site and scheme map values, netwerk/cookie/nsICookie.idl
+const (
+	firefoxCookieSameSiteNone = 0
+	firefoxCookieSchemeHTTP   = 0x01
+	firefoxCookieSchemeHTTPS  = 0x02
+)
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+type firefoxCookie struct {
+	//-- Primary Key ----------
+	ID uint `gorm:"column:id;primary_key"`
+
+	//-- User Variables ----------
+	Name         string `gorm:"column:name"`
+	Value        string `gorm:"column:value"`
+	Host         string `gorm:"column:host"`
+	Path         string `gorm:"column:path"`
+	Expiry       int64  `gorm:"column:expiry"`
+	LastAccessed int64  `gorm:"column:lastAccessed"`
+	CreationTime int64  `gorm:"column:creationTime"`
+	IsSecure     bool   `gorm:"column:isSecure"`
+	IsHTTPOnly   bool   `gorm:"column:isHttpOnly"`
+
+	//-- System Variables ----------
+	OriginAttributes          string `gorm:"column:originAttributes"`
+	InBrowserElement          int    `gorm:"column:inBrowserElement"`
+	SameSite                  int    `gorm:"column:sameSite"`
+	RawSameSite               int    `gorm:"column:rawSameSite"`
+	SchemeMap                 int    `gorm:"column:schemeMap"`
+	IsPartitionedAttributeSet bool   `gorm:"column:isPartitionedAttributeSet"`
+}
+
+func (firefoxCookie) TableName() string {
+	return `moz_cookies`
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+func (f *firefoxProfile) AddCookie(ctx context.Context, item Cookie) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	//-- Session cookies only ever live in the session store ----------
+	{
+		if item.Lifetime <= 0 {
+			return nil
+		}
+	}
+
+	//-- Persistent cookies need a cookies.sqlite to go to ----------
+	{
+		if f.cookieDatabase == nil {
+			return fileError(f.dataPath+FIREFOX_COOKIES_FILE, fmt.Errorf(`%w, start Firefox once to create the database`, ErrUnsupported))
+		}
+	}
+
+	//-- Create cookie, replacing any with the same key as Firefox would ----------
+	{
+		var created = f.random.prTimestamp(item.CreateWindow)
+		var lifetime = int64(item.Lifetime / time.Microsecond)
+		var now = prTimestamp(time.Now())
+
+		var earliest = created
+		if now-lifetime > earliest {
+			earliest = now - lifetime //NOTE: Visits refresh persistent cookies, the last one has to fall within a lifetime
+		}
+		var accessed = earliest + f.random.Int63n(now-earliest+1)
+
+		var cookie = &firefoxCookie{
+			Name:         item.Name,
+			Value:        item.Value,
+			Host:         strings.ToLower(item.Host),
+			Path:         item.Path,
+			Expiry:       (accessed + lifetime) / 1000000,
+			LastAccessed: accessed,
+			CreationTime: created,
+			IsSecure:     item.Secure,
+			IsHTTPOnly:   item.HTTPOnly,
+			SameSite:     firefoxCookieSameSiteNone,
+			RawSameSite:  firefoxCookieSameSiteNone,
+			SchemeMap:    firefoxCookieSchemeHTTP,
+		}
+
+		if cookie.Path == `` {
+			cookie.Path = `/`
+		}
+
+		if item.Secure {
+			cookie.SchemeMap = firefoxCookieSchemeHTTPS
+		}
+
+		for index, existing := range f.cookieItems {
+			if existing.Host == cookie.Host && existing.Name == cookie.Name && existing.Path == cookie.Path {
+				f.cookieItems = append(f.cookieItems[:index], f.cookieItems[index+1:]...)
+				break
+			}
+		}
+
+		f.cookieItems = append(f.cookieItems, cookie)
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+func (f *firefoxProfile) openCookies() error {
+	if orm, err := openOptionalDatabase(f.dataPath + FIREFOX_COOKIES_FILE); err != nil {
+		return err
+	} else {
+		f.cookieDatabase = orm
+	}
+
+	return nil
+}
+
+func (f *firefoxProfile) closeCookies() error {
+	if f.cookieDatabase != nil {
+		return f.cookieDatabase.Close()
+	}
+
+	return nil
+}
+
+func (f *firefoxProfile) purgeCookies() error {
+	if f.cookieDatabase == nil {
+		return nil
+	} else if result := f.transactions.begin(f.cookieDatabase).Exec(`DELETE FROM moz_cookies`); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// commitCookies writes pending cookies, leaving out columns added after the schema version of the profile's database.
+func (f *firefoxProfile) commitCookies() error {
+	if f.cookieDatabase == nil {
+		return nil
+	}
+
+	var omitted = missingColumns(f.cookieDatabase, `moz_cookies`, FIREFOX_COOKIE_OPTIONAL_COLUMNS...)
+	var tx = f.transactions.begin(f.cookieDatabase)
+
+	//-- Commit cookies ----------
+	{
+		for _, item := range f.cookieItems {
+			if result := tx.Omit(omitted...).Create(item); result.Error != nil {
+				return result.Error
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,252 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+var (
+	FIREFOX_LOGINS_FILE    = `logins.json`
+	FIREFOX_LOGINS_BACKUP  = `logins-backup.json`
+	FIREFOX_KEY_FILE       = `key4.db`
+	FIREFOX_LOGIN_USES     = 20
+	FIREFOX_LOGINS_VERSION = 3
+)
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+type firefoxKeyMeta struct {
+	ID    string `gorm:"column:id;primary_key"`
+	Item1 []byte `gorm:"column:item1"`
+	Item2 []byte `gorm:"column:item2"`
+}
+
+func (firefoxKeyMeta) TableName() string {
+	return `metaData`
+}
+
+type firefoxKeyEntry struct {
+	//-- Primary Key ----------
+	ID uint `gorm:"column:id;primary_key"`
+
+	//-- User Variables ----------
+	Value []byte `gorm:"column:a11"`
+	KeyID []byte `gorm:"column:a102"`
+
+	//-- System Variables ----------
+	Class     []byte `gorm:"column:a0"`
+	Token     []byte `gorm:"column:a1"`
+	Private   []byte `gorm:"column:a2"`
+	KeyType   []byte `gorm:"column:a100"`
+	Sensitive []byte `gorm:"column:a103"`
+	Encrypt   []byte `gorm:"column:a104"`
+	Decrypt   []byte `gorm:"column:a105"`
+	ValueLen  []byte `gorm:"column:a161"`
+}
+
+func (firefoxKeyEntry) TableName() string {
+	return `nssPrivate`
+}
+
+type firefoxLogins struct {
+	NextID  int             `json:"nextId"`
+	Logins  []*firefoxLogin `json:"logins"`
+	Version int             `json:"version"`
+
+	PotentiallyVulnerablePasswords   []interface{}          `json:"potentiallyVulnerablePasswords"`
+	DismissedBreachAlertsByLoginGUID map[string]interface{} `json:"dismissedBreachAlertsByLoginGUID"`
+}
+
+type firefoxLogin struct {
+	ID                int     `json:"id"`
+	Hostname          string  `json:"hostname"`
+	HTTPRealm         *string `json:"httpRealm"`
+	FormSubmitURL     string  `json:"formSubmitURL"`
+	UsernameField     string  `json:"usernameField"`
+	PasswordField     string  `json:"passwordField"`
+	EncryptedUsername string  `json:"encryptedUsername"`
+	EncryptedPassword string  `json:"encryptedPassword"`
+	GUID              string  `json:"guid"`
+	EncType           int     `json:"encType"`
+
+	TimeCreated         int64 `json:"timeCreated"`
+	TimeLastUsed        int64 `json:"timeLastUsed"`
+	TimePasswordChanged int64 `json:"timePasswordChanged"`
+	TimesUsed           int   `json:"timesUsed"`
+
+	SyncCounter            int     `json:"syncCounter"`
+	EverSynced             bool    `json:"everSynced"`
+	EncryptedUnknownFields *string `json:"encryptedUnknownFields"`
+
+	userName string
+	password string
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+func (f *firefoxProfile) AddCredential(ctx context.Context, item Credential) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	//-- Require a key4.db, NSS signs the key's attributes in ways not reproduced here ----------
+	{
+		if _, err := os.Stat(f.dataPath + FIREFOX_KEY_FILE); os.IsNotExist(err) {
+			return fileError(f.dataPath+FIREFOX_KEY_FILE, fmt.Errorf(`%w, start Firefox once to create the key database`, ErrUnsupported))
+		} else if err != nil {
+			return fileError(f.dataPath+FIREFOX_KEY_FILE, err)
+		}
+	}
+
+	//-- Create login, encrypted once the profile key is known at commit ----------
+	{
+		var origin = item.URL
+		if parsed, err := url.Parse(item.URL); err == nil && parsed.Host != `` {
+			origin = parsed.Scheme + `://` + parsed.Host
+		}
+
+		var created = f.random.unixTimestamp(item.CreateWindow) * 1000
+		var used = created + f.random.Int63n(time.Now().UnixNano()/int64(time.Millisecond)-created+1)
+
+		f.credentialItems = append(f.credentialItems, &firefoxLogin{
+			Hostname:            origin,
+			FormSubmitURL:       origin,
+			GUID:                `{` + f.random.guid() + `}`,
+			EncType:             1,
+			TimeCreated:         created,
+			TimeLastUsed:        used,
+			TimePasswordChanged: created,
+			TimesUsed:           1 + f.random.Intn(FIREFOX_LOGIN_USES),
+			userName:            item.UserName,
+			password:            item.Password,
+		})
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+func (f *firefoxProfile) loadLogins() error {
+	f.loginManifest = newFirefoxLogins()
+
+	var file, err = os.Open(f.dataPath + FIREFOX_LOGINS_FILE)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fileError(f.dataPath+FIREFOX_LOGINS_FILE, err)
+	}
+	defer file.Close()
+
+	return fileError(f.dataPath+FIREFOX_LOGINS_FILE, json.NewDecoder(file).Decode(f.loginManifest))
+}
+
+// purgeLogins rewrites logins.json from the manifest purge emptied, dropping the backup Firefox would restore from.
+func (f *firefoxProfile) purgeLogins() error {
+	if err := f.staged.remove(f.dataPath + FIREFOX_LOGINS_BACKUP); err != nil {
+		return err
+	}
+
+	return f.writeLogins()
+}
+
+// commitLogins encrypts pending logins with the key of the profile's key4.db.
+func (f *firefoxProfile) commitLogins() error {
+	if len(f.credentialItems) == 0 {
+		return nil
+	}
+
+	var key, err = f.loginKey()
+	if err != nil {
+		return fileError(f.dataPath+FIREFOX_KEY_FILE, err)
+	}
+
+	for _, login := range f.credentialItems {
+		if login.EncryptedUsername, err = nssEncryptLogin(key, login.userName); err != nil {
+			return err
+		} else if login.EncryptedPassword, err = nssEncryptLogin(key, login.password); err != nil {
+			return err
+		}
+
+		login.ID = f.loginManifest.NextID
+		f.loginManifest.NextID++
+		f.loginManifest.Logins = append(f.loginManifest.Logins, login)
+	}
+
+	f.credentialItems = []*firefoxLogin{}
+
+	return f.writeLogins()
+}
+
+func (f *firefoxProfile) writeLogins() error {
+	var file, err = f.staged.create(f.dataPath+FIREFOX_LOGINS_FILE, 0666)
+	if err != nil {
+		return err
+	}
+
+	if err := json.NewEncoder(file).Encode(f.loginManifest); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// loginKey recovers the logins key from key4.db using the empty primary password, profiles protected by a primary
+// password are refused rather than guessed at.
+func (f *firefoxProfile) loginKey() ([]byte, error) {
+	if _, err := os.Stat(f.dataPath + FIREFOX_KEY_FILE); err != nil {
+		return nil, err //NOTE: Opening a missing key4.db would create an empty one
+	}
+
+	var orm, err = openDatabase(f.dataPath + FIREFOX_KEY_FILE)
+	if err != nil {
+		return nil, err
+	}
+	defer orm.Close()
+
+	//-- Verify empty primary password ----------
+	var meta firefoxKeyMeta
+	{
+		if result := orm.Where(`id = ?`, `password`).First(&meta); result.Error != nil {
+			return nil, result.Error
+		}
+
+		if check, err := nssDecrypt(meta.Item1, nil, meta.Item2); err != nil || !bytes.HasPrefix(check, NSS_PASSWORD_CHECK) {
+			return nil, errors.New(`protected by a primary password, unable to act`)
+		}
+	}
+
+	//-- Decrypt logins key ----------
+	{
+		var entries []*firefoxKeyEntry
+		if result := orm.Find(&entries); result.Error != nil {
+			return nil, result.Error
+		}
+
+		for _, entry := range entries {
+			if bytes.Equal(entry.KeyID, NSS_KEY_ID) {
+				return nssDecrypt(meta.Item1, nil, entry.Value)
+			}
+		}
+	}
+
+	return nil, fmt.Errorf(`logins key %w`, ErrNotFound)
+}
+
+func newFirefoxLogins() *firefoxLogins {
+	return &firefoxLogins{
+		NextID:                           1,
+		Logins:                           []*firefoxLogin{},
+		Version:                          FIREFOX_LOGINS_VERSION,
+		PotentiallyVulnerablePasswords:   []interface{}{},
+		DismissedBreachAlertsByLoginGUID: map[string]interface{}{},
+	}
+}
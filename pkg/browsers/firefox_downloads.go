@@ -0,0 +1,223 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+var (
+	FIREFOX_DESTINATION_ANNOTATION = `downloads/destinationFileURI`
+	FIREFOX_METADATA_ANNOTATION    = `downloads/metaData`
+	FIREFOX_DOWNLOAD_RATE          = int64(2 << 20) // Bytes per second assumed when deriving a download's end time
+
+	FIREFOX_LINUX_DOWNLOAD_PATH   = fmt.Sprintf(`%s/Downloads/`, os.Getenv(`HOME`))
+	FIREFOX_DARWIN_DOWNLOAD_PATH  = fmt.Sprintf(`%s/Downloads/`, os.Getenv(`HOME`))
+	FIREFOX_WINDOWS_DOWNLOAD_PATH = fmt.Sprintf(`%s\Downloads\`, os.Getenv(`USERPROFILE`))
+)
+
+// Download state and annotation storage values, toolkit/components/downloads/DownloadHistory.sys.mjs and
+// nsIAnnotationService.idl
+const (
+	firefoxVisitDownload = 7
+
+	firefoxDownloadFinished = 1
+
+	firefoxAnnotationExpireNever = 4
+	firefoxAnnotationString      = 3
+)
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+type firefoxAnnotationAttribute struct {
+	//-- Primary Key ----------
+	ID uint `gorm:"column:id;primary_key"`
+
+	//-- User Variables ----------
+	Name string `gorm:"column:name"`
+}
+
+func (firefoxAnnotationAttribute) TableName() string {
+	return `moz_anno_attributes`
+}
+
+type firefoxAnnotation struct {
+	//-- Primary Key ----------
+	ID uint `gorm:"column:id;primary_key"`
+
+	//-- User Variables ----------
+	PlaceID     uint   `gorm:"column:place_id"`
+	AttributeID uint   `gorm:"column:anno_attribute_id"`
+	Content     string `gorm:"column:content"`
+
+	//-- System Variables ----------
+	Flags        int   `gorm:"column:flags"`
+	Expiration   int   `gorm:"column:expiration"`
+	Type         int   `gorm:"column:type"`
+	DateAdded    int64 `gorm:"column:dateAdded"`
+	LastModified int64 `gorm:"column:lastModified"`
+}
+
+func (firefoxAnnotation) TableName() string {
+	return `moz_annos`
+}
+
+// firefoxDownloadMeta is the JSON kept in the downloads/metaData annotation, in the field order Firefox writes it.
+type firefoxDownloadMeta struct {
+	State    int   `json:"state"`
+	Deleted  bool  `json:"deleted"`
+	EndTime  int64 `json:"endTime"`
+	FileSize int64 `json:"fileSize"`
+}
+
+type firefoxPendingDownload struct {
+	place       *firefoxPlace
+	destination string
+	meta        firefoxDownloadMeta
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+func (f *firefoxProfile) AddDownload(ctx context.Context, item Download) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	//-- Find or create the source place ----------
+	var place *firefoxPlace
+	{
+		if f.historyIndex == nil {
+			f.historyIndex = map[string]*firefoxPlace{}
+		}
+		place = f.historyIndex[item.URL]
+
+		if place == nil {
+			place = &firefoxPlace{
+				URL:     item.URL,
+				Title:   filepath.Base(item.FileName),
+				RevHost: firefoxRevHost(item.URL),
+				GUID:    firefoxGUID(f.random),
+				URLHash: firefoxURLHash(item.URL),
+			}
+
+			f.historyItems = append(f.historyItems, place)
+			f.historyIndex[item.URL] = place
+		}
+	}
+
+	//-- Record the download visit, which Places leaves out of visit_count ----------
+	var started = f.random.prTimestamp(item.CreateWindow)
+	{
+		place.Visits = append(place.Visits, &firefoxVisit{VisitDate: started, VisitType: firefoxVisitDownload})
+		place.Hidden = 0
+
+		if place.LastVisitDate == nil || started > *place.LastVisitDate {
+			place.LastVisitDate = &started
+		}
+
+		place.Frecency = firefoxFrecency(place)
+	}
+
+	//-- Queue annotations until the place has an id ----------
+	{
+		var finished = started + (1+item.Size/FIREFOX_DOWNLOAD_RATE)*1000000
+
+		f.downloadItems = append(f.downloadItems, &firefoxPendingDownload{
+			place:       place,
+			destination: firefoxFileURI(firefoxDownloadPath() + item.FileName),
+			meta: firefoxDownloadMeta{
+				State:    firefoxDownloadFinished,
+				EndTime:  finished / 1000,
+				FileSize: item.Size,
+			},
+		})
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+// writeDownloads stores the destination and metadata annotations of queued downloads, their places must already be
+// saved.
+func (f *firefoxProfile) writeDownloads(tx *gorm.DB) error {
+	if len(f.downloadItems) == 0 {
+		return nil
+	}
+
+	//-- Find or create annotation names ----------
+	var attributes = map[string]uint{}
+	{
+		for _, name := range []string{FIREFOX_DESTINATION_ANNOTATION, FIREFOX_METADATA_ANNOTATION} {
+			var attribute = new(firefoxAnnotationAttribute)
+			if result := tx.Where(`name = ?`, name).First(attribute); result.RecordNotFound() {
+				attribute = &firefoxAnnotationAttribute{Name: name}
+				if result := tx.Create(attribute); result.Error != nil {
+					return result.Error
+				}
+			} else if result.Error != nil {
+				return result.Error
+			}
+
+			attributes[name] = attribute.ID
+		}
+	}
+
+	//-- Insert annotations ----------
+	{
+		for _, pending := range f.downloadItems {
+			var meta, err = json.Marshal(pending.meta)
+			if err != nil {
+				return err
+			}
+
+			var modified = pending.meta.EndTime * 1000
+			for _, content := range [][2]string{{FIREFOX_DESTINATION_ANNOTATION, pending.destination}, {FIREFOX_METADATA_ANNOTATION, string(meta)}} {
+				var annotation = &firefoxAnnotation{
+					PlaceID:      pending.place.ID,
+					AttributeID:  attributes[content[0]],
+					Content:      content[1],
+					Expiration:   firefoxAnnotationExpireNever,
+					Type:         firefoxAnnotationString,
+					DateAdded:    modified,
+					LastModified: modified,
+				}
+
+				if result := tx.Create(annotation); result.Error != nil {
+					return result.Error
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func firefoxDownloadPath() string {
+	switch runtime.GOOS {
+	case `darwin`:
+		return FIREFOX_DARWIN_DOWNLOAD_PATH
+	case `windows`:
+		return FIREFOX_WINDOWS_DOWNLOAD_PATH
+	default:
+		return FIREFOX_LINUX_DOWNLOAD_PATH
+	}
+}
+
+// firefoxFileURI converts a local path to the file:/// form Firefox records, drive letters included.
+func firefoxFileURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, `/`) {
+		path = `/` + path
+	}
+
+	return (&url.URL{Scheme: `file`, Path: path}).String()
+}
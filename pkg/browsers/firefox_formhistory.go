@@ -0,0 +1,130 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+var (
+	FIREFOX_FORM_HISTORY_FILE = `formhistory.sqlite`
+)
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+type firefoxFormEntry struct {
+	//-- Primary Key ----------
+	ID uint `gorm:"column:id;primary_key"`
+
+	//-- User Variables ----------
+	FieldName string `gorm:"column:fieldname"`
+	Value     string `gorm:"column:value"`
+	TimesUsed int    `gorm:"column:timesUsed"`
+	FirstUsed int64  `gorm:"column:firstUsed"`
+	LastUsed  int64  `gorm:"column:lastUsed"`
+
+	//-- System Variables ----------
+	GUID string `gorm:"column:guid"`
+}
+
+func (firefoxFormEntry) TableName() string {
+	return `moz_formhistory`
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+func (f *firefoxProfile) AddFormEntry(ctx context.Context, item FormEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	} else if f.formDatabase == nil {
+		return fileError(f.dataPath+FIREFOX_FORM_HISTORY_FILE, fmt.Errorf(`%w, start Firefox once to create the database`, ErrUnsupported))
+	}
+
+	//-- Merge repeated values, Firefox keeps one row per field name and value ----------
+	{
+		var uses = item.Uses
+		if uses < 1 {
+			uses = 1
+		}
+
+		for _, existing := range f.formItems {
+			if existing.FieldName == item.Name && existing.Value == item.Value {
+				existing.TimesUsed = existing.TimesUsed + uses
+				return nil
+			}
+		}
+
+		var first = f.random.prTimestamp(item.CreateWindow)
+		f.formItems = append(f.formItems, &firefoxFormEntry{
+			FieldName: item.Name,
+			Value:     item.Value,
+			TimesUsed: uses,
+			FirstUsed: first,
+			LastUsed:  first + f.random.Int63n(prTimestamp(time.Now())-first+1),
+			GUID:      firefoxGUID(f.random),
+		})
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+func (f *firefoxProfile) openFormHistory() error {
+	if orm, err := openOptionalDatabase(f.dataPath + FIREFOX_FORM_HISTORY_FILE); err != nil {
+		return err
+	} else {
+		f.formDatabase = orm
+	}
+
+	return nil
+}
+
+func (f *firefoxProfile) closeFormHistory() error {
+	if f.formDatabase != nil {
+		return f.formDatabase.Close()
+	}
+
+	return nil
+}
+
+func (f *firefoxProfile) purgeFormHistory() error {
+	if f.formDatabase == nil {
+		return nil
+	}
+
+	var tx = f.transactions.begin(f.formDatabase)
+
+	//-- Purge values, their sources and the deletion log sync reads ----------
+	{
+		for _, table := range []string{`moz_history_to_sources`, `moz_sources`, `moz_formhistory`, `moz_deleted_formhistory`} {
+			if !tx.HasTable(table) {
+				continue
+			} else if result := tx.Exec(`DELETE FROM ` + table); result.Error != nil {
+				return result.Error
+			}
+		}
+	}
+
+	return nil
+}
+
+func (f *firefoxProfile) commitFormHistory() error {
+	if f.formDatabase == nil {
+		return nil
+	}
+
+	var tx = f.transactions.begin(f.formDatabase)
+
+	//-- Commit form values ----------
+	{
+		for _, item := range f.formItems {
+			if result := tx.Create(item); result.Error != nil {
+				return result.Error
+			}
+		}
+	}
+
+	return nil
+}
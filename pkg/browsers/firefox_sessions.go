@@ -0,0 +1,290 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+var (
+	FIREFOX_SESSION_FILE        = `sessionstore.jsonlz4`
+	FIREFOX_SESSION_BACKUPS     = `sessionstore-backups`
+	FIREFOX_RECOVERY_FILE       = `recovery.jsonlz4`
+	FIREFOX_LEGACY_SESSIONS     = []string{`sessionstore.js`, `sessionstore.bak`}
+	FIREFOX_SESSION_TABS        = 6 // Most tabs left open in the synthesized window
+	FIREFOX_SESSION_CLOSED      = 4 // Most recently closed tabs offered by Ctrl+Shift+T
+	FIREFOX_SESSION_NAVIGATIONS = 3 // Most back/forward entries per tab
+
+	FIREFOX_SYSTEM_PRINCIPAL = `{"3":{}}` // Serialised system principal, the triggering principal of a restored load
+	FIREFOX_WINDOW_SIZES     = [][2]int{{1280, 800}, {1366, 768}, {1440, 900}, {1920, 1080}, {1600, 900}}
+)
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+type firefoxSession struct {
+	Version        []interface{}           `json:"version"`
+	Windows        []*firefoxSessionWindow `json:"windows"`
+	SelectedWindow int                     `json:"selectedWindow"`
+	ClosedWindows  []*firefoxSessionWindow `json:"_closedWindows"`
+	Session        firefoxSessionMeta      `json:"session"`
+	Global         map[string]interface{}  `json:"global"`
+}
+
+type firefoxSessionMeta struct {
+	LastUpdate    int64 `json:"lastUpdate"`
+	StartTime     int64 `json:"startTime"`
+	RecentCrashes int   `json:"recentCrashes"`
+}
+
+type firefoxSessionWindow struct {
+	Tabs       []*firefoxSessionTab `json:"tabs"`
+	Selected   int                  `json:"selected"`
+	ClosedTabs []*firefoxClosedTab  `json:"_closedTabs"`
+	Busy       bool                 `json:"busy"`
+	Width      int                  `json:"width"`
+	Height     int                  `json:"height"`
+	ScreenX    int                  `json:"screenX"`
+	ScreenY    int                  `json:"screenY"`
+	SizeMode   string               `json:"sizemode"`
+	ZIndex     int                  `json:"zIndex"`
+}
+
+type firefoxSessionTab struct {
+	Entries       []*firefoxSessionEntry `json:"entries"`
+	LastAccessed  int64                  `json:"lastAccessed"`
+	Hidden        bool                   `json:"hidden"`
+	Attributes    map[string]interface{} `json:"attributes"`
+	Index         int                    `json:"index"`
+	UserContextID int                    `json:"userContextId"`
+}
+
+type firefoxSessionEntry struct {
+	URL                 string `json:"url"`
+	Title               string `json:"title"`
+	CacheKey            int    `json:"cacheKey"`
+	ID                  int    `json:"ID"`
+	DocShellUUID        string `json:"docshellUUID"`
+	TriggeringPrincipal string `json:"triggeringPrincipal_base64"`
+	DocIdentifier       int    `json:"docIdentifier"`
+	Persist             bool   `json:"persist"`
+	HasUserInteraction  bool   `json:"hasUserInteraction"`
+}
+
+type firefoxClosedTab struct {
+	State    *firefoxSessionTab `json:"state"`
+	Title    string             `json:"title"`
+	Image    *string            `json:"image"`
+	Position int                `json:"pos"`
+	ClosedAt int64              `json:"closedAt"`
+	ClosedID int                `json:"closedId"`
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+// purgeSessions removes the session store and its rolling backups, Firefox falls back to a blank window without them.
+func (f *firefoxProfile) purgeSessions() error {
+	var paths = []string{f.dataPath + FIREFOX_SESSION_FILE}
+
+	for _, name := range FIREFOX_LEGACY_SESSIONS {
+		paths = append(paths, f.dataPath+name)
+	}
+
+	if matches, err := filepath.Glob(filepath.Join(f.dataPath+FIREFOX_SESSION_BACKUPS, `*`)); err != nil {
+		return err
+	} else {
+		paths = append(paths, matches...)
+	}
+
+	for _, path := range paths {
+		if err := f.staged.remove(path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// commitSessions writes the latest visits as a single window of open tabs with a few recently closed ones, both as the
+// clean shutdown session and as the crash recovery copy. It follows a purge, any other session is left to Firefox.
+func (f *firefoxProfile) commitSessions() error {
+	var tabs = firefoxSessionTabs(f.random, f.historyItems)
+	if len(tabs) == 0 {
+		return nil
+	}
+
+	var open = 1 + f.random.Intn(FIREFOX_SESSION_TABS)
+	if open > len(tabs) {
+		open = len(tabs)
+	}
+
+	//-- Build session ----------
+	var session *firefoxSession
+	{
+		var size = FIREFOX_WINDOW_SIZES[f.random.Intn(len(FIREFOX_WINDOW_SIZES))]
+		var window = &firefoxSessionWindow{
+			Tabs:       tabs[:open],
+			Selected:   1,
+			ClosedTabs: []*firefoxClosedTab{},
+			Width:      size[0],
+			Height:     size[1],
+			SizeMode:   `normal`,
+			ZIndex:     1,
+		}
+
+		for index, tab := range tabs[open:] {
+			var entry = tab.Entries[tab.Index-1]
+			window.ClosedTabs = append(window.ClosedTabs, &firefoxClosedTab{
+				State:    tab,
+				Title:    entry.Title,
+				Position: open + index,
+				ClosedAt: tab.LastAccessed,
+				ClosedID: index,
+			})
+		}
+
+		var now = time.Now().UnixNano() / int64(time.Millisecond)
+		session = &firefoxSession{
+			Version:        []interface{}{`sessionrestore`, 1},
+			Windows:        []*firefoxSessionWindow{window},
+			SelectedWindow: 1,
+			ClosedWindows:  []*firefoxSessionWindow{},
+			Session:        firefoxSessionMeta{LastUpdate: now, StartTime: tabs[open-1].LastAccessed},
+			Global:         map[string]interface{}{},
+		}
+	}
+
+	//-- Write session and recovery copy ----------
+	{
+		var buffer bytes.Buffer
+		if err := json.NewEncoder(&buffer).Encode(session); err != nil {
+			return err
+		}
+
+		for _, path := range []string{f.dataPath + FIREFOX_SESSION_FILE, filepath.Join(f.dataPath+FIREFOX_SESSION_BACKUPS, FIREFOX_RECOVERY_FILE)} {
+			if err := writeFirefoxSessionFile(&f.staged, path, bytes.TrimSpace(buffer.Bytes())); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// readSessions returns the selected entry of every open tab, preferring the crash recovery copy Firefox keeps updated
+// while running over the one written at shutdown.
+func (f *firefoxProfile) readSessions() ([]SessionTab, error) {
+	var session firefoxSession
+	{
+		var file *os.File
+		for _, path := range []string{filepath.Join(f.dataPath+FIREFOX_SESSION_BACKUPS, FIREFOX_RECOVERY_FILE), f.dataPath + FIREFOX_SESSION_FILE} {
+			if handle, err := os.Open(path); err == nil {
+				file = handle
+				break
+			} else if !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+
+		if file == nil {
+			return nil, nil
+		}
+		defer file.Close()
+
+		if data, err := readMozLz4(file); err != nil {
+			return nil, err
+		} else if err := json.Unmarshal(data, &session); err != nil {
+			return nil, err
+		}
+	}
+
+	var tabs []SessionTab
+	for _, window := range session.Windows {
+		for _, tab := range window.Tabs {
+			if tab.Index < 1 || tab.Index > len(tab.Entries) {
+				continue
+			}
+
+			var entry = tab.Entries[tab.Index-1]
+			tabs = append(tabs, SessionTab{
+				Title:      entry.Title,
+				URL:        entry.URL,
+				LastActive: time.Unix(0, tab.LastAccessed*int64(time.Millisecond)),
+			})
+		}
+	}
+
+	return tabs, nil
+}
+
+// firefoxSessionTabs splits the most recent top level visits into tabs of a few entries each, newest tab first.
+func firefoxSessionTabs(random random, places []*firefoxPlace) []*firefoxSessionTab {
+	type navigation struct {
+		place *firefoxPlace
+		date  int64
+	}
+
+	var navigations []navigation
+	for _, place := range places {
+		for _, visit := range place.Visits {
+			if visit.VisitType != firefoxVisitEmbed && visit.VisitType != firefoxVisitFramedLink {
+				navigations = append(navigations, navigation{place: place, date: visit.VisitDate})
+			}
+		}
+	}
+
+	sort.Slice(navigations, func(i, j int) bool { return navigations[i].date > navigations[j].date })
+
+	var tabs []*firefoxSessionTab
+	var id = 0
+	for len(navigations) > 0 && len(tabs) < FIREFOX_SESSION_TABS+FIREFOX_SESSION_CLOSED {
+		var size = 1 + random.Intn(FIREFOX_SESSION_NAVIGATIONS)
+		if size > len(navigations) {
+			size = len(navigations)
+		}
+
+		var tab = &firefoxSessionTab{
+			LastAccessed: navigations[0].date / 1000,
+			Attributes:   map[string]interface{}{},
+			Index:        size,
+		}
+
+		for index := size - 1; index >= 0; index-- {
+			id++
+			tab.Entries = append(tab.Entries, &firefoxSessionEntry{
+				URL:                 navigations[index].place.URL,
+				Title:               navigations[index].place.Title,
+				ID:                  id,
+				DocShellUUID:        `{` + random.guid() + `}`,
+				TriggeringPrincipal: FIREFOX_SYSTEM_PRINCIPAL,
+				DocIdentifier:       id,
+				Persist:             true,
+				HasUserInteraction:  index > 0,
+			})
+		}
+
+		tabs = append(tabs, tab)
+		navigations = navigations[size:]
+	}
+
+	return tabs
+}
+
+func writeFirefoxSessionFile(staged *stagedFiles, path string, data []byte) error {
+	var file, err = staged.create(path, 0600)
+	if err != nil {
+		return err
+	}
+
+	if err := writeMozLz4(file, data); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,150 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+// FIREFOX_TEST_PLACES_SCHEMA holds the places.sqlite tables a Firefox profile writes to as Firefox creates them, with the
+// bookmark roots and one page already visited and bookmarked on the toolbar.
+var FIREFOX_TEST_PLACES_SCHEMA = []string{
+	`CREATE TABLE moz_origins ( id INTEGER PRIMARY KEY, prefix TEXT NOT NULL, host TEXT NOT NULL, frecency INTEGER NOT NULL, recalc_frecency INTEGER NOT NULL DEFAULT 0, alt_frecency INTEGER, recalc_alt_frecency INTEGER NOT NULL DEFAULT 0, UNIQUE (prefix, host) )`,
+	`CREATE TABLE moz_places (   id INTEGER PRIMARY KEY, url LONGVARCHAR, title LONGVARCHAR, rev_host LONGVARCHAR, visit_count INTEGER DEFAULT 0, hidden INTEGER DEFAULT 0 NOT NULL, typed INTEGER DEFAULT 0 NOT NULL, frecency INTEGER DEFAULT -1 NOT NULL, last_visit_date INTEGER , guid TEXT, foreign_count INTEGER DEFAULT 0 NOT NULL, url_hash INTEGER DEFAULT 0 NOT NULL , description TEXT, preview_image_url TEXT, site_name TEXT, origin_id INTEGER REFERENCES moz_origins(id), recalc_frecency INTEGER NOT NULL DEFAULT 0, alt_frecency INTEGER, recalc_alt_frecency INTEGER NOT NULL DEFAULT 0)`,
+	`CREATE TABLE moz_historyvisits (  id INTEGER PRIMARY KEY, from_visit INTEGER, place_id INTEGER, visit_date INTEGER, visit_type INTEGER, session INTEGER, source INTEGER DEFAULT 0 NOT NULL, triggeringPlaceId INTEGER)`,
+	`CREATE TABLE moz_bookmarks (  id INTEGER PRIMARY KEY, type INTEGER, fk INTEGER DEFAULT NULL, parent INTEGER, position INTEGER, title LONGVARCHAR, keyword_id INTEGER, folder_type TEXT, dateAdded INTEGER, lastModified INTEGER, guid TEXT, syncStatus INTEGER NOT NULL DEFAULT 0, syncChangeCounter INTEGER NOT NULL DEFAULT 1)`,
+	`CREATE TABLE moz_anno_attributes (  id INTEGER PRIMARY KEY, name VARCHAR(32) UNIQUE NOT NULL)`,
+	`CREATE TABLE moz_annos (  id INTEGER PRIMARY KEY, place_id INTEGER NOT NULL, anno_attribute_id INTEGER, content LONGVARCHAR, flags INTEGER DEFAULT 0, expiration INTEGER DEFAULT 0, type INTEGER DEFAULT 0, dateAdded INTEGER DEFAULT 0, lastModified INTEGER DEFAULT 0)`,
+	`INSERT INTO moz_bookmarks VALUES (1,2,NULL,0,0,'',NULL,NULL,1600000000000000,1600000000000000,'root________',1,1), (2,2,NULL,1,0,'menu',NULL,NULL,1600000000000000,1600000000000000,'menu________',1,1), (3,2,NULL,1,1,'toolbar',NULL,NULL,1600000000000000,1600000000000000,'toolbar_____',1,1), (4,2,NULL,1,2,'tags',NULL,NULL,1600000000000000,1600000000000000,'tags________',1,1), (5,2,NULL,1,3,'unfiled',NULL,NULL,1600000000000000,1600000000000000,'unfiled_____',1,1), (6,2,NULL,1,4,'mobile',NULL,NULL,1600000000000000,1600000000000000,'mobile______',1,1)`,
+	`INSERT INTO moz_origins VALUES (1,'https://','real.example.com',100,0,NULL,0)`,
+	`INSERT INTO moz_places VALUES (1,'https://real.example.com/','Real','moc.elpmaxe.laer.',1,0,0,100,1600000000000000,'aaaaaaaaaaaa',1,0,NULL,NULL,NULL,1,0,NULL,0)`,
+	`INSERT INTO moz_historyvisits VALUES (1,0,1,1600000000000000,1,0,0,NULL)`,
+	`INSERT INTO moz_bookmarks VALUES (7,1,1,3,0,'Real',NULL,NULL,1600000000000000,1600000000000000,'bbbbbbbbbbbb',1,1)`,
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+// TestFirefoxCommitPlaces adds visits of every kind and foldered bookmarks to a loaded profile, checking the counters
+// Places' triggers would keep, the origins and the bookmark tree written in their place.
+func TestFirefoxCommitPlaces(t *testing.T) {
+	var profile = openFirefoxProfile(t)
+	var ctx = context.Background()
+	var now = time.Now().Add(-time.Hour)
+
+	if err := profile.load(); err != nil {
+		t.Fatalf(`load: %s`, err)
+	}
+
+	for _, item := range []History{
+		{URL: `https://real.example.com/`, Timeline: []Visit{{Time: now, Transition: TransitionTyped}}},
+		{URL: `https://news.example.org/a`, Name: `News`, Timeline: []Visit{
+			{Time: now, Transition: TransitionLink},
+			{Time: now.Add(time.Second), Transition: TransitionAutoSubframe},
+			{Time: now.Add(2 * time.Second), Transition: TransitionReload},
+			{Time: now.Add(3 * time.Second), Transition: TransitionManualSubframe},
+		}},
+		{URL: `https://ads.example.net/frame`, Timeline: []Visit{{Time: now, Transition: TransitionAutoSubframe}}},
+	} {
+		if err := profile.AddHistory(ctx, item); err != nil {
+			t.Fatalf(`AddHistory: %s`, err)
+		}
+	}
+
+	for _, item := range []Bookmark{
+		{Name: `Manual`, URL: `https://docs.example.com/`, Folder: []string{`Work`, `Docs`}, CreatedAt: now},
+		{Name: `News`, URL: `https://news.example.org/a`, Folder: []string{`Work`}, CreatedAt: now.Add(time.Minute)},
+	} {
+		if err := profile.AddBookmark(ctx, item); err != nil {
+			t.Fatalf(`AddBookmark: %s`, err)
+		}
+	}
+
+	if err := profile.commit(ctx); err != nil {
+		t.Fatalf(`commit: %s`, err)
+	}
+	expectFile(t, profile.dataPath+FIREFOX_SESSION_FILE, ``) //NOTE: Without a purge the session store is Firefox's own
+
+	var cases = []struct {
+		query string
+		want  string
+	}{
+		{`SELECT COUNT(*) FROM moz_places WHERE url = 'https://real.example.com/'`, `1`},
+		{`SELECT visit_count || ',' || typed || ',' || hidden || ',' || foreign_count FROM moz_places WHERE url = 'https://real.example.com/'`, `2,1,0,1`},
+		{`SELECT visit_count || ',' || typed || ',' || hidden || ',' || foreign_count FROM moz_places WHERE url = 'https://news.example.org/a'`, `1,0,0,1`},
+		{`SELECT visit_count || ',' || typed || ',' || hidden || ',' || foreign_count FROM moz_places WHERE url = 'https://ads.example.net/frame'`, `0,0,1,0`},
+		{`SELECT visit_count || ',' || hidden || ',' || foreign_count FROM moz_places WHERE url = 'https://docs.example.com/'`, `0,0,1`},
+		{`SELECT GROUP_CONCAT(visit_type) FROM (SELECT visit_type FROM moz_historyvisits JOIN moz_places p ON p.id = place_id WHERE p.url = 'https://news.example.org/a' ORDER BY visit_date)`, `1,4,9,8`},
+		{`SELECT rev_host || ',' || url_hash || ',' || LENGTH(guid) FROM moz_places WHERE url = 'https://news.example.org/a'`, fmt.Sprintf(`gro.elpmaxe.swen.,%d,12`, firefoxURLHash(`https://news.example.org/a`))},
+		{`SELECT GROUP_CONCAT(host) FROM (SELECT host FROM moz_origins ORDER BY id)`, `real.example.com,news.example.org,ads.example.net,docs.example.com`},
+		{`SELECT COUNT(*) FROM moz_places p JOIN moz_origins o ON o.id = p.origin_id WHERE p.url LIKE o.prefix || o.host || '/%'`, `4`},
+		{`SELECT b.title || '/' || f.title || '/' || w.title || '/' || r.guid FROM moz_bookmarks b JOIN moz_bookmarks f ON f.id = b.parent JOIN moz_bookmarks w ON w.id = f.parent JOIN moz_bookmarks r ON r.id = w.parent WHERE b.title = 'Manual'`, `Manual/Docs/Work/toolbar_____`},
+		{`SELECT GROUP_CONCAT(title || ':' || position) FROM (SELECT title, position FROM moz_bookmarks WHERE parent = (SELECT id FROM moz_bookmarks WHERE title = 'Work') ORDER BY position)`, `Docs:0,News:1`},
+		{`SELECT position FROM moz_bookmarks WHERE title = 'Work'`, `1`},
+		{`SELECT lastModified = ` + fmt.Sprint(prTimestamp(now.Add(time.Minute))) + ` FROM moz_bookmarks WHERE title = 'Work'`, `1`},
+	}
+
+	for _, test := range cases {
+		var got string
+		if err := profile.placesDatabase.Raw(test.query).Row().Scan(&got); err != nil {
+			t.Errorf(`%s: %s`, test.query, err)
+		} else if got != test.want {
+			t.Errorf(`%s gave '%s', want '%s'`, test.query, got, test.want)
+		}
+	}
+}
+
+func TestFirefoxPlaceKeys(t *testing.T) {
+	var cases = []struct {
+		url    string
+		host   string
+		prefix string
+		origin string
+	}{
+		{`https://www.Example.com/path?q=1`, `moc.elpmaxe.www.`, `https://`, `www.example.com`},
+		{`http://localhost:8080/`, `tsohlacol.`, `http://`, `localhost:8080`},
+		{`about:blank`, `.`, `about:`, ``},
+		{`file:///home/user/page.html`, `.`, `file:`, ``},
+	}
+
+	for _, test := range cases {
+		if host := firefoxRevHost(test.url); host != test.host {
+			t.Errorf(`firefoxRevHost(%s) is '%s', want '%s'`, test.url, host, test.host)
+		}
+		if prefix, origin := firefoxOriginKey(test.url); prefix != test.prefix || origin != test.origin {
+			t.Errorf(`firefoxOriginKey(%s) is '%s' '%s', want '%s' '%s'`, test.url, prefix, origin, test.prefix, test.origin)
+		}
+	}
+
+	if guid := firefoxGUID(newRandom(`Firefox`, `default`)); len(guid) != 12 {
+		t.Errorf(`firefoxGUID gave '%s', want twelve characters`, guid)
+	}
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+func openFirefoxProfile(tb testing.TB) *firefoxProfile {
+	tb.Helper()
+
+	var directory = tb.TempDir() + `/`
+	var profile = &firefoxProfile{name: `default`, dataPath: directory, random: newRandom(`Firefox`, `default`)}
+
+	var err error
+	if profile.placesDatabase, err = openDatabase(filepath.Join(directory, FIREFOX_PLACES_FILE)); err != nil {
+		tb.Fatalf(`openDatabase: %s`, err)
+	}
+	tb.Cleanup(func() {
+		profile.transactions.rollback()
+		profile.placesDatabase.Close()
+	})
+
+	for _, statement := range FIREFOX_TEST_PLACES_SCHEMA {
+		if result := profile.placesDatabase.Exec(statement); result.Error != nil {
+			tb.Fatalf(`%s: %s`, statement, result.Error)
+		}
+	}
+
+	return profile
+}
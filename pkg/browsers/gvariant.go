@@ -0,0 +1,496 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+var (
+	GVARIANT_MAX_DEPTH = 64 // Nesting beyond this is treated as corrupt rather than recursed into
+)
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+// gvariant is a boxed value of type v, values decode to bool, uint8, int16, uint16, int32, uint32, int64, uint64,
+// float64, string, []interface{} for arrays and tuples or a nested gvariant.
+type gvariant struct {
+	signature string
+	value     interface{}
+}
+
+// gvariantType is one node of a parsed type signature, items hold the element of an array or the members of a tuple.
+type gvariantType struct {
+	code  byte
+	items []*gvariantType
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+// readGVariant decodes data in the little endian serialisation of GLib's GVariant, as found in GVDB files.
+func readGVariant(signature string, data []byte) (interface{}, error) {
+	var kind, err = parseGVariantType(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	return kind.decode(data, 0)
+}
+
+// writeGVariant encodes a value in the little endian serialisation of GLib's GVariant.
+func writeGVariant(signature string, value interface{}) ([]byte, error) {
+	var kind, err = parseGVariantType(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	return kind.encode(value, 0)
+}
+
+func parseGVariantType(signature string) (*gvariantType, error) {
+	var kind, position, err = parseGVariantItem(signature, 0)
+	if err != nil {
+		return nil, err
+	} else if position != len(signature) {
+		return nil, fmt.Errorf(`trailing characters in type signature '%s'`, signature)
+	}
+
+	return kind, nil
+}
+
+func parseGVariantItem(signature string, position int) (*gvariantType, int, error) {
+	if position >= len(signature) {
+		return nil, position, fmt.Errorf(`truncated type signature '%s'`, signature)
+	}
+
+	switch code := signature[position]; code {
+	case 'b', 'y', 'n', 'q', 'i', 'u', 'x', 't', 'd', 's', 'o', 'g', 'v':
+		return &gvariantType{code: code}, position + 1, nil
+
+	case 'a':
+		var element, next, err = parseGVariantItem(signature, position+1)
+		if err != nil {
+			return nil, next, err
+		}
+		return &gvariantType{code: code, items: []*gvariantType{element}}, next, nil
+
+	case '(':
+		var tuple = &gvariantType{code: code}
+		for position = position + 1; position < len(signature) && signature[position] != ')'; {
+			var item, next, err = parseGVariantItem(signature, position)
+			if err != nil {
+				return nil, next, err
+			}
+
+			tuple.items = append(tuple.items, item)
+			position = next
+		}
+
+		if position >= len(signature) {
+			return nil, position, fmt.Errorf(`unterminated tuple in type signature '%s'`, signature)
+		}
+		return tuple, position + 1, nil
+
+	default:
+		return nil, position, fmt.Errorf(`unsupported type '%c' in signature '%s'`, code, signature)
+	}
+}
+
+func (t *gvariantType) String() string {
+	switch t.code {
+	case 'a':
+		return `a` + t.items[0].String()
+	case '(':
+		var signature = `(`
+		for _, item := range t.items {
+			signature = signature + item.String()
+		}
+		return signature + `)`
+	default:
+		return string(t.code)
+	}
+}
+
+func (t *gvariantType) alignment() int {
+	switch t.code {
+	case 'n', 'q':
+		return 2
+	case 'i', 'u':
+		return 4
+	case 'x', 't', 'd', 'v':
+		return 8
+	case 'a':
+		return t.items[0].alignment()
+	case '(':
+		var alignment = 1
+		for _, item := range t.items {
+			if item.alignment() > alignment {
+				alignment = item.alignment()
+			}
+		}
+		return alignment
+	default:
+		return 1
+	}
+}
+
+// fixedSize is the serialised size of a type whose instances all share one, zero when it varies.
+func (t *gvariantType) fixedSize() int {
+	switch t.code {
+	case 'b', 'y':
+		return 1
+	case 'n', 'q':
+		return 2
+	case 'i', 'u':
+		return 4
+	case 'x', 't', 'd':
+		return 8
+	case '(':
+		if len(t.items) == 0 {
+			return 1
+		}
+
+		var size = 0
+		for _, item := range t.items {
+			if item.fixedSize() == 0 {
+				return 0
+			}
+			size = gvariantAlign(size, item.alignment()) + item.fixedSize()
+		}
+		return gvariantAlign(size, t.alignment())
+	default:
+		return 0
+	}
+}
+
+func (t *gvariantType) decode(data []byte, depth int) (interface{}, error) {
+	if depth > GVARIANT_MAX_DEPTH {
+		return nil, fmt.Errorf(`gvariant nested deeper than %d`, GVARIANT_MAX_DEPTH)
+	} else if size := t.fixedSize(); size > 0 && len(data) != size {
+		return nil, fmt.Errorf(`gvariant of type %s is %d bytes, expected %d`, t, len(data), size)
+	}
+
+	switch t.code {
+	case 'b':
+		return data[0] != 0, nil
+	case 'y':
+		return data[0], nil
+	case 'n':
+		return int16(binary.LittleEndian.Uint16(data)), nil
+	case 'q':
+		return binary.LittleEndian.Uint16(data), nil
+	case 'i':
+		return int32(binary.LittleEndian.Uint32(data)), nil
+	case 'u':
+		return binary.LittleEndian.Uint32(data), nil
+	case 'x':
+		return int64(binary.LittleEndian.Uint64(data)), nil
+	case 't':
+		return binary.LittleEndian.Uint64(data), nil
+	case 'd':
+		return math.Float64frombits(binary.LittleEndian.Uint64(data)), nil
+
+	case 's', 'o', 'g':
+		if len(data) == 0 || data[len(data)-1] != 0 {
+			return nil, fmt.Errorf(`gvariant string is not nul terminated`)
+		}
+		return string(data[:len(data)-1]), nil
+
+	case 'v':
+		var separator = bytes.LastIndexByte(data, 0)
+		if separator < 0 {
+			return nil, fmt.Errorf(`gvariant variant carries no type signature`)
+		}
+
+		var kind, err = parseGVariantType(string(data[separator+1:]))
+		if err != nil {
+			return nil, err
+		}
+
+		var value, decodeErr = kind.decode(data[:separator], depth+1)
+		if decodeErr != nil {
+			return nil, decodeErr
+		}
+		return gvariant{signature: kind.String(), value: value}, nil
+
+	case 'a':
+		return t.decodeArray(data, depth)
+
+	case '(':
+		return t.decodeTuple(data, depth)
+	}
+
+	return nil, fmt.Errorf(`unsupported gvariant type %s`, t)
+}
+
+func (t *gvariantType) decodeArray(data []byte, depth int) (interface{}, error) {
+	var element = t.items[0]
+	var values = []interface{}{}
+
+	//-- Fixed size elements are packed back to back ----------
+	if size := element.fixedSize(); size > 0 {
+		if len(data)%size != 0 {
+			return nil, fmt.Errorf(`gvariant array of %d bytes is not a multiple of %d`, len(data), size)
+		}
+
+		for start := 0; start < len(data); start = start + size {
+			if value, err := element.decode(data[start:start+size], depth+1); err != nil {
+				return nil, err
+			} else {
+				values = append(values, value)
+			}
+		}
+
+		return values, nil
+	}
+
+	//-- Variable size elements are followed by a table of their end offsets ----------
+	if len(data) == 0 {
+		return values, nil
+	}
+
+	var width = gvariantOffsetWidth(len(data))
+	var table = int(gvariantOffset(data[len(data)-width:]))
+	if table > len(data) || (len(data)-table)%width != 0 {
+		return nil, fmt.Errorf(`gvariant array offset table is out of range`)
+	}
+
+	var start = 0
+	for position := table; position < len(data); position = position + width {
+		var end = int(gvariantOffset(data[position : position+width]))
+		start = gvariantAlign(start, element.alignment())
+		if start > end || end > table {
+			return nil, fmt.Errorf(`gvariant array element is out of range`)
+		}
+
+		if value, err := element.decode(data[start:end], depth+1); err != nil {
+			return nil, err
+		} else {
+			values = append(values, value)
+		}
+
+		start = end
+	}
+
+	return values, nil
+}
+
+func (t *gvariantType) decodeTuple(data []byte, depth int) (interface{}, error) {
+	var width = gvariantOffsetWidth(len(data))
+	var framed = 0
+	var end = 0
+	var values = []interface{}{}
+
+	for index, item := range t.items {
+		var start = gvariantAlign(end, item.alignment())
+
+		//-- Fixed items have known sizes, the last variable item runs up to the offsets, the rest are framed ----------
+		if size := item.fixedSize(); size > 0 {
+			end = start + size
+		} else if index == len(t.items)-1 {
+			end = len(data) - framed*width
+		} else {
+			framed++
+			if framed*width > len(data) {
+				return nil, fmt.Errorf(`gvariant tuple offset table is out of range`)
+			}
+			end = int(gvariantOffset(data[len(data)-framed*width : len(data)-(framed-1)*width]))
+		}
+
+		if start > end || end > len(data) {
+			return nil, fmt.Errorf(`gvariant tuple member is out of range`)
+		}
+
+		if value, err := item.decode(data[start:end], depth+1); err != nil {
+			return nil, err
+		} else {
+			values = append(values, value)
+		}
+	}
+
+	return values, nil
+}
+
+func (t *gvariantType) encode(value interface{}, depth int) ([]byte, error) {
+	if depth > GVARIANT_MAX_DEPTH {
+		return nil, fmt.Errorf(`gvariant nested deeper than %d`, GVARIANT_MAX_DEPTH)
+	}
+
+	var data = []byte{}
+	var mismatch = fmt.Errorf(`unable to encode %T as gvariant type %s`, value, t)
+
+	switch t.code {
+	case 'b':
+		if typed, ok := value.(bool); !ok {
+			return nil, mismatch
+		} else if typed {
+			return []byte{1}, nil
+		} else {
+			return []byte{0}, nil
+		}
+	case 'y':
+		if typed, ok := value.(uint8); ok {
+			return []byte{typed}, nil
+		}
+	case 'n':
+		if typed, ok := value.(int16); ok {
+			return binary.LittleEndian.AppendUint16(data, uint16(typed)), nil
+		}
+	case 'q':
+		if typed, ok := value.(uint16); ok {
+			return binary.LittleEndian.AppendUint16(data, typed), nil
+		}
+	case 'i':
+		if typed, ok := value.(int32); ok {
+			return binary.LittleEndian.AppendUint32(data, uint32(typed)), nil
+		}
+	case 'u':
+		if typed, ok := value.(uint32); ok {
+			return binary.LittleEndian.AppendUint32(data, typed), nil
+		}
+	case 'x':
+		if typed, ok := value.(int64); ok {
+			return binary.LittleEndian.AppendUint64(data, uint64(typed)), nil
+		}
+	case 't':
+		if typed, ok := value.(uint64); ok {
+			return binary.LittleEndian.AppendUint64(data, typed), nil
+		}
+	case 'd':
+		if typed, ok := value.(float64); ok {
+			return binary.LittleEndian.AppendUint64(data, math.Float64bits(typed)), nil
+		}
+
+	case 's', 'o', 'g':
+		if typed, ok := value.(string); ok {
+			return append([]byte(typed), 0), nil
+		}
+
+	case 'v':
+		if typed, ok := value.(gvariant); ok {
+			var kind, err = parseGVariantType(typed.signature)
+			if err != nil {
+				return nil, err
+			}
+
+			if data, err = kind.encode(typed.value, depth+1); err != nil {
+				return nil, err
+			}
+			return append(append(data, 0), typed.signature...), nil
+		}
+
+	case 'a':
+		if typed, ok := value.([]interface{}); ok {
+			return t.encodeArray(typed, depth)
+		}
+
+	case '(':
+		if typed, ok := value.([]interface{}); ok && len(typed) == len(t.items) {
+			return t.encodeTuple(typed, depth)
+		}
+	}
+
+	return nil, mismatch
+}
+
+func (t *gvariantType) encodeArray(values []interface{}, depth int) ([]byte, error) {
+	var element = t.items[0]
+	var data = []byte{}
+	var ends []int
+
+	for _, value := range values {
+		var encoded, err = element.encode(value, depth+1)
+		if err != nil {
+			return nil, err
+		}
+
+		data = append(data, make([]byte, gvariantAlign(len(data), element.alignment())-len(data))...)
+		data = append(data, encoded...)
+		ends = append(ends, len(data))
+	}
+
+	if element.fixedSize() > 0 {
+		return data, nil
+	}
+
+	return gvariantAppendOffsets(data, ends), nil
+}
+
+func (t *gvariantType) encodeTuple(values []interface{}, depth int) ([]byte, error) {
+	var data = []byte{}
+	var ends []int
+
+	for index, item := range t.items {
+		var encoded, err = item.encode(values[index], depth+1)
+		if err != nil {
+			return nil, err
+		}
+
+		data = append(data, make([]byte, gvariantAlign(len(data), item.alignment())-len(data))...)
+		data = append(data, encoded...)
+
+		if item.fixedSize() == 0 && index < len(t.items)-1 {
+			ends = append(ends, len(data))
+		}
+	}
+
+	//-- Fixed tuples are padded to their alignment, others end in their offsets, last member first ----------
+	if size := t.fixedSize(); size > 0 {
+		return append(data, make([]byte, size-len(data))...), nil
+	}
+
+	for left, right := 0, len(ends)-1; left < right; left, right = left+1, right-1 {
+		ends[left], ends[right] = ends[right], ends[left]
+	}
+
+	return gvariantAppendOffsets(data, ends), nil
+}
+
+// gvariantAppendOffsets adds framing offsets using the narrowest width able to address the finished container.
+func gvariantAppendOffsets(data []byte, offsets []int) []byte {
+	var width = 1
+	for _, candidate := range []int{1, 2, 4, 8} {
+		width = candidate
+		if candidate == 8 || uint64(len(data)+len(offsets)*candidate) <= uint64(1)<<(8*uint(candidate))-1 {
+			break
+		}
+	}
+
+	for _, offset := range offsets {
+		var encoded = make([]byte, 8)
+		binary.LittleEndian.PutUint64(encoded, uint64(offset))
+		data = append(data, encoded[:width]...)
+	}
+
+	return data
+}
+
+func gvariantOffsetWidth(size int) int {
+	switch {
+	case uint64(size) > math.MaxUint32:
+		return 8
+	case size > math.MaxUint16:
+		return 4
+	case size > math.MaxUint8:
+		return 2
+	case size > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func gvariantOffset(data []byte) uint64 {
+	var value uint64
+	for index := len(data) - 1; index >= 0; index-- {
+		value = value<<8 | uint64(data[index])
+	}
+
+	return value
+}
+
+func gvariantAlign(offset int, alignment int) int {
+	return (offset + alignment - 1) / alignment * alignment
+}
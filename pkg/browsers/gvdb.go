@@ -0,0 +1,305 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+var (
+	GVDB_SIGNATURE   = []byte(`GVariant`)
+	GVDB_HEADER_SIZE = 24
+	GVDB_ITEM_SIZE   = 24
+	GVDB_BLOOM_SHIFT = 5 // Written like gvdb-builder, which never fills the bloom filter but records its shift
+	GVDB_MAX_DEPTH   = 16
+)
+
+// Item types, gvdb-format.h
+const (
+	gvdbItemValue = 'v'
+	gvdbItemTable = 'H'
+	gvdbItemList  = 'L'
+
+	gvdbNoParent = 0xffffffff
+)
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+// gvdbTable is one hash table of a GVariant database keyed by full item name.
+type gvdbTable map[string]*gvdbItem
+
+// gvdbItem holds a boxed value, a nested table or neither, in which case the key alone is the data.
+type gvdbItem struct {
+	value *gvariant
+	table gvdbTable
+}
+
+type gvdbReader struct {
+	data []byte
+}
+
+type gvdbWriter struct {
+	data []byte
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+// readGVDB parses a little endian GVariant database, the format GLib uses for compiled resources, GSettings schemas and
+// GNOME Web's bookmarks.
+func readGVDB(input io.Reader) (gvdbTable, error) {
+	var data, err = io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+
+	//-- Validate header ----------
+	{
+		if len(data) < GVDB_HEADER_SIZE {
+			return nil, errors.New(`gvdb file is too small`)
+		} else if string(data[:len(GVDB_SIGNATURE)]) != string(GVDB_SIGNATURE) {
+			return nil, errors.New(`not a little endian gvdb file`)
+		} else if version := binary.LittleEndian.Uint32(data[8:]); version != 0 {
+			return nil, fmt.Errorf(`%w: gvdb version %d`, ErrSchemaUnsupported, version)
+		}
+	}
+
+	var reader = &gvdbReader{data: data}
+	return reader.table(binary.LittleEndian.Uint32(data[16:]), binary.LittleEndian.Uint32(data[20:]), 0)
+}
+
+func (r *gvdbReader) table(start uint32, end uint32, depth int) (gvdbTable, error) {
+	if depth > GVDB_MAX_DEPTH {
+		return nil, fmt.Errorf(`gvdb tables nested deeper than %d`, GVDB_MAX_DEPTH)
+	}
+
+	var block, err = r.slice(start, end)
+	if err != nil {
+		return nil, err
+	} else if len(block) < 8 {
+		return nil, errors.New(`gvdb hash table header is truncated`)
+	}
+
+	//-- Skip bloom filter and buckets to reach the items ----------
+	var items []byte
+	{
+		var bloomWords = uint64(binary.LittleEndian.Uint32(block) & (1<<27 - 1))
+		var buckets = uint64(binary.LittleEndian.Uint32(block[4:]))
+		var offset = 8 + 4*bloomWords + 4*buckets
+
+		if offset > uint64(len(block)) || (uint64(len(block))-offset)%uint64(GVDB_ITEM_SIZE) != 0 {
+			return nil, errors.New(`gvdb hash table items are misaligned`)
+		}
+
+		items = block[offset:]
+	}
+
+	//-- Resolve keys, which may be stored relative to a parent item ----------
+	var keys = make([]string, len(items)/GVDB_ITEM_SIZE)
+	var resolve func(index uint32, hops int) (string, error)
+	{
+		resolve = func(index uint32, hops int) (string, error) {
+			if int(index) >= len(keys) || hops > len(keys) {
+				return ``, errors.New(`gvdb item parent is out of range`)
+			} else if keys[index] != `` {
+				return keys[index], nil
+			}
+
+			var item = items[int(index)*GVDB_ITEM_SIZE:]
+			var keyStart = binary.LittleEndian.Uint32(item[8:])
+			var key, err = r.slice(keyStart, keyStart+uint32(binary.LittleEndian.Uint16(item[12:])))
+			if err != nil {
+				return ``, err
+			}
+
+			var prefix = ``
+			if parent := binary.LittleEndian.Uint32(item[4:]); parent != gvdbNoParent {
+				if prefix, err = resolve(parent, hops+1); err != nil {
+					return ``, err
+				}
+			}
+
+			keys[index] = prefix + string(key)
+			return keys[index], nil
+		}
+	}
+
+	//-- Decode items ----------
+	var table = gvdbTable{}
+	{
+		for index := range keys {
+			var key, err = resolve(uint32(index), 0)
+			if err != nil {
+				return nil, err
+			}
+
+			var item = items[index*GVDB_ITEM_SIZE:]
+			var entry = new(gvdbItem)
+			var valueStart, valueEnd = binary.LittleEndian.Uint32(item[16:]), binary.LittleEndian.Uint32(item[20:])
+
+			switch item[14] {
+			case gvdbItemValue:
+				var data, err = r.slice(valueStart, valueEnd)
+				if err != nil {
+					return nil, err
+				}
+
+				if value, err := readGVariant(`v`, data); err != nil {
+					return nil, fmt.Errorf(`gvdb item '%s': %s`, key, err)
+				} else {
+					var boxed = value.(gvariant)
+					entry.value = &boxed
+				}
+
+			case gvdbItemTable:
+				if entry.table, err = r.table(valueStart, valueEnd, depth+1); err != nil {
+					return nil, err
+				}
+
+			case gvdbItemList:
+				//NOTE: Lists only index the children of path-like keys, which are already items of this table
+			}
+
+			table[key] = entry
+		}
+	}
+
+	return table, nil
+}
+
+func (r *gvdbReader) slice(start uint32, end uint32) ([]byte, error) {
+	if start > end || uint64(end) > uint64(len(r.data)) {
+		return nil, errors.New(`gvdb pointer is out of range`)
+	}
+
+	return r.data[start:end], nil
+}
+
+// writeGVDB writes a little endian GVariant database holding the table as its root, laid out like gvdb-builder with
+// one bucket per item and no bloom filter.
+func writeGVDB(output io.Writer, root gvdbTable) error {
+	var writer = &gvdbWriter{data: make([]byte, GVDB_HEADER_SIZE)}
+
+	//-- Header ----------
+	{
+		copy(writer.data, GVDB_SIGNATURE)
+	}
+
+	//-- Tables, keys and values ----------
+	{
+		var start, end, err = writer.table(root, 0)
+		if err != nil {
+			return err
+		}
+
+		binary.LittleEndian.PutUint32(writer.data[16:], start)
+		binary.LittleEndian.PutUint32(writer.data[20:], end)
+	}
+
+	var _, err = output.Write(writer.data)
+	return err
+}
+
+func (w *gvdbWriter) table(table gvdbTable, depth int) (uint32, uint32, error) {
+	if depth > GVDB_MAX_DEPTH {
+		return 0, 0, fmt.Errorf(`gvdb tables nested deeper than %d`, GVDB_MAX_DEPTH)
+	}
+
+	//-- Order keys by bucket, readers scan from a bucket's first item to the next bucket's ----------
+	var keys []string
+	var buckets = uint32(len(table))
+	{
+		for key := range table {
+			keys = append(keys, key)
+		}
+
+		sort.Slice(keys, func(i, j int) bool {
+			var left, right = gvdbHash(keys[i]) % buckets, gvdbHash(keys[j]) % buckets
+			if left == right {
+				return keys[i] < keys[j]
+			}
+			return left < right
+		})
+	}
+
+	//-- Reserve header, buckets and items ----------
+	var start = w.allocate(4, 8+4*len(keys)+GVDB_ITEM_SIZE*len(keys))
+	var items = start + 8 + 4*len(keys)
+	{
+		binary.LittleEndian.PutUint32(w.data[start:], uint32(GVDB_BLOOM_SHIFT)<<27)
+		binary.LittleEndian.PutUint32(w.data[start+4:], buckets)
+
+		var next = 0
+		for bucket := uint32(0); bucket < buckets; bucket++ {
+			for next < len(keys) && gvdbHash(keys[next])%buckets < bucket {
+				next++
+			}
+			binary.LittleEndian.PutUint32(w.data[start+8+4*int(bucket):], uint32(next))
+		}
+	}
+
+	//-- Fill items, the buffer moves as keys and values are appended so offsets are used throughout ----------
+	{
+		for index, key := range keys {
+			var item = items + index*GVDB_ITEM_SIZE
+			var entry = table[key]
+
+			var keyStart = w.allocate(1, len(key))
+			copy(w.data[keyStart:], key)
+
+			var kind byte
+			var valueStart, valueEnd uint32
+
+			switch {
+			case entry != nil && entry.value != nil:
+				var data, err = writeGVariant(`v`, *entry.value)
+				if err != nil {
+					return 0, 0, fmt.Errorf(`gvdb item '%s': %s`, key, err)
+				}
+
+				var offset = w.allocate(8, len(data))
+				copy(w.data[offset:], data)
+
+				kind, valueStart, valueEnd = gvdbItemValue, uint32(offset), uint32(offset+len(data))
+
+			case entry != nil && entry.table != nil:
+				var childStart, childEnd, err = w.table(entry.table, depth+1)
+				if err != nil {
+					return 0, 0, err
+				}
+
+				kind, valueStart, valueEnd = gvdbItemTable, childStart, childEnd
+			}
+
+			binary.LittleEndian.PutUint32(w.data[item:], gvdbHash(key))
+			binary.LittleEndian.PutUint32(w.data[item+4:], gvdbNoParent)
+			binary.LittleEndian.PutUint32(w.data[item+8:], uint32(keyStart))
+			binary.LittleEndian.PutUint16(w.data[item+12:], uint16(len(key)))
+			w.data[item+14] = kind
+			binary.LittleEndian.PutUint32(w.data[item+16:], valueStart)
+			binary.LittleEndian.PutUint32(w.data[item+20:], valueEnd)
+		}
+	}
+
+	return uint32(start), uint32(items + GVDB_ITEM_SIZE*len(keys)), nil
+}
+
+// allocate zero pads the buffer to the alignment and reserves size bytes, returning their offset.
+func (w *gvdbWriter) allocate(alignment int, size int) int {
+	var start = (len(w.data) + alignment - 1) / alignment * alignment
+	w.data = append(w.data, make([]byte, start+size-len(w.data))...)
+
+	return start
+}
+
+// gvdbHash is the djb hash over signed chars that gvdb buckets keys with.
+func gvdbHash(key string) uint32 {
+	var hash = uint32(5381)
+	for index := 0; index < len(key); index++ {
+		hash = hash*33 + uint32(int32(int8(key[index])))
+	}
+
+	return hash
+}
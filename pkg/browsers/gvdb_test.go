@@ -0,0 +1,94 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+func TestGVDBRoundTrip(t *testing.T) {
+	var many = gvdbTable{}
+	for index := 0; index < 500; index++ {
+		many[fmt.Sprintf(`https://site%d.example.com/`, index)] = &gvdbItem{value: &gvariant{signature: `x`, value: int64(index)}}
+	}
+
+	var cases = []struct {
+		name  string
+		table gvdbTable
+	}{
+		{`empty`, gvdbTable{}},
+		{`value`, gvdbTable{`key`: {value: &gvariant{signature: `s`, value: `value`}}}},
+		{`bare keys`, gvdbTable{`Favorites`: {}, `Mobile`: {}, `Work ✓`: {}}},
+		{`many keys`, many},
+		{`bookmarks`, gvdbTable{
+			EPIPHANY_TAGS_TABLE: {table: gvdbTable{EPIPHANY_FAVORITES_TAG: {}, `Reading`: {}}},
+			EPIPHANY_BOOKMARKS_TABLE: {table: gvdbTable{
+				`https://example.com/`: {value: &gvariant{
+					signature: EPIPHANY_BOOKMARK_SIGNATURE,
+					value:     []interface{}{int64(1589912345123456), `Example`, `abcdefghijkl`, 0.0, false, []interface{}{`Reading`}},
+				}},
+				`https://example.org/`: {value: &gvariant{
+					signature: EPIPHANY_BOOKMARK_SIGNATURE,
+					value:     []interface{}{int64(1589912399000000), `Other`, `mnopqrstuvwx`, 1589912399.5, true, []interface{}{}},
+				}},
+			}},
+		}},
+		{`empty nested table`, gvdbTable{`tables`: {table: gvdbTable{}}}},
+		{`deeply nested`, gvdbTable{`a`: {table: gvdbTable{`b`: {table: gvdbTable{`c`: {value: &gvariant{signature: `b`, value: true}}}}}}}},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			var encoded bytes.Buffer
+			if err := writeGVDB(&encoded, test.table); err != nil {
+				t.Fatalf(`writeGVDB: %s`, err)
+			}
+
+			var decoded, err = readGVDB(bytes.NewReader(encoded.Bytes()))
+			if err != nil {
+				t.Fatalf(`readGVDB: %s`, err)
+			} else if !reflect.DeepEqual(decoded, test.table) {
+				t.Fatalf(`round trip gave %v, want %v`, decoded, test.table)
+			}
+
+			var again bytes.Buffer
+			if err := writeGVDB(&again, decoded); err != nil {
+				t.Fatalf(`writeGVDB again: %s`, err)
+			} else if !bytes.Equal(again.Bytes(), encoded.Bytes()) {
+				t.Fatalf(`rewriting the decoded table changed its bytes`)
+			}
+		})
+	}
+}
+
+func TestGVDBRejectsMalformed(t *testing.T) {
+	var valid bytes.Buffer
+	if err := writeGVDB(&valid, gvdbTable{`key`: {value: &gvariant{signature: `s`, value: `value`}}}); err != nil {
+		t.Fatalf(`writeGVDB: %s`, err)
+	}
+
+	var versioned = append([]byte{}, valid.Bytes()...)
+	versioned[8] = 1
+
+	var cases = []struct {
+		name string
+		data []byte
+	}{
+		{`empty`, nil},
+		{`big endian`, append([]byte(`tnairaVG`), valid.Bytes()[len(GVDB_SIGNATURE):]...)},
+		{`newer version`, versioned},
+		{`truncated`, valid.Bytes()[:valid.Len()-8]},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := readGVDB(bytes.NewReader(test.data)); err == nil {
+				t.Fatalf(`readGVDB accepted a malformed file`)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+var (
+	MOZLZ4_MAGIC = []byte("mozLz40\x00")
+
+	LZ4_MIN_MATCH     = 4
+	LZ4_LAST_LITERALS = 5  // The final bytes of a block are always literals
+	LZ4_MATCH_LIMIT   = 12 // No match may start closer than this to the end of a block
+	LZ4_MAX_OFFSET    = 0xffff
+	LZ4_HASH_BITS     = 16
+)
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+// readMozLz4 decodes Mozilla's LZ4 container, an eight byte magic and little endian decompressed size ahead of a single
+// raw LZ4 block, as used by sessionstore and search engine files.
+func readMozLz4(input io.Reader) ([]byte, error) {
+	var data, err = io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < len(MOZLZ4_MAGIC)+4 || string(data[:len(MOZLZ4_MAGIC)]) != string(MOZLZ4_MAGIC) {
+		return nil, errors.New(`not a mozLz4 file`)
+	}
+
+	var size = binary.LittleEndian.Uint32(data[len(MOZLZ4_MAGIC):])
+	return lz4DecompressBlock(data[len(MOZLZ4_MAGIC)+4:], int(size))
+}
+
+func writeMozLz4(output io.Writer, data []byte) error {
+	var header = make([]byte, len(MOZLZ4_MAGIC)+4)
+	copy(header, MOZLZ4_MAGIC)
+	binary.LittleEndian.PutUint32(header[len(MOZLZ4_MAGIC):], uint32(len(data)))
+
+	if _, err := output.Write(header); err != nil {
+		return err
+	} else if _, err := output.Write(lz4CompressBlock(data)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func lz4DecompressBlock(input []byte, size int) ([]byte, error) {
+	var output = make([]byte, 0, size)
+	var position = 0
+
+	for position < len(input) {
+		var token = input[position]
+		position++
+
+		//-- Copy literals ----------
+		var literals, next, err = lz4Length(input, position, int(token>>4))
+		if err != nil {
+			return nil, err
+		} else if next+literals > len(input) {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		output = append(output, input[next:next+literals]...)
+		position = next + literals
+
+		if position == len(input) {
+			break //NOTE: The last sequence carries literals only
+		} else if position+2 > len(input) {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		//-- Copy match ----------
+		var offset = int(binary.LittleEndian.Uint16(input[position:]))
+		position = position + 2
+
+		var length int
+		if length, position, err = lz4Length(input, position, int(token&0x0f)); err != nil {
+			return nil, err
+		}
+		length = length + LZ4_MIN_MATCH
+
+		if offset == 0 || offset > len(output) {
+			return nil, errors.New(`LZ4 match offset outside of decoded data`)
+		}
+
+		var start = len(output) - offset
+		for index := 0; index < length; index++ {
+			output = append(output, output[start+index]) //NOTE: Matches may overlap the bytes they produce
+		}
+	}
+
+	if len(output) != size {
+		return nil, errors.New(`LZ4 block does not match its declared size`)
+	}
+
+	return output, nil
+}
+
+// lz4Length reads a token nibble's length, a value of 15 continues through following bytes until one is below 255.
+func lz4Length(input []byte, position int, length int) (int, int, error) {
+	if length != 0x0f {
+		return length, position, nil
+	}
+
+	for {
+		if position >= len(input) {
+			return 0, position, io.ErrUnexpectedEOF
+		}
+
+		var value = input[position]
+		position++
+		length = length + int(value)
+
+		if value != 0xff {
+			return length, position, nil
+		}
+	}
+}
+
+// lz4CompressBlock is a greedy single pass compressor, hashing every four byte sequence to its last position.
+func lz4CompressBlock(input []byte) []byte {
+	var output = make([]byte, 0, len(input)/2+16)
+	var table = make([]int, 1<<uint(LZ4_HASH_BITS))
+	var anchor = 0
+
+	for position := 0; position+LZ4_MATCH_LIMIT < len(input); {
+		var sequence = binary.LittleEndian.Uint32(input[position:])
+		var slot = (sequence * 2654435761) >> uint(32-LZ4_HASH_BITS)
+		var candidate = table[slot] - 1
+		table[slot] = position + 1
+
+		if candidate < 0 || position-candidate > LZ4_MAX_OFFSET || binary.LittleEndian.Uint32(input[candidate:]) != sequence {
+			position++
+			continue
+		}
+
+		var length = LZ4_MIN_MATCH
+		for position+length < len(input)-LZ4_LAST_LITERALS && input[candidate+length] == input[position+length] {
+			length++
+		}
+
+		output = lz4Sequence(output, input[anchor:position], position-candidate, length)
+		position = position + length
+		anchor = position
+	}
+
+	return lz4Sequence(output, input[anchor:], 0, 0)
+}
+
+func lz4Sequence(output []byte, literals []byte, offset int, length int) []byte {
+	var token = byte(0)
+	var matched = length - LZ4_MIN_MATCH
+
+	if len(literals) >= 0x0f {
+		token = 0xf0
+	} else {
+		token = byte(len(literals) << 4)
+	}
+
+	if length > 0 {
+		if matched >= 0x0f {
+			token = token | 0x0f
+		} else {
+			token = token | byte(matched)
+		}
+	}
+
+	output = append(output, token)
+	output = lz4AppendLength(output, len(literals))
+	output = append(output, literals...)
+
+	if length > 0 {
+		output = append(output, byte(offset), byte(offset>>8))
+		output = lz4AppendLength(output, matched)
+	}
+
+	return output
+}
+
+func lz4AppendLength(output []byte, length int) []byte {
+	if length < 0x0f {
+		return output
+	}
+
+	for length = length - 0x0f; length >= 0xff; length = length - 0xff {
+		output = append(output, 0xff)
+	}
+
+	return append(output, byte(length))
+}
@@ -0,0 +1,80 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+// TestReadMozLz4 decodes a session store whose block was compressed by the reference lz4 tool at its highest level.
+func TestReadMozLz4(t *testing.T) {
+	var want, err = os.ReadFile(filepath.Join(`testdata`, `firefox`, `sessionstore.json`))
+	if err != nil {
+		t.Fatalf(`ReadFile: %s`, err)
+	}
+
+	var file, openErr = os.Open(filepath.Join(`testdata`, `firefox`, FIREFOX_SESSION_FILE))
+	if openErr != nil {
+		t.Fatalf(`Open: %s`, openErr)
+	}
+	defer file.Close()
+
+	if data, err := readMozLz4(file); err != nil {
+		t.Fatalf(`readMozLz4: %s`, err)
+	} else if !bytes.Equal(data, want) {
+		t.Fatalf(`decoded %d bytes differing from the %d expected`, len(data), len(want))
+	}
+
+	for name, data := range map[string][]byte{
+		`empty`:     nil,
+		`bad magic`: []byte("mozLz41\x00\x05\x00\x00\x00\x50hello"),
+		`truncated`: []byte("mozLz40\x00\x05\x00\x00\x00\x50hel"),
+	} {
+		if _, err := readMozLz4(bytes.NewReader(data)); err == nil {
+			t.Errorf(`%s: decoded without an error`, name)
+		}
+	}
+}
+
+// TestMozLz4RoundTrip compresses inputs that exercise literal runs, the trailing literal rule and the match offset limit,
+// the reference lz4 tool decodes the same blocks to the same bytes.
+func TestMozLz4RoundTrip(t *testing.T) {
+	var fixture, err = os.ReadFile(filepath.Join(`testdata`, `firefox`, `sessionstore.json`))
+	if err != nil {
+		t.Fatalf(`ReadFile: %s`, err)
+	}
+
+	var noise = make([]byte, 70000)
+	rand.New(rand.NewSource(1)).Read(noise)
+
+	var cases = map[string][]byte{
+		`empty`:          {},
+		`short`:          []byte(`{}`),
+		`match limit`:    []byte(strings.Repeat(`a`, LZ4_MATCH_LIMIT+1)),
+		`session`:        fixture,
+		`incompressible`: noise,
+		`beyond offset`:  append(append(append([]byte{}, noise[:100]...), noise[:LZ4_MAX_OFFSET+10]...), noise[:100]...),
+		`long run`:       bytes.Repeat(fixture, 40),
+	}
+
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			if err := writeMozLz4(&buffer, data); err != nil {
+				t.Fatalf(`writeMozLz4: %s`, err)
+			}
+
+			if decoded, err := readMozLz4(&buffer); err != nil {
+				t.Fatalf(`readMozLz4: %s`, err)
+			} else if !bytes.Equal(decoded, data) {
+				t.Fatalf(`round trip of %d bytes gave %d differing bytes`, len(data), len(decoded))
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+var (
+	NETSCAPE_HEADER = "<!DOCTYPE NETSCAPE-Bookmark-file-1>\n" +
+		"<!-- This is an automatically generated file.\n" +
+		"     It will be read and overwritten.\n" +
+		"     DO NOT EDIT! -->\n" +
+		"<META HTTP-EQUIV=\"Content-Type\" CONTENT=\"text/html; charset=UTF-8\">\n" +
+		"<TITLE>Bookmarks</TITLE>\n" +
+		"<H1>Bookmarks</H1>\n"
+
+	netscapeTokens     = regexp.MustCompile(`(?is)(<dl\b[^>]*>)|(</dl\s*>)|<h3\b([^>]*)>(.*?)</h3\s*>|<a\b([^>]*)>(.*?)</a\s*>`)
+	netscapeAttributes = regexp.MustCompile(`(?is)([a-z_\-]+)\s*=\s*"([^"]*)"`)
+)
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+type netscapeFolder struct {
+	name     string
+	included bool
+	created  time.Time
+	children []*netscapeNode
+}
+
+type netscapeNode struct {
+	folder   *netscapeFolder
+	bookmark *Bookmark
+}
+
+func (n *netscapeFolder) child(name string) *netscapeFolder {
+	for _, node := range n.children {
+		if node.folder != nil && node.folder.name == name {
+			return node.folder
+		}
+	}
+
+	var folder = &netscapeFolder{name: name, included: true}
+	n.children = append(n.children, &netscapeNode{folder: folder})
+
+	return folder
+}
+
+func (n *netscapeFolder) write(output *bufio.Writer, depth int) {
+	var indent = strings.Repeat(`    `, depth)
+
+	for _, node := range n.children {
+		if node.folder != nil {
+			fmt.Fprintf(output, "%s<DT><H3%s>%s</H3>\n", indent, netscapeDate(`ADD_DATE`, node.folder.created), html.EscapeString(node.folder.name))
+			fmt.Fprintf(output, "%s<DL><p>\n", indent)
+			node.folder.write(output, depth+1)
+			fmt.Fprintf(output, "%s</DL><p>\n", indent)
+		} else {
+			fmt.Fprintf(output, "%s<DT><A HREF=\"%s\"%s>%s</A>\n", indent, html.EscapeString(node.bookmark.URL), netscapeDate(`ADD_DATE`, node.bookmark.CreatedAt), html.EscapeString(node.bookmark.Name))
+		}
+	}
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+// ReadNetscapeBookmarks parses a Netscape `bookmarks.html` export, folder headings become the bookmark's Folder path
+// except for the toolbar folder which every browser names differently.
+func ReadNetscapeBookmarks(input io.Reader) ([]Bookmark, error) {
+	var document string
+	{
+		if contents, err := io.ReadAll(input); err != nil {
+			return nil, err
+		} else {
+			document = string(contents)
+		}
+	}
+
+	//-- Walk tokens ----------
+	var bookmarks []Bookmark
+	{
+		var stack []*netscapeFolder
+		var pending *netscapeFolder
+
+		for _, indexes := range netscapeTokens.FindAllStringSubmatchIndex(document, -1) {
+			var group = func(number int) string {
+				if indexes[number*2] < 0 {
+					return ``
+				}
+				return document[indexes[number*2]:indexes[number*2+1]]
+			}
+
+			switch {
+			case indexes[2] >= 0: // <DL> opens the pending folder, or the document root
+				if pending == nil {
+					pending = &netscapeFolder{}
+				}
+				stack = append(stack, pending)
+				pending = nil
+
+			case indexes[4] >= 0: // </DL>
+				if len(stack) > 0 {
+					stack = stack[:len(stack)-1]
+				}
+
+			case indexes[6] >= 0: // <H3>
+				var attributes = netscapeAttributeMap(group(3))
+				pending = &netscapeFolder{
+					name:     netscapeText(group(4)),
+					included: attributes[`personal_toolbar_folder`] != `true`,
+					created:  netscapeTime(attributes[`add_date`]),
+				}
+
+			default: // <A>
+				var attributes = netscapeAttributeMap(group(5))
+				if attributes[`href`] == `` {
+					continue
+				}
+
+				var bookmark = Bookmark{
+					Name:      netscapeText(group(6)),
+					URL:       attributes[`href`],
+					CreatedAt: netscapeTime(attributes[`add_date`]),
+				}
+
+				for _, folder := range stack {
+					if folder.included && folder.name != `` {
+						bookmark.Folder = append(bookmark.Folder, folder.name)
+					}
+				}
+
+				bookmarks = append(bookmarks, bookmark)
+			}
+		}
+	}
+
+	//-- Return ---------
+	return bookmarks, nil
+}
+
+// WriteNetscapeBookmarks renders bookmarks as a Netscape `bookmarks.html` document which every browser can import.
+func WriteNetscapeBookmarks(output io.Writer, bookmarks []Bookmark) error {
+	//-- Build folder tree ----------
+	var root = new(netscapeFolder)
+	{
+		for index := range bookmarks {
+			var bookmark = &bookmarks[index]
+
+			var folder = root
+			for _, name := range bookmark.Folder {
+				folder = folder.child(name)
+
+				if !bookmark.CreatedAt.IsZero() && (folder.created.IsZero() || bookmark.CreatedAt.Before(folder.created)) {
+					folder.created = bookmark.CreatedAt
+				}
+			}
+
+			folder.children = append(folder.children, &netscapeNode{bookmark: bookmark})
+		}
+	}
+
+	//-- Write document ----------
+	{
+		var writer = bufio.NewWriter(output)
+
+		writer.WriteString(NETSCAPE_HEADER)
+		writer.WriteString("<DL><p>\n")
+		root.write(writer, 1)
+		writer.WriteString("</DL><p>\n")
+
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+func netscapeAttributeMap(raw string) map[string]string {
+	var attributes = map[string]string{}
+
+	for _, match := range netscapeAttributes.FindAllStringSubmatch(raw, -1) {
+		attributes[strings.ToLower(match[1])] = html.UnescapeString(match[2])
+	}
+
+	return attributes
+}
+
+func netscapeText(raw string) string {
+	return strings.TrimSpace(html.UnescapeString(raw))
+}
+
+func netscapeTime(raw string) time.Time {
+	if seconds, err := strconv.ParseInt(raw, 10, 64); err != nil || seconds <= 0 {
+		return time.Time{}
+	} else {
+		return time.Unix(seconds, 0)
+	}
+}
+
+func netscapeDate(name string, value time.Time) string {
+	if value.IsZero() {
+		return ``
+	}
+
+	return fmt.Sprintf(` %s="%d"`, name, value.Unix())
+}
@@ -0,0 +1,88 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+func TestReadNetscapeBookmarks(t *testing.T) {
+	var cases = []struct {
+		name     string
+		document string
+		want     []Bookmark
+	}{
+		{`empty`, NETSCAPE_HEADER + "<DL><p>\n</DL><p>\n", nil},
+		{`nested folders under the toolbar`, NETSCAPE_HEADER + `<DL><p>
+    <DT><H3 ADD_DATE="1600000000" LAST_MODIFIED="1600000009" PERSONAL_TOOLBAR_FOLDER="true">Bookmarks bar</H3>
+    <DL><p>
+        <DT><A HREF="https://a.example/" ADD_DATE="1600000001" ICON="data:image/png;base64,AAAA">A</A>
+        <DT><H3 ADD_DATE="1600000002">Work</H3>
+        <DL><p>
+            <DT><H3>Docs</H3>
+            <DL><p>
+                <DT><A HREF="https://docs.example/">Manual</A>
+            </DL><p>
+        </DL><p>
+        <DT><A HREF="https://b.example/">B</A>
+    </DL><p>
+    <DT><H3>Other</H3>
+    <DL><p>
+        <DT><A HREF="https://c.example/" ADD_DATE="not a date">C</A>
+    </DL><p>
+</DL><p>
+`, []Bookmark{
+			{Name: `A`, URL: `https://a.example/`, CreatedAt: time.Unix(1600000001, 0)},
+			{Name: `Manual`, URL: `https://docs.example/`, Folder: []string{`Work`, `Docs`}},
+			{Name: `B`, URL: `https://b.example/`},
+			{Name: `C`, URL: `https://c.example/`, Folder: []string{`Other`}},
+		}},
+		{`entities`, `<dl><p><dt><h3>R&amp;D &#x2713;</h3><dl><p>
+<dt><a href="https://e.example/?a=1&amp;b=2" add_date="1600000003">Tom &amp; Jerry &lt;3 &#39;quoted&#39; &quot;x&quot;</a>
+</dl><p></dl><p>`, []Bookmark{
+			{Name: `Tom & Jerry <3 'quoted' "x"`, URL: `https://e.example/?a=1&b=2`, Folder: []string{`R&D ✓`}, CreatedAt: time.Unix(1600000003, 0)},
+		}},
+		{`anchors without a link`, `<DL><p><DT><A NAME="top">Top</A><DT><A HREF="">Empty</A><DT><A HREF="https://f.example/">F</A></DL><p>`, []Bookmark{
+			{Name: `F`, URL: `https://f.example/`},
+		}},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			var bookmarks, err = ReadNetscapeBookmarks(strings.NewReader(test.document))
+			if err != nil {
+				t.Fatalf(`ReadNetscapeBookmarks: %s`, err)
+			} else if !reflect.DeepEqual(bookmarks, test.want) {
+				t.Fatalf(`read %+v, want %+v`, bookmarks, test.want)
+			}
+		})
+	}
+}
+
+func TestNetscapeBookmarksRoundTrip(t *testing.T) {
+	var bookmarks = []Bookmark{
+		{Name: `Loose`, URL: `https://loose.example/`},
+		{Name: `Fish & Chips <menu>`, URL: `https://food.example/?q="chips"&size=2`, Folder: []string{`Food & Drink`}, CreatedAt: time.Unix(1600000100, 0)},
+		{Name: `Recipe`, URL: `https://food.example/recipe`, Folder: []string{`Food & Drink`, `Recipes`}, CreatedAt: time.Unix(1600000050, 0)},
+		{Name: `Café`, URL: `https://café.example/`, Folder: []string{`Travel`}},
+	}
+
+	var document bytes.Buffer
+	if err := WriteNetscapeBookmarks(&document, bookmarks); err != nil {
+		t.Fatalf(`WriteNetscapeBookmarks: %s`, err)
+	} else if !strings.HasPrefix(document.String(), NETSCAPE_HEADER) {
+		t.Fatalf(`document does not start with the Netscape header`)
+	}
+
+	var read, err = ReadNetscapeBookmarks(&document)
+	if err != nil {
+		t.Fatalf(`ReadNetscapeBookmarks: %s`, err)
+	} else if !reflect.DeepEqual(read, bookmarks) {
+		t.Fatalf(`round trip gave %+v, want %+v`, read, bookmarks)
+	}
+}
@@ -0,0 +1,206 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+var (
+	NSS_PASSWORD_CHECK = []byte(`password-check`)
+	NSS_KEY_ID         = []byte{0xf8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1} // CKA_ID of the logins key
+
+	oidPBES2           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidAES256CBC       = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidDESEDE3CBC      = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+	oidPBEWithSHA13DES = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 5, 1, 3}
+)
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+type nssAlgorithm struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+// nssEncrypted is the DER wrapper key4.db uses for both the password check and the stored key.
+type nssEncrypted struct {
+	Algorithm  nssAlgorithm
+	Ciphertext []byte
+}
+
+type nssPBES2Parameters struct {
+	KeyDerivation struct {
+		Algorithm  asn1.ObjectIdentifier
+		Parameters struct {
+			Salt       []byte
+			Iterations int
+			KeyLength  int
+			PRF        nssAlgorithm
+		}
+	}
+	Cipher struct {
+		Algorithm asn1.ObjectIdentifier
+		IV        []byte
+	}
+}
+
+type nssPBEParameters struct {
+	Salt       []byte
+	Iterations int
+}
+
+// nssLogin is the DER of an encryptedUsername or encryptedPassword value in logins.json.
+type nssLogin struct {
+	KeyID  []byte
+	Cipher struct {
+		Algorithm asn1.ObjectIdentifier
+		IV        []byte
+	}
+	Ciphertext []byte
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+// nssDecrypt opens a key4.db entry protected by the (empty) primary password, either modern PBES2 with AES-256 or the
+// PKCS#12 triple DES scheme written by Firefox 58 to 71.
+func nssDecrypt(globalSalt []byte, password []byte, data []byte) ([]byte, error) {
+	var entry nssEncrypted
+	if _, err := asn1.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+
+	var hashed = sha1.Sum(append(append([]byte{}, globalSalt...), password...))
+
+	switch {
+	case entry.Algorithm.Algorithm.Equal(oidPBES2):
+		var parameters nssPBES2Parameters
+		if _, err := asn1.Unmarshal(entry.Algorithm.Parameters.FullBytes, &parameters); err != nil {
+			return nil, err
+		}
+
+		var derivation = parameters.KeyDerivation.Parameters
+		var key, err = pbkdf2.Key(sha256.New, string(hashed[:]), derivation.Salt, derivation.Iterations, derivation.KeyLength)
+		if err != nil {
+			return nil, err
+		}
+
+		var iv = parameters.Cipher.IV
+		if len(iv) == 14 {
+			iv = append([]byte{0x04, 0x0e}, iv...) //NOTE: NSS stores the IV without its DER header, which still counts
+		}
+
+		return cbcDecrypt(aes.NewCipher, key, iv, entry.Ciphertext)
+
+	case entry.Algorithm.Algorithm.Equal(oidPBEWithSHA13DES):
+		var parameters nssPBEParameters
+		if _, err := asn1.Unmarshal(entry.Algorithm.Parameters.FullBytes, &parameters); err != nil {
+			return nil, err
+		}
+
+		var salt = parameters.Salt
+		var padded = append(append([]byte{}, salt...), make([]byte, 20)...)[:20]
+		var combined = sha1.Sum(append(hashed[:], salt...))
+
+		var sign = func(data ...[]byte) []byte {
+			var mac = hmac.New(sha1.New, combined[:])
+			for _, chunk := range data {
+				mac.Write(chunk)
+			}
+			return mac.Sum(nil)
+		}
+
+		var first = sign(padded, salt)
+		var second = sign(sign(padded), salt)
+		var derived = append(first, second...)
+
+		return cbcDecrypt(des.NewTripleDESCipher, derived[:24], derived[len(derived)-8:], entry.Ciphertext)
+	}
+
+	return nil, fmt.Errorf(`%w: key4.db algorithm %s`, ErrSchemaUnsupported, entry.Algorithm.Algorithm)
+}
+
+// nssEncryptLogin produces the base64 value logins.json stores, triple DES for 24 byte keys and AES-256 for 32. The IV
+// comes from nssRandomBytes, never from the profile's seeded source.
+func nssEncryptLogin(key []byte, value string) (string, error) {
+	var login = nssLogin{KeyID: NSS_KEY_ID}
+	var err error
+
+	if len(key) >= 32 {
+		login.Cipher.Algorithm = oidAES256CBC
+		if login.Cipher.IV, err = nssRandomBytes(aes.BlockSize); err == nil {
+			login.Ciphertext, err = cbcEncrypt(aes.NewCipher, key[:32], login.Cipher.IV, []byte(value))
+		}
+	} else {
+		login.Cipher.Algorithm = oidDESEDE3CBC
+		if login.Cipher.IV, err = nssRandomBytes(des.BlockSize); err == nil {
+			login.Ciphertext, err = cbcEncrypt(des.NewTripleDESCipher, key[:24], login.Cipher.IV, []byte(value))
+		}
+	}
+
+	if err != nil {
+		return ``, err
+	}
+
+	var data []byte
+	if data, err = asn1.Marshal(login); err != nil {
+		return ``, err
+	}
+
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+func cbcEncrypt(block func([]byte) (cipher.Block, error), key []byte, iv []byte, plaintext []byte) ([]byte, error) {
+	var engine, err = block(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var padding = engine.BlockSize() - len(plaintext)%engine.BlockSize()
+	var output = append(append([]byte{}, plaintext...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	cipher.NewCBCEncrypter(engine, iv).CryptBlocks(output, output)
+	return output, nil
+}
+
+func cbcDecrypt(block func([]byte) (cipher.Block, error), key []byte, iv []byte, ciphertext []byte) ([]byte, error) {
+	var engine, err = block(key)
+	if err != nil {
+		return nil, err
+	} else if len(ciphertext) == 0 || len(ciphertext)%engine.BlockSize() != 0 || len(iv) != engine.BlockSize() {
+		return nil, errors.New(`ciphertext is not a whole number of blocks`)
+	}
+
+	var output = make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(engine, iv).CryptBlocks(output, ciphertext)
+
+	var padding = int(output[len(output)-1])
+	if padding < 1 || padding > engine.BlockSize() || padding > len(output) {
+		return nil, errors.New(`invalid padding, wrong key or password`)
+	}
+
+	return output[:len(output)-padding], nil
+}
+
+// nssRandomBytes draws salts and IVs from the system's secure source, the seeded sources only ever choose visible data.
+func nssRandomBytes(size int) ([]byte, error) {
+	var output = make([]byte, size)
+	if _, err := rand.Read(output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
@@ -0,0 +1,137 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+// TestFirefoxLoginKey decrypts logins written by NSS's own SDR with the key4.db it created under an empty password.
+func TestFirefoxLoginKey(t *testing.T) {
+	var profile = &firefoxProfile{name: `default`, dataPath: t.TempDir() + `/`}
+	for _, name := range []string{FIREFOX_KEY_FILE, FIREFOX_LOGINS_FILE} {
+		if data, err := os.ReadFile(filepath.Join(`testdata`, `firefox`, name)); err != nil {
+			t.Fatalf(`ReadFile: %s`, err)
+		} else if err = os.WriteFile(profile.dataPath+name, data, 0o600); err != nil {
+			t.Fatalf(`WriteFile: %s`, err)
+		}
+	}
+
+	var key, err = profile.loginKey()
+	if err != nil {
+		t.Fatalf(`loginKey: %s`, err)
+	} else if len(key) != 24 {
+		t.Fatalf(`loginKey gave %d bytes, want the 24 of a triple DES key`, len(key))
+	}
+
+	if err = profile.loadLogins(); err != nil {
+		t.Fatalf(`loadLogins: %s`, err)
+	} else if len(profile.loginManifest.Logins) != 1 {
+		t.Fatalf(`loadLogins gave %d logins, want 1`, len(profile.loginManifest.Logins))
+	}
+
+	var login = profile.loginManifest.Logins[0]
+	for value, want := range map[string]string{
+		login.EncryptedUsername: `ada@example.com`,
+		login.EncryptedPassword: `correct horse battery staple ✓`,
+	} {
+		if got := decryptLogin(t, key, value); got != want {
+			t.Errorf(`decrypted '%s', want '%s'`, got, want)
+		}
+	}
+}
+
+// TestNSSEncryptLogin reads values back for both key sizes, triple DES for 24 bytes and AES-256 from 32 on.
+func TestNSSEncryptLogin(t *testing.T) {
+	for _, size := range []int{24, 32} {
+		var key = bytes.Repeat([]byte{byte(size)}, size)
+
+		for _, value := range []string{``, `ada`, `exactly sixteen!`, `correct horse battery staple ✓`} {
+			var encrypted, err = nssEncryptLogin(key, value)
+			if err != nil {
+				t.Fatalf(`nssEncryptLogin: %s`, err)
+			}
+
+			if got := decryptLogin(t, key, encrypted); got != value {
+				t.Errorf(`%d byte key: decrypted '%s', want '%s'`, size, got, value)
+			}
+		}
+	}
+}
+
+// TestChromeEncryptPassword checks the "v10" scheme against ciphertexts produced by openssl with Chrome's derived key.
+func TestChromeEncryptPassword(t *testing.T) {
+	if runtime.GOOS != `linux` {
+		t.Skipf(`chrome password encryption is only implemented on linux`)
+	}
+
+	for password, want := range map[string]string{
+		``:                               `2bbc717b6f460c3a2bd002dcb1e48a97`,
+		`hunter2`:                        `58186cf88abd515a1cd36afe2d4d93ca`,
+		`correct horse battery staple ✓`: `6c7a64a6e71e09d40d8d41e7787334b2af49c6de15c30cdd38fa0fbbaf07948b1af5bca0a45fb23e970ec1f4ed80691d`,
+	} {
+		var encrypted, err = chromeEncryptPassword(password)
+		if err != nil {
+			t.Fatalf(`chromeEncryptPassword: %s`, err)
+		} else if !bytes.HasPrefix(encrypted, []byte(`v10`)) {
+			t.Fatalf(`'%s' encrypted without the v10 prefix`, password)
+		}
+
+		if got := hex.EncodeToString(encrypted[3:]); got != want {
+			t.Errorf(`'%s' encrypted to %s, want %s`, password, got, want)
+		}
+
+		var key, _ = hex.DecodeString(`fd621fe5a2b402539dfa147ca9272778`) // pbkdf2(sha1, "peanuts", "saltysalt", 1, 16)
+		if plaintext, err := cbcDecrypt(aes.NewCipher, key, bytes.Repeat([]byte{' '}, aes.BlockSize), encrypted[3:]); err != nil {
+			t.Errorf(`'%s' does not decrypt: %s`, password, err)
+		} else if string(plaintext) != password {
+			t.Errorf(`'%s' decrypted to '%s'`, password, plaintext)
+		}
+	}
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+// decryptLogin reverses a logins.json value the way Firefox's SDR does, with the cipher named in the value itself.
+func decryptLogin(tb testing.TB, key []byte, value string) string {
+	tb.Helper()
+
+	var data, err = base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		tb.Fatalf(`DecodeString: %s`, err)
+	}
+
+	var login nssLogin
+	if _, err = asn1.Unmarshal(data, &login); err != nil {
+		tb.Fatalf(`Unmarshal: %s`, err)
+	} else if !bytes.Equal(login.KeyID, NSS_KEY_ID) {
+		tb.Fatalf(`login encrypted under key %x`, login.KeyID)
+	}
+
+	var block func([]byte) (cipher.Block, error)
+	switch {
+	case login.Cipher.Algorithm.Equal(oidDESEDE3CBC):
+		block, key = des.NewTripleDESCipher, key[:24]
+	case login.Cipher.Algorithm.Equal(oidAES256CBC):
+		block, key = aes.NewCipher, key[:32]
+	default:
+		tb.Fatalf(`login encrypted with %s`, login.Cipher.Algorithm)
+	}
+
+	var plaintext []byte
+	if plaintext, err = cbcDecrypt(block, key, login.Cipher.IV, login.Ciphertext); err != nil {
+		tb.Fatalf(`cbcDecrypt: %s`, err)
+	}
+
+	return string(plaintext)
+}
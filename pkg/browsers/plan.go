@@ -0,0 +1,476 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/jinzhu/gorm"
+	"github.com/mattn/go-sqlite3"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+// DRY_RUN opens every database as an in-memory copy and records file writes instead of making them, so a full run can
+// be planned without touching a profile. Set it before Open and read the result with Plans before Close.
+var DRY_RUN = false
+
+var dryRunOperations = []string{`insert`, `update`, `delete`}
+
+// planned collects the copies and file changes of the current dry run.
+var planned = &dryRun{databases: map[string]*dryRunDatabase{}, files: map[string]*FileChange{}}
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+// Plan lists the changes a dry run made to the copies of a single browser profile.
+type Plan struct {
+	Browser string `json:"browser"`
+	Profile string `json:"profile"`
+	Path    string `json:"path"`
+
+	Tables []TableChange `json:"tables"`
+	Files  []FileChange  `json:"files"`
+}
+
+// TableChange counts the rows a dry run inserted, updated and deleted in a table, File is relative to the profile.
+type TableChange struct {
+	File     string `json:"file"`
+	Table    string `json:"table"`
+	Deleted  int    `json:"deleted"`
+	Inserted int    `json:"inserted"`
+	Updated  int    `json:"updated"`
+}
+
+// FileChange is a file a dry run would have created, rewritten or removed, Bytes is the size it would have been written
+// with.
+type FileChange struct {
+	File   string `json:"file"`
+	Action string `json:"action"`
+	Bytes  int64  `json:"bytes"`
+}
+
+// dryRun holds the in-memory copy of each database opened during a dry run and the file changes recorded so far, both
+// keyed by their path on disk.
+type dryRun struct {
+	lock      sync.Mutex
+	databases map[string]*dryRunDatabase
+	files     map[string]*FileChange
+}
+
+// dryRunDatabase is a shared-cache in-memory copy, kept alive by an anchor connection so reopening the same path within
+// a run sees earlier changes the way the file would.
+type dryRunDatabase struct {
+	name    string
+	missing bool
+	tracked map[string]bool
+	handle  *sql.DB
+	anchor  *sql.Conn
+}
+
+// dryRunFile measures what would have been written to a file and records it when closed.
+type dryRunFile struct {
+	path  string
+	bytes int64
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+// Plans lists the changes made during a dry run to each profile's copies, in browser and profile order. Tables and
+// files are attributed to the profile whose directory holds them.
+func Plans(browsers []Browser) ([]Plan, error) {
+	var plans []Plan
+
+	planned.lock.Lock()
+	defer planned.lock.Unlock()
+
+	for _, browser := range browsers {
+		for _, profile := range browser.Profiles() {
+			var plan = Plan{Browser: profile.Browser(), Profile: profile.Name(), Path: profile.Path()}
+
+			//-- Count table changes ----------
+			for path, snapshot := range planned.databases {
+				if !strings.HasPrefix(path, profile.Path()) {
+					continue
+				}
+
+				var changes, err = snapshot.changes()
+				if err != nil {
+					return plans, profileError(profile, `plan`, fileError(path, err))
+				}
+
+				for _, change := range changes {
+					change.File = strings.TrimPrefix(path, profile.Path())
+					plan.Tables = append(plan.Tables, change)
+				}
+
+				if snapshot.missing && len(changes) > 0 {
+					plan.Files = append(plan.Files, FileChange{File: strings.TrimPrefix(path, profile.Path()), Action: `create`})
+				}
+			}
+
+			//-- List file changes ----------
+			for path, change := range planned.files {
+				if strings.HasPrefix(path, profile.Path()) {
+					var relative = *change
+					relative.File = strings.TrimPrefix(path, profile.Path())
+					plan.Files = append(plan.Files, relative)
+				}
+			}
+
+			sort.Slice(plan.Tables, func(i, j int) bool {
+				if plan.Tables[i].File != plan.Tables[j].File {
+					return plan.Tables[i].File < plan.Tables[j].File
+				}
+				return plan.Tables[i].Table < plan.Tables[j].Table
+			})
+			sort.Slice(plan.Files, func(i, j int) bool { return plan.Files[i].File < plan.Files[j].File })
+
+			plans = append(plans, plan)
+		}
+	}
+
+	return plans, nil
+}
+
+// WriteText renders the plan as aligned plain text tables.
+func (p Plan) WriteText(output io.Writer) error {
+	var writer = tabwriter.NewWriter(output, 0, 4, 2, ' ', 0)
+
+	//-- Summary ----------
+	{
+		fmt.Fprintf(writer, "%s / %s\t%s\n", p.Browser, p.Profile, p.Path)
+
+		if len(p.Tables) == 0 && len(p.Files) == 0 {
+			fmt.Fprintf(writer, "  No changes\n")
+		}
+	}
+
+	//-- Tables ----------
+	if len(p.Tables) > 0 {
+		fmt.Fprintf(writer, "\n  Table\tDeleted\tInserted\tUpdated\n")
+		for _, change := range p.Tables {
+			fmt.Fprintf(writer, "  %s/%s\t%d\t%d\t%d\n", change.File, change.Table, change.Deleted, change.Inserted, change.Updated)
+		}
+	}
+
+	//-- Files ----------
+	if len(p.Files) > 0 {
+		fmt.Fprintf(writer, "\n  File\tAction\tBytes\n")
+		for _, change := range p.Files {
+			fmt.Fprintf(writer, "  %s\t%s\t%d\n", change.File, change.Action, change.Bytes)
+		}
+	}
+
+	fmt.Fprintln(writer)
+
+	return writer.Flush()
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+// openDatabase connects to a SQLite file, or during a dry run to the in-memory copy of it.
+func openDatabase(path string) (*gorm.DB, error) {
+	if !DRY_RUN {
+		return gorm.Open(`sqlite3`, `file:`+path)
+	}
+
+	if name, err := planned.database(path); err != nil {
+		return nil, err
+	} else {
+		return gorm.Open(`sqlite3`, name)
+	}
+}
+
+// createFile opens a file for rewriting, during a dry run what is written is only measured.
+func createFile(path string, permissions os.FileMode) (io.WriteCloser, error) {
+	if DRY_RUN {
+		return &dryRunFile{path: path}, nil
+	}
+
+	return os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, permissions)
+}
+
+// removeFile deletes a file if it exists, during a dry run the removal is only recorded.
+func removeFile(path string) error {
+	if DRY_RUN {
+		if _, err := os.Stat(path); err == nil {
+			planned.file(path, `remove`, 0)
+		}
+		return nil
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// makeDirectory creates a directory and its parents, during a dry run it is left to the files planned inside it.
+func makeDirectory(path string) error {
+	if DRY_RUN {
+		return nil
+	}
+
+	return os.MkdirAll(path, 0700)
+}
+
+// database returns the data source name of the in-memory copy of a file, copying it the first time it is opened. A
+// missing file starts empty, as SQLite would create it.
+func (d *dryRun) database(path string) (string, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if snapshot, ok := d.databases[path]; ok {
+		return snapshot.name, nil
+	}
+
+	var snapshot = &dryRunDatabase{name: fmt.Sprintf(`file:dry-run-%d?mode=memory&cache=shared`, len(d.databases)), tracked: map[string]bool{}}
+
+	//-- Open and pin the copy ----------
+	{
+		if handle, err := sql.Open(`sqlite3`, snapshot.name); err != nil {
+			return ``, err
+		} else {
+			snapshot.handle = handle
+		}
+
+		if anchor, err := snapshot.handle.Conn(context.Background()); err != nil {
+			snapshot.handle.Close()
+			return ``, err
+		} else {
+			snapshot.anchor = anchor
+		}
+	}
+
+	//-- Copy the file's contents ----------
+	{
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			snapshot.missing = true
+		} else if err != nil {
+			snapshot.close()
+			return ``, err
+		} else if err := snapshot.restore(path); err != nil {
+			snapshot.close()
+			return ``, err
+		}
+	}
+
+	//-- Count changes to existing tables ----------
+	{
+		if err := snapshot.track(); err != nil {
+			snapshot.close()
+			return ``, err
+		}
+	}
+
+	d.databases[path] = snapshot
+
+	return snapshot.name, nil
+}
+
+func (d *dryRun) file(path string, action string, bytes int64) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if action != `remove` {
+		if _, err := os.Stat(path); err == nil {
+			action = `rewrite`
+		}
+	}
+
+	d.files[path] = &FileChange{File: path, Action: action, Bytes: bytes}
+}
+
+// release closes every copy and forgets the recorded changes.
+func (d *dryRun) release() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	var errs Errors
+	for _, snapshot := range d.databases {
+		errs = errs.add(snapshot.close())
+	}
+
+	d.databases = map[string]*dryRunDatabase{}
+	d.files = map[string]*FileChange{}
+
+	return errs.errorOrNil()
+}
+
+// restore copies a database file into the copy with SQLite's online backup, the file is opened read-only.
+func (d *dryRunDatabase) restore(path string) error {
+	var source, err = sql.Open(`sqlite3`, `file:`+path+`?mode=ro`)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	var connection *sql.Conn
+	if opened, err := source.Conn(context.Background()); err != nil {
+		return err
+	} else {
+		connection = opened
+		defer connection.Close()
+	}
+
+	return d.anchor.Raw(func(destination interface{}) error {
+		return connection.Raw(func(origin interface{}) error {
+			var backup, err = destination.(*sqlite3.SQLiteConn).Backup(`main`, origin.(*sqlite3.SQLiteConn), `main`)
+			if err != nil {
+				return err
+			}
+
+			if _, err := backup.Step(-1); err != nil {
+				backup.Finish()
+				return err
+			}
+
+			return backup.Finish()
+		})
+	})
+}
+
+// track adds a counter table and triggers counting every insert, update and delete on the tables the file already
+// holds. Tables created later are counted whole when planned.
+func (d *dryRunDatabase) track() error {
+	var ctx = context.Background()
+
+	if _, err := d.anchor.ExecContext(ctx, `CREATE TABLE dry_run_changes (name TEXT, operation TEXT, rows INTEGER, PRIMARY KEY (name, operation))`); err != nil {
+		return err
+	}
+
+	var tables []string
+	{
+		var rows, err = d.anchor.QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name != 'dry_run_changes' AND sql NOT LIKE 'CREATE VIRTUAL%'`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return err
+			}
+			tables = append(tables, name)
+		}
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	for _, table := range tables {
+		for _, operation := range dryRunOperations {
+			var trigger = `"` + strings.ReplaceAll(`dry_run_`+table+`_`+operation, `"`, `""`) + `"`
+			var literal = `'` + strings.ReplaceAll(table, `'`, `''`) + `'`
+
+			if _, err := d.anchor.ExecContext(ctx, `INSERT INTO dry_run_changes (name, operation, rows) VALUES (?, ?, 0)`, table, operation); err != nil {
+				return err
+			} else if _, err := d.anchor.ExecContext(ctx, fmt.Sprintf(`CREATE TRIGGER %s AFTER %s ON "%s" BEGIN UPDATE dry_run_changes SET rows = rows + 1 WHERE name = %s AND operation = '%s'; END`, trigger, strings.ToUpper(operation), strings.ReplaceAll(table, `"`, `""`), literal, operation)); err != nil {
+				return err
+			}
+		}
+
+		d.tracked[table] = true
+	}
+
+	return nil
+}
+
+// changes reads the counted changes of tracked tables and counts every row of a table created since the copy was made.
+func (d *dryRunDatabase) changes() ([]TableChange, error) {
+	var ctx = context.Background()
+	var indexed = map[string]*TableChange{}
+
+	//-- Tracked tables ----------
+	{
+		var rows, err = d.anchor.QueryContext(ctx, `SELECT name, operation, rows FROM dry_run_changes WHERE rows > 0`)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var name, operation string
+			var count int
+			if err := rows.Scan(&name, &operation, &count); err != nil {
+				return nil, err
+			}
+
+			var change, ok = indexed[name]
+			if !ok {
+				change = &TableChange{Table: name}
+				indexed[name] = change
+			}
+
+			switch operation {
+			case `insert`:
+				change.Inserted = count
+			case `update`:
+				change.Updated = count
+			case `delete`:
+				change.Deleted = count
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	//-- Tables created during the run ----------
+	{
+		var created []string
+		if rows, err := d.anchor.QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name != 'dry_run_changes'`); err != nil {
+			return nil, err
+		} else {
+			for rows.Next() {
+				var name string
+				if err := rows.Scan(&name); err != nil {
+					rows.Close()
+					return nil, err
+				} else if !d.tracked[name] {
+					created = append(created, name)
+				}
+			}
+			rows.Close()
+		}
+
+		for _, name := range created {
+			var count int
+			if err := d.anchor.QueryRowContext(ctx, `SELECT COUNT(*) FROM "`+strings.ReplaceAll(name, `"`, `""`)+`"`).Scan(&count); err != nil {
+				return nil, err
+			} else if count > 0 {
+				indexed[name] = &TableChange{Table: name, Inserted: count}
+			}
+		}
+	}
+
+	var changes []TableChange
+	for _, change := range indexed {
+		changes = append(changes, *change)
+	}
+
+	return changes, nil
+}
+
+func (d *dryRunDatabase) close() error {
+	d.anchor.Close()
+	return d.handle.Close()
+}
+
+func (f *dryRunFile) Write(data []byte) (int, error) {
+	f.bytes += int64(len(data))
+	return len(data), nil
+}
+
+func (f *dryRunFile) Close() error {
+	planned.file(f.path, `create`, f.bytes)
+	return nil
+}
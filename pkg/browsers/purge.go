@@ -0,0 +1,129 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+// DataType names a kind of item a selective purge can be limited to.
+type DataType string
+
+const (
+	DataHistory     DataType = `history`
+	DataDownloads   DataType = `downloads`
+	DataCredentials DataType = `credentials`
+	DataCookies     DataType = `cookies`
+	DataBookmarks   DataType = `bookmarks`
+)
+
+var dataTypes = []DataType{DataHistory, DataDownloads, DataCredentials, DataCookies, DataBookmarks}
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+// PurgeFilter selects the items a selective purge removes. An item matches when its host matches any of Domains, its
+// URL matches Pattern and its time falls within After and Before, criteria left empty match everything. Types limits
+// the purge to some kinds of item, empty purges all of them. Domains are path.Match globs, a malformed one matches
+// nothing.
+type PurgeFilter struct {
+	Domains []string       // Host globs, `*.example.com` matches subdomains but not `example.com` itself
+	Pattern *regexp.Regexp // Matched against the full URL
+	After   time.Time      // Inclusive, zero is unbounded
+	Before  time.Time      // Exclusive, zero is unbounded
+	Types   []DataType
+}
+
+// selectivePurger is implemented by browsers able to purge only the items matching a filter.
+type selectivePurger interface {
+	PurgeMatching(ctx context.Context, filter PurgeFilter) error
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+// PurgeMatching stages the removal of the items matching filter in every browser, along with the rows that depend on
+// them, leaving everything else in place. Browsers that can only be purged whole report ErrUnsupported and are left
+// untouched.
+func PurgeMatching(ctx context.Context, browsers []Browser, filter PurgeFilter) error {
+	return eachBrowser(ctx, browsers, func(browser Browser, ctx context.Context) error {
+		if purger, ok := browser.(selectivePurger); ok {
+			return purger.PurgeMatching(ctx, filter)
+		}
+
+		return &Error{Browser: browser.Metadata().Name, Op: `purge`, Err: ErrUnsupported}
+	})
+}
+
+func ParseDataType(name string) (DataType, error) {
+	for _, candidate := range dataTypes {
+		if strings.EqualFold(strings.TrimSpace(name), string(candidate)) {
+			return candidate, nil
+		}
+	}
+
+	return ``, fmt.Errorf(`unknown data type '%s'`, name)
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+func (f PurgeFilter) includes(kind DataType) bool {
+	if len(f.Types) == 0 {
+		return true
+	}
+
+	for _, candidate := range f.Types {
+		if candidate == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// timed reports whether the filter is bounded in time, an untimed filter removes matching items whole.
+func (f PurgeFilter) timed() bool {
+	return !f.After.IsZero() || !f.Before.IsZero()
+}
+
+func (f PurgeFilter) within(moment time.Time) bool {
+	if !f.After.IsZero() && moment.Before(f.After) {
+		return false
+	} else if !f.Before.IsZero() && !moment.Before(f.Before) {
+		return false
+	}
+
+	return true
+}
+
+// matchesURL checks a URL against the domain globs and pattern, a URL without a host never matches a domain.
+func (f PurgeFilter) matchesURL(raw string) bool {
+	if f.Pattern != nil && !f.Pattern.MatchString(raw) {
+		return false
+	} else if len(f.Domains) == 0 {
+		return true
+	}
+
+	var parsed, err = url.Parse(raw)
+	if err != nil {
+		return false
+	}
+
+	return f.matchesHost(parsed.Hostname())
+}
+
+func (f PurgeFilter) matchesHost(host string) bool {
+	if host == `` {
+		return false
+	}
+
+	for _, domain := range f.Domains {
+		if matched, _ := path.Match(strings.ToLower(domain), strings.ToLower(host)); matched {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,75 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"fmt"
+	"runtime"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+var registry []registration
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+// Factory creates an unopened Browser, or nil when the browser does not apply to this machine.
+type Factory func() Browser
+
+type registration struct {
+	name    string
+	factory Factory
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+// Register makes a browser available to Open under a unique name, browsers open in the order they were registered.
+// Like database/sql drivers, it is meant to be called from an init function and panics on a nil factory or a name
+// that is already taken.
+func Register(name string, factory Factory) {
+	if factory == nil {
+		panic(fmt.Sprintf(`browsers: Register factory for %s is nil`, name))
+	}
+
+	for _, registered := range registry {
+		if registered.name == name {
+			panic(fmt.Sprintf(`browsers: Register called twice for %s`, name))
+		}
+	}
+
+	registry = append(registry, registration{name: name, factory: factory})
+}
+
+// Registered lists the names of registered browsers in the order Open tries them.
+func Registered() []string {
+	var names []string
+	for _, registered := range registry {
+		names = append(names, registered.name)
+	}
+
+	return names
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+func init() {
+	Register(`chrome`, func() Browser { return new(chrome) })
+	Register(`firefox`, func() Browser { return new(firefox) })
+
+	Register(`epiphany`, func() Browser {
+		if runtime.GOOS != `linux` {
+			return nil
+		}
+		return new(epiphany)
+	})
+
+	Register(`falkon`, func() Browser {
+		if runtime.GOOS != `linux` {
+			return nil
+		}
+		return new(falkon)
+	})
+
+	Register(`safari`, func() Browser {
+		if SAFARI_DATA_PATH == `` && runtime.GOOS != `darwin` {
+			return nil //NOTE: Offline targets are only written when a path is given
+		}
+		return new(safari)
+	})
+}
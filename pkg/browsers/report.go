@@ -0,0 +1,197 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+var (
+	REPORT_TOP_DOMAINS = 10
+	REPORT_TIME_FORMAT = `2006-01-02 15:04:05`
+)
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+// Report is a read-only summary of a single browser profile.
+type Report struct {
+	Browser string `json:"browser"`
+	Profile string `json:"profile"`
+	Path    string `json:"path"`
+
+	URLs        int `json:"urls"`
+	Visits      int `json:"visits"`
+	Credentials int `json:"credentials"`
+	Bookmarks   int `json:"bookmarks"`
+
+	FirstVisit time.Time `json:"first_visit"`
+	LastVisit  time.Time `json:"last_visit"`
+
+	TopDomains []DomainCount `json:"top_domains"`
+	Hours      [24]int       `json:"visits_by_hour"`
+	Weekdays   [7]int        `json:"visits_by_weekday"`
+
+	BookmarkTree []*BookmarkNode `json:"bookmark_tree"`
+	OpenTabs     []SessionTab    `json:"open_tabs"`
+}
+
+type DomainCount struct {
+	Domain string `json:"domain"`
+	Visits int    `json:"visits"`
+}
+
+type BookmarkNode struct {
+	Name     string          `json:"name"`
+	URL      string          `json:"url,omitempty"`
+	Children []*BookmarkNode `json:"children,omitempty"`
+}
+
+// SessionTab is the selected entry of a tab left open in the last saved session.
+type SessionTab struct {
+	Title      string    `json:"title"`
+	URL        string    `json:"url"`
+	LastActive time.Time `json:"last_active"`
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+// WriteText renders the report as aligned plain text tables.
+func (r Report) WriteText(output io.Writer) error {
+	var writer = tabwriter.NewWriter(output, 0, 4, 2, ' ', 0)
+
+	//-- Summary ----------
+	{
+		fmt.Fprintf(writer, "%s / %s\t%s\n", r.Browser, r.Profile, r.Path)
+		fmt.Fprintf(writer, "  URLs\t%d\n", r.URLs)
+		fmt.Fprintf(writer, "  Visits\t%d\n", r.Visits)
+		fmt.Fprintf(writer, "  Credentials\t%d\n", r.Credentials)
+		fmt.Fprintf(writer, "  Bookmarks\t%d\n", r.Bookmarks)
+
+		if r.Visits > 0 {
+			fmt.Fprintf(writer, "  First visit\t%s\n", r.FirstVisit.Local().Format(REPORT_TIME_FORMAT))
+			fmt.Fprintf(writer, "  Last visit\t%s\n", r.LastVisit.Local().Format(REPORT_TIME_FORMAT))
+		}
+	}
+
+	//-- Top domains ----------
+	if len(r.TopDomains) > 0 {
+		fmt.Fprintf(writer, "\n  Top domains\tVisits\n")
+		for _, domain := range r.TopDomains {
+			fmt.Fprintf(writer, "  %s\t%d\n", domain.Domain, domain.Visits)
+		}
+	}
+
+	//-- Histograms ----------
+	if r.Visits > 0 {
+		fmt.Fprintf(writer, "\n  Hour\tVisits\n")
+		for hour, count := range r.Hours {
+			fmt.Fprintf(writer, "  %02d\t%d\n", hour, count)
+		}
+
+		fmt.Fprintf(writer, "\n  Weekday\tVisits\n")
+		for day, count := range r.Weekdays {
+			fmt.Fprintf(writer, "  %s\t%d\n", time.Weekday(day), count)
+		}
+	}
+
+	//-- Open tabs ----------
+	if len(r.OpenTabs) > 0 {
+		fmt.Fprintf(writer, "\n  Open tabs\tURL\n")
+		for _, tab := range r.OpenTabs {
+			fmt.Fprintf(writer, "  %s\t%s\n", tab.Title, tab.URL)
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	//-- Bookmark tree ----------
+	if len(r.BookmarkTree) > 0 {
+		fmt.Fprintf(output, "\n  Bookmark tree\n")
+		for _, node := range r.BookmarkTree {
+			node.writeText(output, 2)
+		}
+	}
+
+	//-- Return ---------
+	_, err := fmt.Fprintln(output)
+	return err
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+func (n *BookmarkNode) writeText(output io.Writer, depth int) {
+	var indent = strings.Repeat(`  `, depth)
+
+	if n.URL == `` {
+		fmt.Fprintf(output, "%s+ %s\n", indent, n.Name)
+		for _, child := range n.Children {
+			child.writeText(output, depth+1)
+		}
+	} else {
+		fmt.Fprintf(output, "%s- %s <%s>\n", indent, n.Name, n.URL)
+	}
+}
+
+// count is the number of bookmarks below the node, folders excluded.
+func (n *BookmarkNode) count() int {
+	var count = 0
+
+	for _, child := range n.Children {
+		if child.URL == `` {
+			count = count + child.count()
+		} else {
+			count = count + 1
+		}
+	}
+
+	return count
+}
+
+// addVisit folds a single visit into the date range and histograms.
+func (r *Report) addVisit(moment time.Time) {
+	if r.FirstVisit.IsZero() || moment.Before(r.FirstVisit) {
+		r.FirstVisit = moment
+	}
+	if moment.After(r.LastVisit) {
+		r.LastVisit = moment
+	}
+
+	var local = moment.Local()
+	r.Hours[local.Hour()]++
+	r.Weekdays[local.Weekday()]++
+	r.Visits++
+}
+
+// rankDomains keeps the busiest domains from a domain to visit count mapping.
+func (r *Report) rankDomains(counts map[string]int) {
+	r.TopDomains = []DomainCount{}
+
+	for domain, visits := range counts {
+		r.TopDomains = append(r.TopDomains, DomainCount{Domain: domain, Visits: visits})
+	}
+
+	sort.Slice(r.TopDomains, func(i, j int) bool {
+		if r.TopDomains[i].Visits == r.TopDomains[j].Visits {
+			return r.TopDomains[i].Domain < r.TopDomains[j].Domain
+		}
+		return r.TopDomains[i].Visits > r.TopDomains[j].Visits
+	})
+
+	if len(r.TopDomains) > REPORT_TOP_DOMAINS {
+		r.TopDomains = r.TopDomains[:REPORT_TOP_DOMAINS]
+	}
+}
+
+func reportDomain(raw string) string {
+	if parsed, err := url.Parse(raw); err != nil || parsed.Hostname() == `` {
+		return raw
+	} else {
+		return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), `www.`)
+	}
+}
@@ -0,0 +1,711 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+var (
+	SAFARI_DATA_PATH = `` // Library/Safari of the target user, the local one is used on macOS when left empty
+
+	SAFARI_HISTORY_FILE    = `History.db`
+	SAFARI_BOOKMARKS_FILE  = `Bookmarks.plist`
+	SAFARI_SESSION_FILES   = []string{`LastSession.plist`, `RecentlyClosedTabs.plist`}
+	SAFARI_HISTORY_VERSION = 20 // Schema version recorded in a created History.db
+
+	SAFARI_BOOKMARKS_BAR   = `BookmarksBar`
+	SAFARI_BOOKMARKS_MENU  = `BookmarksMenu`
+	SAFARI_READING_LIST    = `com.apple.ReadingList`
+	SAFARI_BOOKMARK_ROOTS  = []string{SAFARI_BOOKMARKS_BAR, SAFARI_BOOKMARKS_MENU}
+	SAFARI_BOOKMARK_TITLES = map[string]string{
+		SAFARI_BOOKMARKS_BAR:  `Favourites`,
+		SAFARI_BOOKMARKS_MENU: `Bookmarks Menu`,
+	}
+
+	SAFARI_DARWIN_DATA_PATH = fmt.Sprintf(`%s/Library/Safari/`, os.Getenv(`HOME`))
+
+	SAFARI_HISTORY_SCHEMA = []string{
+		`CREATE TABLE history_items (id INTEGER PRIMARY KEY AUTOINCREMENT,url TEXT NOT NULL UNIQUE,domain_expansion TEXT NULL,visit_count INTEGER NOT NULL,daily_visit_counts BLOB NOT NULL,weekly_visit_counts BLOB NULL,autocomplete_triggers BLOB NULL,should_recompute_derived_visit_counts INTEGER NOT NULL,visit_count_score INTEGER NOT NULL,status_code INTEGER NOT NULL DEFAULT 0)`,
+		`CREATE TABLE history_visits (id INTEGER PRIMARY KEY AUTOINCREMENT,history_item INTEGER NOT NULL REFERENCES history_items(id) ON DELETE CASCADE,visit_time REAL NOT NULL,title TEXT NULL,load_successful BOOLEAN NOT NULL DEFAULT 1,http_non_get BOOLEAN NOT NULL DEFAULT 0,synthesized BOOLEAN NOT NULL DEFAULT 0,redirect_source INTEGER NULL UNIQUE REFERENCES history_visits(id) ON DELETE CASCADE,redirect_destination INTEGER NULL UNIQUE REFERENCES history_visits(id) ON DELETE CASCADE,origin INTEGER NOT NULL DEFAULT 0,generation INTEGER NOT NULL DEFAULT 0,attributes INTEGER NOT NULL DEFAULT 0,score INTEGER NOT NULL DEFAULT 0)`,
+		`CREATE TABLE history_tombstones (id INTEGER PRIMARY KEY AUTOINCREMENT,start_time REAL NOT NULL,end_time REAL NOT NULL,url TEXT,generation INTEGER NOT NULL DEFAULT 0)`,
+		`CREATE TABLE history_client_versions (client_version INTEGER PRIMARY KEY,last_seen REAL NOT NULL)`,
+		`CREATE TABLE history_event_listeners (listener_name TEXT PRIMARY KEY NOT NULL UNIQUE,last_seen REAL NOT NULL)`,
+		`CREATE TABLE history_events (id INTEGER PRIMARY KEY AUTOINCREMENT,event_type TEXT NOT NULL,event_time REAL NOT NULL,pending_listeners TEXT NOT NULL,value BLOB)`,
+		`CREATE TABLE history_tags (id INTEGER PRIMARY KEY AUTOINCREMENT,type INTEGER NOT NULL,level INTEGER NOT NULL,identifier TEXT NOT NULL,title TEXT NOT NULL,modification_timestamp REAL NOT NULL,item_count INTEGER NOT NULL DEFAULT 0,UNIQUE(type, identifier))`,
+		`CREATE TABLE history_items_to_tags (history_item INTEGER NOT NULL REFERENCES history_items(id) ON DELETE CASCADE,tag_id INTEGER NOT NULL REFERENCES history_tags(id) ON DELETE CASCADE,timestamp REAL NOT NULL,UNIQUE(history_item, tag_id))`,
+		`CREATE TABLE metadata (key TEXT NOT NULL UNIQUE, value)`,
+		`CREATE INDEX history_items__domain_expansion ON history_items (domain_expansion)`,
+		`CREATE INDEX history_visits__last_visit ON history_visits (history_item, visit_time DESC, synthesized ASC)`,
+		`CREATE INDEX history_visits__origin ON history_visits (origin, generation)`,
+	}
+)
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+type safari struct {
+	dataPath string
+
+	historyDatabase *gorm.DB
+	historyMissing  bool // History.db is only created by a commit with history to write
+	transactions    transactions
+	staged          stagedFiles
+	random          random
+	purging         bool
+
+	historyIndex map[string]*safariHistoryItem
+	historyItems []*safariHistoryItem
+	bookmarks    map[string]interface{}
+}
+
+type safariHistoryItem struct {
+	//-- Primary Key ----------
+	ID uint `gorm:"column:id;primary_key"`
+
+	//-- User Variables ----------
+	URL             string  `gorm:"column:url"`
+	DomainExpansion *string `gorm:"column:domain_expansion"`
+	VisitCount      int     `gorm:"column:visit_count"`
+
+	//-- Relations ----------
+	Visits []*safariHistoryVisit `gorm:"foreignkey:HistoryItem"`
+
+	//-- System Variables ----------
+	DailyVisitCounts []byte `gorm:"column:daily_visit_counts"`
+	Recompute        int    `gorm:"column:should_recompute_derived_visit_counts"`
+	VisitCountScore  int    `gorm:"column:visit_count_score"`
+	StatusCode       int    `gorm:"column:status_code"`
+}
+
+func (safariHistoryItem) TableName() string {
+	return `history_items`
+}
+
+type safariHistoryVisit struct {
+	//-- Primary Key ----------
+	ID uint `gorm:"column:id;primary_key"`
+
+	//-- User Variables ----------
+	HistoryItem uint    `gorm:"column:history_item"`
+	VisitTime   float64 `gorm:"column:visit_time"`
+	Title       string  `gorm:"column:title"`
+
+	//-- System Variables ----------
+	LoadSuccessful      bool  `gorm:"column:load_successful"`
+	HTTPNonGet          bool  `gorm:"column:http_non_get"`
+	Synthesized         bool  `gorm:"column:synthesized"`
+	RedirectSource      *uint `gorm:"column:redirect_source"`
+	RedirectDestination *uint `gorm:"column:redirect_destination"`
+	Origin              int   `gorm:"column:origin"`
+	Generation          int   `gorm:"column:generation"`
+	Attributes          int   `gorm:"column:attributes"`
+	Score               int   `gorm:"column:score"`
+}
+
+func (safariHistoryVisit) TableName() string {
+	return `history_visits`
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+func (s *safari) Metadata() Metadata {
+	return Metadata{Name: `Safari`, Engine: `WebKit`, Profiles: []string{`Default`}}
+}
+
+// Profiles returns the browser itself, Safari keeps a single profile per user.
+func (s *safari) Profiles() []Profile {
+	if s.historyDatabase == nil && !s.historyMissing {
+		return nil //NOTE: Safari is its own single profile, usable once open has found History.db or its absence
+	}
+
+	return []Profile{s}
+}
+
+func (s *safari) Name() string {
+	return `Default`
+}
+
+func (s *safari) Path() string {
+	return s.dataPath
+}
+
+func (s *safari) Browser() string {
+	return `Safari`
+}
+
+func (s *safari) AddHistory(ctx context.Context, item History) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	//-- Find or create history item, urls are unique in History.db ----------
+	var entry *safariHistoryItem
+	{
+		if s.historyIndex == nil {
+			s.historyIndex = map[string]*safariHistoryItem{}
+		}
+		entry = s.historyIndex[item.URL]
+
+		if entry == nil {
+			entry = &safariHistoryItem{
+				URL:              item.URL,
+				DomainExpansion:  safariDomainExpansion(item.URL),
+				DailyVisitCounts: []byte{},
+				Recompute:        1, //NOTE: Safari rebuilds the daily and weekly counts and score from the visits
+			}
+
+			s.historyItems = append(s.historyItems, entry)
+			s.historyIndex[item.URL] = entry
+		}
+	}
+
+	//-- Add visits ----------
+	{
+		var pending = len(entry.Visits)
+		var visit = func(moment float64) *safariHistoryVisit {
+			return &safariHistoryVisit{VisitTime: moment, Title: item.Name, LoadSuccessful: true}
+		}
+
+		if len(item.Timeline) > 0 {
+			for _, recorded := range item.Timeline {
+				entry.Visits = append(entry.Visits, visit(coreDataTimestamp(recorded.Time)))
+			}
+		} else {
+			for i := 0; i < item.Visits; i++ {
+				var moment = time.Unix(s.random.unixTimestamp(item.VisitWindow), s.random.Int63n(int64(time.Second)))
+				entry.Visits = append(entry.Visits, visit(coreDataTimestamp(moment)))
+			}
+		}
+
+		entry.VisitCount += len(entry.Visits) - pending //NOTE: Loaded visits are not held, the stored count already has them
+		entry.VisitCountScore = entry.VisitCount * 100
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (s *safari) AddBookmark(ctx context.Context, item Bookmark) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	//-- Select root, foldered bookmarks are kept together on the favourites bar ----------
+	var parent map[string]interface{}
+	{
+		var root = SAFARI_BOOKMARKS_BAR
+		if len(item.Folder) == 0 {
+			root = SAFARI_BOOKMARK_ROOTS[s.random.Intn(len(SAFARI_BOOKMARK_ROOTS))]
+		}
+
+		if parent = safariBookmarkChild(s.bookmarks, root); parent == nil {
+			parent = safariBookmarkFolder(s.random, root)
+			s.bookmarks[`Children`] = append(safariBookmarkChildren(s.bookmarks), parent)
+		}
+	}
+
+	//-- Walk or create sub-folders ----------
+	{
+		for _, name := range item.Folder {
+			var folder = safariBookmarkChild(parent, name)
+			if folder == nil {
+				folder = safariBookmarkFolder(s.random, name)
+				parent[`Children`] = append(safariBookmarkChildren(parent), folder)
+			}
+
+			parent = folder
+		}
+	}
+
+	//-- Insert leaf ----------
+	{
+		parent[`Children`] = append(safariBookmarkChildren(parent), map[string]interface{}{
+			`WebBookmarkType`: `WebBookmarkTypeLeaf`,
+			`WebBookmarkUUID`: strings.ToUpper(s.random.guid()),
+			`URLString`:       item.URL,
+			`URIDictionary`:   map[string]interface{}{`title`: item.Name},
+		})
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (s *safari) AddCredential(ctx context.Context, item Credential) error {
+	//TODO: Passwords live in the login keychain, which cannot be written offline
+	return ErrUnsupported
+}
+
+func (s *safari) AddFormEntry(ctx context.Context, item FormEntry) error {
+	//TODO: Form values are encrypted with a key held in the keychain
+	return ErrUnsupported
+}
+
+func (s *safari) AddCookie(ctx context.Context, item Cookie) error {
+	//TODO: Cookies belong in Cookies.binarycookies under the user's container
+	return ErrUnsupported
+}
+
+func (s *safari) AddDownload(ctx context.Context, item Download) error {
+	//TODO: Downloads belong in Downloads.plist
+	return ErrUnsupported
+}
+
+func (s *safari) AddAddress(ctx context.Context, item Address) error {
+	//TODO: Addresses come from the user's Contacts card
+	return ErrUnsupported
+}
+
+func (s *safari) AddSearchEngine(ctx context.Context, item SearchEngine) error {
+	//TODO: Safari only offers its built in search engines
+	return ErrUnsupported
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+func (s *safari) Open(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return profileError(s, `open`, s.open())
+}
+
+func (s *safari) open() error {
+	s.random = newRandom(s.Browser(), s.Name())
+
+	//-- Determine data path, offline targets are given explicitly ----------
+	{
+		switch {
+		case SAFARI_DATA_PATH != ``:
+			s.dataPath = strings.TrimSuffix(SAFARI_DATA_PATH, `/`) + `/`
+		case runtime.GOOS == `darwin`:
+			s.dataPath = SAFARI_DARWIN_DATA_PATH
+		default:
+			return fmt.Errorf(`no data path configured: %w`, ErrNotFound)
+		}
+
+		if info, err := os.Stat(s.dataPath); err != nil {
+			return fileError(s.dataPath, err)
+		} else if !info.IsDir() {
+			return fileError(s.dataPath, errors.New(`not a directory`))
+		}
+	}
+
+	//-- Open history database, a missing or empty one is left to the first commit that writes history ----------
+	{
+		if info, err := os.Stat(s.dataPath + SAFARI_HISTORY_FILE); os.IsNotExist(err) || (err == nil && info.Size() == 0) {
+			s.historyMissing = true
+		} else if err != nil {
+			return fileError(s.dataPath+SAFARI_HISTORY_FILE, err)
+		} else if err := s.openHistory(); err != nil {
+			return err
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (s *safari) openHistory() error {
+	if orm, err := openDatabase(s.dataPath + SAFARI_HISTORY_FILE); err != nil {
+		return fileError(s.dataPath+SAFARI_HISTORY_FILE, err)
+	} else if err := orm.DB().Ping(); err != nil {
+		orm.Close()
+		return fileError(s.dataPath+SAFARI_HISTORY_FILE, err)
+	} else {
+		s.historyDatabase = orm
+	}
+
+	return nil
+}
+
+// createHistory lays out History.db within the commit's transaction, a failed commit leaves at most an empty file that
+// the next open treats as missing.
+func (s *safari) createHistory(tx *gorm.DB) error {
+	for _, statement := range SAFARI_HISTORY_SCHEMA {
+		if result := tx.Exec(statement); result.Error != nil {
+			return result.Error
+		}
+	}
+
+	if result := tx.Exec(`INSERT INTO metadata (key, value) VALUES ('version', ?)`, SAFARI_HISTORY_VERSION); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (s *safari) Load(ctx context.Context) error {
+	return eachProfile(ctx, `load`, s.Profiles(), func(int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		return profileError(s, `load`, s.load())
+	}).errorOrNil()
+}
+
+func (s *safari) load() error {
+	//-- Load history ----------
+	{
+		s.historyItems = []*safariHistoryItem{}
+
+		if s.historyDatabase != nil {
+			if result := s.historyDatabase.Find(&s.historyItems); result.Error != nil {
+				return fileError(s.dataPath+SAFARI_HISTORY_FILE, result.Error)
+			}
+		}
+
+		s.historyIndex = map[string]*safariHistoryItem{}
+		for _, item := range s.historyItems {
+			s.historyIndex[item.URL] = item
+
+			if item.DailyVisitCounts == nil {
+				item.DailyVisitCounts = []byte{} //NOTE: An empty blob scans as nil, saving it back would break NOT NULL
+			}
+		}
+	}
+
+	//-- Open/Parse bookmark property list ----------
+	{
+		s.bookmarks = safariDefaultBookmarks(s.random)
+
+		if file, err := os.Open(s.dataPath + SAFARI_BOOKMARKS_FILE); os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return fileError(s.dataPath+SAFARI_BOOKMARKS_FILE, err)
+		} else {
+			defer file.Close()
+
+			if value, err := readBinaryPlist(file); err != nil {
+				return fileError(s.dataPath+SAFARI_BOOKMARKS_FILE, err)
+			} else if root, ok := value.(map[string]interface{}); !ok {
+				return fileError(s.dataPath+SAFARI_BOOKMARKS_FILE, fmt.Errorf(`%w: unexpected root`, ErrSchemaUnsupported))
+			} else {
+				s.bookmarks = root
+			}
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (s *safari) Inspect() ([]Report, error) {
+	var reports, err = s.inspect()
+	return reports, profileError(s, `inspect`, err)
+}
+
+func (s *safari) inspect() ([]Report, error) {
+	var report = Report{
+		Browser: `Safari`,
+		Profile: `Default`,
+		Path:    s.dataPath,
+		URLs:    len(s.historyItems),
+	}
+
+	//-- Summarise loaded history ----------
+	{
+		var domains = map[string]int{}
+		for _, item := range s.historyItems {
+			domains[reportDomain(item.URL)] += item.VisitCount
+		}
+
+		report.rankDomains(domains)
+	}
+
+	//-- Summarise individual visits ----------
+	{
+		if s.historyDatabase != nil {
+			if err := s.inspectVisits(&report); err != nil {
+				return nil, fileError(s.dataPath+SAFARI_HISTORY_FILE, err)
+			}
+		}
+	}
+
+	//-- Summarise bookmark tree ----------
+	{
+		for _, root := range SAFARI_BOOKMARK_ROOTS {
+			if folder := safariBookmarkChild(s.bookmarks, root); folder != nil {
+				var node = safariBookmarkReport(folder)
+				node.Name = SAFARI_BOOKMARK_TITLES[root]
+
+				report.Bookmarks = report.Bookmarks + node.count()
+				report.BookmarkTree = append(report.BookmarkTree, node)
+			}
+		}
+	}
+
+	//-- Return ---------
+	return []Report{report}, nil
+}
+
+func (s *safari) inspectVisits(report *Report) error {
+	var rows, err = s.historyDatabase.Raw(`SELECT visit_time FROM history_visits`).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var timestamp float64
+		if err := rows.Scan(&timestamp); err != nil {
+			return err
+		}
+
+		report.addVisit(fromCoreDataTimestamp(timestamp))
+	}
+
+	return rows.Err()
+}
+
+func (s *safari) Close() error {
+	return profileError(s, `close`, s.close())
+}
+
+func (s *safari) close() error {
+	//-- Close history database ----------
+	{
+		if s.historyDatabase != nil {
+			if err := s.historyDatabase.Close(); err != nil {
+				return fileError(s.dataPath+SAFARI_HISTORY_FILE, err)
+			}
+		}
+	}
+
+	//-- Return ---------
+	return nil
+}
+
+func (s *safari) Purge(ctx context.Context) error {
+	return eachProfile(ctx, `purge`, s.Profiles(), func(int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		s.purge()
+		return nil
+	}).errorOrNil()
+}
+
+// purge drops every pending and loaded item, the files themselves are emptied at the start of the next commit.
+func (s *safari) purge() {
+	s.purging = true
+
+	s.historyItems = []*safariHistoryItem{}
+	s.historyIndex = map[string]*safariHistoryItem{}
+	s.bookmarks = safariDefaultBookmarks(s.random)
+}
+
+func (s *safari) Commit(ctx context.Context) error {
+	return eachProfile(ctx, `commit`, s.Profiles(), func(int) error {
+		if err := s.commit(ctx); err != nil {
+			s.transactions.rollback()
+			s.staged.rollback()
+			return profileError(s, `commit`, err)
+		}
+
+		return nil
+	}).errorOrNil()
+}
+
+// commit writes the staged purge and items to the history database in one transaction, the session and bookmark files
+// are staged beside their targets first and only moved into place once it holds.
+func (s *safari) commit(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	//-- Create missing history database once there is history to write ----------
+	{
+		if s.historyMissing && len(s.historyItems) > 0 {
+			if s.historyDatabase == nil {
+				if err := s.openHistory(); err != nil {
+					return err
+				}
+			}
+
+			if err := s.createHistory(s.transactions.begin(s.historyDatabase)); err != nil {
+				return fileError(s.dataPath+SAFARI_HISTORY_FILE, err)
+			}
+		}
+	}
+
+	//-- Purge history database ----------
+	{
+		if s.purging && s.historyDatabase != nil {
+			var tx = s.transactions.begin(s.historyDatabase)
+			for _, table := range []string{`history_items_to_tags`, `history_tags`, `history_visits`, `history_items`, `history_tombstones`, `history_events`} {
+				if !tx.HasTable(table) {
+					continue
+				} else if result := tx.Exec(`DELETE FROM ` + table); result.Error != nil {
+					return fileError(s.dataPath+SAFARI_HISTORY_FILE, result.Error)
+				}
+			}
+		}
+	}
+
+	//-- Commit pending history to database ----------
+	{
+		for _, item := range s.historyItems {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if result := s.transactions.begin(s.historyDatabase).Save(item); result.Error != nil {
+				return fileError(s.dataPath+SAFARI_HISTORY_FILE, result.Error)
+			}
+		}
+	}
+
+	//-- Purge last session and closed tabs ----------
+	{
+		if s.purging {
+			for _, name := range SAFARI_SESSION_FILES {
+				if err := s.staged.remove(s.dataPath + name); err != nil {
+					return fileError(s.dataPath+name, err)
+				}
+			}
+		}
+	}
+
+	//-- Stage pending bookmarks ----------
+	{
+		if err := s.writeBookmarks(); err != nil {
+			return fileError(s.dataPath+SAFARI_BOOKMARKS_FILE, err)
+		}
+	}
+
+	//-- Commit database transaction, the last point a cancellation leaves the profile untouched ----------
+	{
+		if err := ctx.Err(); err != nil {
+			return err
+		} else if err := s.transactions.commit(); err != nil {
+			return fileError(s.dataPath+SAFARI_HISTORY_FILE, err)
+		}
+	}
+
+	//-- Move staged files into place ----------
+	{
+		if err := s.staged.commit(); err != nil {
+			return err
+		}
+	}
+
+	s.purging = false
+	s.historyMissing = s.historyMissing && len(s.historyItems) == 0
+
+	//-- Return ---------
+	return nil
+}
+
+func (s *safari) writeBookmarks() error {
+	var file, err = s.staged.create(s.dataPath+SAFARI_BOOKMARKS_FILE, 0666)
+	if err != nil {
+		return fileError(s.dataPath+SAFARI_BOOKMARKS_FILE, err)
+	}
+
+	if err := writeBinaryPlist(file, s.bookmarks); err != nil {
+		file.Close()
+		return fileError(s.dataPath+SAFARI_BOOKMARKS_FILE, err)
+	}
+
+	return file.Close()
+}
+
+// safariDefaultBookmarks is the tree of a fresh profile: the history proxy, favourites bar, bookmarks menu and the
+// hidden reading list.
+func safariDefaultBookmarks(random random) map[string]interface{} {
+	var readingList = safariBookmarkFolder(random, SAFARI_READING_LIST)
+	readingList[`ShouldOmitFromUI`] = true
+
+	return map[string]interface{}{
+		`Title`:                  ``,
+		`WebBookmarkFileVersion`: 1,
+		`WebBookmarkType`:        `WebBookmarkTypeList`,
+		`WebBookmarkUUID`:        strings.ToUpper(random.guid()),
+		`Children`: []interface{}{
+			map[string]interface{}{
+				`Title`:                 `History`,
+				`WebBookmarkIdentifier`: `History`,
+				`WebBookmarkType`:       `WebBookmarkTypeProxy`,
+				`WebBookmarkUUID`:       strings.ToUpper(random.guid()),
+			},
+			safariBookmarkFolder(random, SAFARI_BOOKMARKS_BAR),
+			safariBookmarkFolder(random, SAFARI_BOOKMARKS_MENU),
+			readingList,
+		},
+	}
+}
+
+func safariBookmarkFolder(random random, title string) map[string]interface{} {
+	return map[string]interface{}{
+		`Title`:           title,
+		`WebBookmarkType`: `WebBookmarkTypeList`,
+		`WebBookmarkUUID`: strings.ToUpper(random.guid()),
+		`Children`:        []interface{}{},
+	}
+}
+
+func safariBookmarkChildren(folder map[string]interface{}) []interface{} {
+	var children, _ = folder[`Children`].([]interface{})
+	return children
+}
+
+// safariBookmarkChild finds a sub-folder by title.
+func safariBookmarkChild(folder map[string]interface{}, title string) map[string]interface{} {
+	for _, child := range safariBookmarkChildren(folder) {
+		if node, ok := child.(map[string]interface{}); ok && node[`WebBookmarkType`] == `WebBookmarkTypeList` && node[`Title`] == title {
+			return node
+		}
+	}
+
+	return nil
+}
+
+func safariBookmarkReport(bookmark map[string]interface{}) *BookmarkNode {
+	var node = new(BookmarkNode)
+
+	if bookmark[`WebBookmarkType`] == `WebBookmarkTypeLeaf` {
+		node.URL, _ = bookmark[`URLString`].(string)
+		if titles, ok := bookmark[`URIDictionary`].(map[string]interface{}); ok {
+			node.Name, _ = titles[`title`].(string)
+		}
+
+		return node
+	}
+
+	node.Name, _ = bookmark[`Title`].(string)
+	for _, child := range safariBookmarkChildren(bookmark) {
+		if typed, ok := child.(map[string]interface{}); ok && typed[`WebBookmarkType`] != `WebBookmarkTypeProxy` {
+			node.Children = append(node.Children, safariBookmarkReport(typed))
+		}
+	}
+
+	return node
+}
+
+// safariDomainExpansion is the host Safari's autocomplete matches against, without a leading www. or the top level
+// domain.
+func safariDomainExpansion(raw string) *string {
+	var parsed, err = url.Parse(raw)
+	if err != nil || parsed.Hostname() == `` {
+		return nil
+	}
+
+	var host = strings.TrimPrefix(strings.ToLower(parsed.Hostname()), `www.`)
+	if index := strings.LastIndexByte(host, '.'); index > 0 {
+		host = host[:index]
+	}
+
+	return &host
+}
@@ -0,0 +1,128 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+func TestSafariHistoryCommit(t *testing.T) {
+	var ctx = context.Background()
+	var recorded = time.Date(2020, 3, 1, 9, 15, 0, 0, time.UTC)
+
+	var previous = SAFARI_DATA_PATH
+	SAFARI_DATA_PATH = t.TempDir()
+	t.Cleanup(func() { SAFARI_DATA_PATH = previous })
+
+	//-- Inspecting or committing without history leaves History.db uncreated ----------
+	{
+		var s = openSafari(t)
+
+		if reports, err := s.Inspect(); err != nil {
+			t.Fatalf(`Inspect: %s`, err)
+		} else if len(reports) != 1 || reports[0].URLs != 0 {
+			t.Fatalf(`Inspect gave %+v, want one empty report`, reports)
+		} else if err := s.Commit(ctx); err != nil {
+			t.Fatalf(`Commit: %s`, err)
+		} else if err := s.Close(); err != nil {
+			t.Fatalf(`Close: %s`, err)
+		}
+
+		expectFile(t, SAFARI_DATA_PATH+`/`+SAFARI_HISTORY_FILE, ``)
+	}
+
+	//-- Create History.db and add to it ----------
+	{
+		var s = openSafari(t)
+
+		var items = []History{
+			{Name: `Example`, URL: `https://example.com/`, Visits: 3, VisitWindow: time.Hour},
+			{Name: `Recorded`, URL: `https://www.example.org/page`, Timeline: []Visit{{Time: recorded}}},
+		}
+		for _, item := range items {
+			if err := s.AddHistory(ctx, item); err != nil {
+				t.Fatalf(`AddHistory: %s`, err)
+			}
+		}
+
+		if err := s.Commit(ctx); err != nil {
+			t.Fatalf(`Commit: %s`, err)
+		} else if err := s.Close(); err != nil {
+			t.Fatalf(`Close: %s`, err)
+		}
+	}
+
+	if _, err := os.Stat(SAFARI_DATA_PATH + `/` + SAFARI_BOOKMARKS_FILE); err != nil {
+		t.Fatalf(`bookmarks were not written: %s`, err)
+	}
+
+	//-- Reload and add to an existing item ----------
+	{
+		var s = openSafari(t)
+		if len(s.historyItems) != 2 {
+			t.Fatalf(`loaded %d history items, want 2`, len(s.historyItems))
+		}
+
+		if err := s.AddHistory(ctx, History{Name: `Example`, URL: `https://example.com/`, Visits: 2, VisitWindow: time.Hour}); err != nil {
+			t.Fatalf(`AddHistory: %s`, err)
+		} else if err := s.Commit(ctx); err != nil {
+			t.Fatalf(`Commit: %s`, err)
+		}
+
+		var counts = map[string]int{}
+		var visits = map[string]int{}
+		{
+			var rows, err = s.historyDatabase.Raw(`SELECT url, visit_count, (SELECT COUNT(*) FROM history_visits WHERE history_item = history_items.id) FROM history_items`).Rows()
+			if err != nil {
+				t.Fatalf(`query: %s`, err)
+			}
+			defer rows.Close()
+
+			for rows.Next() {
+				var url string
+				var count, stored int
+				if err := rows.Scan(&url, &count, &stored); err != nil {
+					t.Fatalf(`scan: %s`, err)
+				}
+
+				counts[url] = count
+				visits[url] = stored
+			}
+		}
+
+		for url, want := range map[string]int{`https://example.com/`: 5, `https://www.example.org/page`: 1} {
+			if counts[url] != want {
+				t.Errorf(`%s has visit_count %d, want %d`, url, counts[url], want)
+			} else if visits[url] != want {
+				t.Errorf(`%s has %d visits, want %d`, url, visits[url], want)
+			}
+		}
+
+		var moment float64
+		if err := s.historyDatabase.Raw(`SELECT visit_time FROM history_visits JOIN history_items ON history_items.id = history_item WHERE url = ?`, `https://www.example.org/page`).Row().Scan(&moment); err != nil {
+			t.Fatalf(`query: %s`, err)
+		} else if !fromCoreDataTimestamp(moment).Equal(recorded) {
+			t.Errorf(`recorded visit stored at %s, want %s`, fromCoreDataTimestamp(moment), recorded)
+		}
+
+		if err := s.Close(); err != nil {
+			t.Fatalf(`Close: %s`, err)
+		}
+	}
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+func openSafari(t *testing.T) *safari {
+	var s = new(safari)
+	if err := s.Open(context.Background()); err != nil {
+		t.Fatalf(`Open: %s`, err)
+	} else if err := s.Load(context.Background()); err != nil {
+		t.Fatalf(`Load: %s`, err)
+	}
+
+	return s
+}
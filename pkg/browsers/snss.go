@@ -0,0 +1,227 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf16"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+var (
+	SNSS_SIGNATURE = []byte(`SNSS`)
+	SNSS_VERSION   = int32(1)
+)
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+// snssCommand is a single record of Chromium's session command file, a 16 bit size covering the id byte and payload.
+type snssCommand struct {
+	id      uint8
+	payload []byte
+}
+
+// pickle builds a base::Pickle, a uint32 payload size header followed by fields aligned to four bytes.
+type pickle struct {
+	buffer []byte
+}
+
+type pickleReader struct {
+	buffer []byte
+	offset int
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+func readSNSS(input io.Reader) (int32, []snssCommand, error) {
+	var reader = bufio.NewReader(input)
+
+	//-- Header ----------
+	var version int32
+	{
+		var signature = make([]byte, len(SNSS_SIGNATURE))
+		if _, err := io.ReadFull(reader, signature); err != nil {
+			return 0, nil, err
+		} else if string(signature) != string(SNSS_SIGNATURE) {
+			return 0, nil, errors.New(`not an SNSS command file`)
+		} else if err := binary.Read(reader, binary.LittleEndian, &version); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	//-- Commands ----------
+	var commands []snssCommand
+	{
+		for {
+			var size uint16
+			if err := binary.Read(reader, binary.LittleEndian, &size); err == io.EOF {
+				break
+			} else if err != nil {
+				return version, commands, err
+			} else if size < 1 {
+				return version, commands, errors.New(`SNSS command without an id`)
+			}
+
+			var record = make([]byte, size)
+			if _, err := io.ReadFull(reader, record); err != nil {
+				return version, commands, err //NOTE: Chrome truncates a half written trailing command the same way
+			}
+
+			commands = append(commands, snssCommand{id: record[0], payload: record[1:]})
+		}
+	}
+
+	//-- Return ---------
+	return version, commands, nil
+}
+
+func writeSNSS(output io.Writer, commands []snssCommand) error {
+	var writer = bufio.NewWriter(output)
+
+	writer.Write(SNSS_SIGNATURE)
+	binary.Write(writer, binary.LittleEndian, SNSS_VERSION)
+
+	for _, command := range commands {
+		if len(command.payload)+1 > 0xffff {
+			return fmt.Errorf(`SNSS command %d payload of %d bytes is too large`, command.id, len(command.payload))
+		}
+
+		binary.Write(writer, binary.LittleEndian, uint16(len(command.payload)+1))
+		writer.WriteByte(command.id)
+		writer.Write(command.payload)
+	}
+
+	return writer.Flush()
+}
+
+// snssStruct packs fixed size payloads, the commands Chromium writes straight from a C struct of int32 fields.
+func snssStruct(values ...int32) []byte {
+	var output = make([]byte, len(values)*4)
+
+	for index, value := range values {
+		binary.LittleEndian.PutUint32(output[index*4:], uint32(value))
+	}
+
+	return output
+}
+
+func (p *pickle) align() {
+	for len(p.buffer)%4 != 0 {
+		p.buffer = append(p.buffer, 0)
+	}
+}
+
+func (p *pickle) writeInt32(value int32) *pickle {
+	p.buffer = append(p.buffer, snssStruct(value)...)
+	return p
+}
+
+func (p *pickle) writeInt64(value int64) *pickle {
+	var bytes = make([]byte, 8)
+	binary.LittleEndian.PutUint64(bytes, uint64(value))
+
+	p.buffer = append(p.buffer, bytes...)
+	return p
+}
+
+func (p *pickle) writeBool(value bool) *pickle {
+	if value {
+		return p.writeInt32(1)
+	}
+	return p.writeInt32(0)
+}
+
+func (p *pickle) writeString(value string) *pickle {
+	p.writeInt32(int32(len(value)))
+	p.buffer = append(p.buffer, value...)
+	p.align()
+	return p
+}
+
+func (p *pickle) writeString16(value string) *pickle {
+	var units = utf16.Encode([]rune(value))
+
+	p.writeInt32(int32(len(units)))
+	p.buffer = append(p.buffer, utf16Bytes(value)...)
+	p.align()
+	return p
+}
+
+func (p *pickle) bytes() []byte {
+	var output = make([]byte, 4, 4+len(p.buffer))
+	binary.LittleEndian.PutUint32(output, uint32(len(p.buffer)))
+
+	return append(output, p.buffer...)
+}
+
+func newPickleReader(data []byte) (*pickleReader, error) {
+	if len(data) < 4 {
+		return nil, errors.New(`pickle too short for its header`)
+	}
+
+	var size = int(binary.LittleEndian.Uint32(data))
+	if size > len(data)-4 {
+		return nil, errors.New(`pickle payload size exceeds its data`)
+	}
+
+	return &pickleReader{buffer: data[4 : 4+size]}, nil
+}
+
+func (p *pickleReader) take(size int) ([]byte, error) {
+	var aligned = (size + 3) &^ 3
+	if size < 0 || p.offset+size > len(p.buffer) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	var value = p.buffer[p.offset : p.offset+size]
+	p.offset = p.offset + aligned
+	if p.offset > len(p.buffer) {
+		p.offset = len(p.buffer)
+	}
+
+	return value, nil
+}
+
+func (p *pickleReader) readInt32() (int32, error) {
+	if value, err := p.take(4); err != nil {
+		return 0, err
+	} else {
+		return int32(binary.LittleEndian.Uint32(value)), nil
+	}
+}
+
+func (p *pickleReader) readInt64() (int64, error) {
+	if value, err := p.take(8); err != nil {
+		return 0, err
+	} else {
+		return int64(binary.LittleEndian.Uint64(value)), nil
+	}
+}
+
+func (p *pickleReader) readString() (string, error) {
+	if length, err := p.readInt32(); err != nil {
+		return ``, err
+	} else if value, err := p.take(int(length)); err != nil {
+		return ``, err
+	} else {
+		return string(value), nil
+	}
+}
+
+func (p *pickleReader) readString16() (string, error) {
+	if length, err := p.readInt32(); err != nil {
+		return ``, err
+	} else if value, err := p.take(int(length) * 2); err != nil {
+		return ``, err
+	} else {
+		var units = make([]uint16, length)
+		for index := range units {
+			units[index] = binary.LittleEndian.Uint16(value[index*2:])
+		}
+		return string(utf16.Decode(units)), nil
+	}
+}
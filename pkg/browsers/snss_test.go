@@ -0,0 +1,147 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+	"time"
+)
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+// TestPickleLayout checks base::Pickle's layout, a payload size header and every field padded to four bytes.
+func TestPickleLayout(t *testing.T) {
+	var data = new(pickle).writeInt32(7).writeString(`abc`).writeString16(`é`).writeBool(true).writeInt64(-1).bytes()
+	var want = `20000000` + `07000000` + `03000000616263` + `00` + `01000000e900` + `0000` + `01000000` + `ffffffffffffffff`
+
+	if got := hex.EncodeToString(data); got != want {
+		t.Fatalf(`pickle is %s, want %s`, got, want)
+	}
+
+	var reader, err = newPickleReader(data)
+	if err != nil {
+		t.Fatalf(`newPickleReader: %s`, err)
+	}
+
+	if value, err := reader.readInt32(); err != nil || value != 7 {
+		t.Errorf(`readInt32 gave %d, %v`, value, err)
+	}
+	if value, err := reader.readString(); err != nil || value != `abc` {
+		t.Errorf(`readString gave '%s', %v`, value, err)
+	}
+	if value, err := reader.readString16(); err != nil || value != `é` {
+		t.Errorf(`readString16 gave '%s', %v`, value, err)
+	}
+	if value, err := reader.readInt32(); err != nil || value != 1 {
+		t.Errorf(`readInt32 gave %d for a bool, %v`, value, err)
+	}
+	if value, err := reader.readInt64(); err != nil || value != -1 {
+		t.Errorf(`readInt64 gave %d, %v`, value, err)
+	}
+	if _, err := reader.readInt32(); err == nil {
+		t.Errorf(`read past the end of the payload`)
+	}
+
+	if _, err := newPickleReader(data[:len(data)-1]); err == nil {
+		t.Errorf(`accepted a pickle shorter than its header says`)
+	}
+}
+
+// TestSNSSRoundTrip writes a session's commands and reads them back, navigations decoding to the entries they were
+// built from.
+func TestSNSSRoundTrip(t *testing.T) {
+	var stamp = time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	var navigations = []chromeSessionNavigation{
+		{URL: `https://example.com/a`, Title: `Café 🚀`, Timestamp: webKitTimestamp(stamp), Transition: int32(TransitionTyped)},
+		{URL: `https://example.com/b?q=1`, Title: ``, Timestamp: webKitTimestamp(stamp.Add(time.Minute)), Transition: int32(TransitionLink)},
+	}
+	var tabs = []*chromeSessionTab{{id: 2, navigations: navigations}, {id: 3, navigations: navigations[1:]}}
+
+	for name, commands := range map[string][]snssCommand{
+		`session`:     chromeSessionCommands(newRandom(`Chrome`, `Default`), tabs),
+		`tab restore`: chromeTabRestoreCommands(tabs),
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			if err := writeSNSS(&buffer, commands); err != nil {
+				t.Fatalf(`writeSNSS: %s`, err)
+			} else if !bytes.HasPrefix(buffer.Bytes(), []byte("SNSS\x01\x00\x00\x00")) {
+				t.Fatalf(`file starts % x`, buffer.Bytes()[:8])
+			}
+
+			var version, read, err = readSNSS(&buffer)
+			if err != nil {
+				t.Fatalf(`readSNSS: %s`, err)
+			} else if version != SNSS_VERSION || !reflect.DeepEqual(read, commands) {
+				t.Fatalf(`read version %d and %d commands, want %d and %d`, version, len(read), SNSS_VERSION, len(commands))
+			}
+
+			var decoded int
+			for _, command := range read {
+				if (name == `session` && command.id != chromeSessionUpdateTabNavigation) || (name == `tab restore` && command.id != chromeTabRestoreUpdateTabNavigation) {
+					continue
+				}
+
+				var tab, index, entry, err = readChromeNavigation(command.payload)
+				if err != nil {
+					t.Fatalf(`readChromeNavigation: %s`, err)
+				}
+
+				var want = tabs[tab-2].navigations[index]
+				if entry.URL != want.URL || entry.Title != want.Title || !entry.LastActive.Equal(fromWebKitTimestamp(want.Timestamp)) {
+					t.Errorf(`tab %d entry %d decoded as %+v, want %+v`, tab, index, entry, want)
+				}
+				decoded++
+			}
+
+			if decoded != 3 {
+				t.Errorf(`decoded %d navigations, want 3`, decoded)
+			}
+		})
+	}
+
+	//-- A truncated trailing command keeps the commands before it ----------
+	{
+		var buffer bytes.Buffer
+		if err := writeSNSS(&buffer, chromeTabRestoreCommands(tabs)); err != nil {
+			t.Fatalf(`writeSNSS: %s`, err)
+		}
+
+		var _, read, err = readSNSS(bytes.NewReader(buffer.Bytes()[:buffer.Len()-3]))
+		if err == nil || len(read) != len(chromeTabRestoreCommands(tabs))-1 {
+			t.Errorf(`truncated file read %d commands with error %v`, len(read), err)
+		}
+	}
+}
+
+// TestChromeSessionRestore commits a session into a profile without a Sessions directory and reads its open tabs back.
+func TestChromeSessionRestore(t *testing.T) {
+	var profile = &chromeProfile{dataPath: t.TempDir() + `/`, random: newRandom(`Chrome`, `Default`)}
+	var stamp = time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	var tabs = []*chromeSessionTab{
+		{id: 2, navigations: []chromeSessionNavigation{{URL: `https://example.com/old`, Title: `Old`, Timestamp: webKitTimestamp(stamp)}, {URL: `https://example.com/new`, Title: `New`, Timestamp: webKitTimestamp(stamp.Add(time.Second))}}},
+		{id: 3, navigations: []chromeSessionNavigation{{URL: `https://example.org/`, Title: `Org`, Timestamp: webKitTimestamp(stamp)}}},
+	}
+
+	var path = profile.dataPath + CHROME_SESSIONS_DIR + `/` + CHROME_SESSION_PREFIX + `1`
+	if err := writeChromeSessionFile(&profile.staged, path, chromeSessionCommands(profile.random, tabs)); err != nil {
+		t.Fatalf(`writeChromeSessionFile: %s`, err)
+	} else if err := profile.staged.commit(); err != nil {
+		t.Fatalf(`commit: %s`, err)
+	}
+
+	var restored, err = profile.readSessions()
+	if err != nil {
+		t.Fatalf(`readSessions: %s`, err)
+	}
+
+	var want = []SessionTab{
+		{URL: `https://example.com/new`, Title: `New`, LastActive: fromWebKitTimestamp(webKitTimestamp(stamp.Add(time.Second)))},
+		{URL: `https://example.org/`, Title: `Org`, LastActive: fromWebKitTimestamp(webKitTimestamp(stamp))},
+	}
+	if !reflect.DeepEqual(restored, want) {
+		t.Fatalf(`restored %+v, want %+v`, restored, want)
+	}
+}
@@ -0,0 +1,265 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//-- Constants ---------------------------------------------------------------------------------------------------------
+
+//-- Structs -----------------------------------------------------------------------------------------------------------
+type takeoutEntry struct {
+	Title          string `json:"title"`
+	URL            string `json:"url"`
+	PageTransition string `json:"page_transition"`
+	TimeMicros     int64  `json:"time_usec"`
+}
+
+type timelineEntry struct {
+	url   string
+	title string
+	visit Visit
+}
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+// ReadTakeoutHistory parses a Google Takeout `BrowserHistory.json` export into one History item per URL carrying the
+// exact recorded visits.
+func ReadTakeoutHistory(input io.Reader) ([]History, error) {
+	var history []History
+	var err = StreamTakeoutHistory(input, math.MaxInt, func(chunk []History) error {
+		history = append(history, chunk...)
+		return nil
+	})
+
+	return history, err
+}
+
+// StreamTakeoutHistory parses a Google Takeout export like ReadTakeoutHistory but decodes it entry by entry, handing
+// every size entries over grouped by URL so an export larger than memory can be replayed. A URL visited across several
+// chunks is handed over once per chunk.
+func StreamTakeoutHistory(input io.Reader, size int, handle func([]History) error) error {
+	var parser = json.NewDecoder(input)
+
+	if token, err := parser.Token(); err != nil {
+		return err
+	} else if token != json.Delim('{') {
+		return fmt.Errorf(`takeout export is not a JSON object`)
+	}
+
+	for parser.More() {
+		var key string
+		if token, err := parser.Token(); err != nil {
+			return err
+		} else {
+			key, _ = token.(string)
+		}
+
+		//-- Skip everything but the history ----------
+		if key != `Browser History` {
+			var skipped json.RawMessage
+			if err := parser.Decode(&skipped); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if token, err := parser.Token(); err != nil {
+			return err
+		} else if token != json.Delim('[') {
+			return fmt.Errorf(`takeout 'Browser History' is not a list`)
+		}
+
+		//-- Convert entries ----------
+		var entries []timelineEntry
+		for index := 0; parser.More(); index++ {
+			var item takeoutEntry
+			if err := parser.Decode(&item); err != nil {
+				return err
+			}
+
+			if entry, err := takeoutTimelineEntry(index, item); err != nil {
+				return err
+			} else {
+				entries = append(entries, entry)
+			}
+
+			if len(entries) >= size {
+				if err := handle(groupTimeline(entries)); err != nil {
+					return err
+				}
+				entries = nil
+			}
+		}
+
+		if _, err := parser.Token(); err != nil {
+			return err
+		}
+
+		if len(entries) > 0 {
+			if err := handle(groupTimeline(entries)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ReadTimelineCSV parses `timestamp,url,title,transition` rows, an optional header row is skipped. Timestamps may be
+// RFC 3339 or Unix seconds and an empty transition is treated as a link.
+func ReadTimelineCSV(input io.Reader) ([]History, error) {
+	var history []History
+	var err = StreamTimelineCSV(input, math.MaxInt, func(chunk []History) error {
+		history = append(history, chunk...)
+		return nil
+	})
+
+	return history, err
+}
+
+// StreamTimelineCSV parses timeline rows like ReadTimelineCSV but hands every size rows over grouped by URL, so a
+// timeline larger than memory can be replayed. A URL visited across several chunks is handed over once per chunk.
+func StreamTimelineCSV(input io.Reader, size int, handle func([]History) error) error {
+	var parser = csv.NewReader(input)
+	parser.FieldsPerRecord = -1
+	parser.TrimLeadingSpace = true
+
+	var entries []timelineEntry
+	for index := 0; ; index++ {
+		var row, err = parser.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		if index == 0 && len(row) > 0 && strings.EqualFold(strings.TrimSpace(row[0]), `timestamp`) {
+			continue
+		} else if entry, err := csvTimelineEntry(index, row); err != nil {
+			return err
+		} else {
+			entries = append(entries, entry)
+		}
+
+		if len(entries) >= size {
+			if err := handle(groupTimeline(entries)); err != nil {
+				return err
+			}
+			entries = nil
+		}
+	}
+
+	if len(entries) > 0 {
+		return handle(groupTimeline(entries))
+	}
+
+	return nil
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+func takeoutTimelineEntry(index int, item takeoutEntry) (timelineEntry, error) {
+	if item.URL == `` || item.TimeMicros <= 0 {
+		return timelineEntry{}, fmt.Errorf(`takeout entry %d is missing a url or time_usec`, index)
+	}
+
+	var transition = TransitionLink
+	if item.PageTransition != `` {
+		if parsed, err := ParseTransition(item.PageTransition); err != nil {
+			return timelineEntry{}, fmt.Errorf(`takeout entry %d: %s`, index, err)
+		} else {
+			transition = parsed
+		}
+	}
+
+	return timelineEntry{
+		url:   item.URL,
+		title: item.Title,
+		visit: Visit{Time: time.Unix(0, item.TimeMicros*int64(time.Microsecond)), Transition: transition},
+	}, nil
+}
+
+func csvTimelineEntry(index int, row []string) (timelineEntry, error) {
+	if len(row) < 2 {
+		return timelineEntry{}, fmt.Errorf(`timeline row %d: expected at least timestamp and url`, index+1)
+	}
+
+	var entry = timelineEntry{url: strings.TrimSpace(row[1])}
+
+	if moment, err := parseTimelineTime(row[0]); err != nil {
+		return timelineEntry{}, fmt.Errorf(`timeline row %d: %s`, index+1, err)
+	} else {
+		entry.visit.Time = moment
+	}
+
+	if len(row) > 2 {
+		entry.title = row[2]
+	}
+
+	if len(row) > 3 && strings.TrimSpace(row[3]) != `` {
+		if transition, err := ParseTransition(row[3]); err != nil {
+			return timelineEntry{}, fmt.Errorf(`timeline row %d: %s`, index+1, err)
+		} else {
+			entry.visit.Transition = transition
+		}
+	}
+
+	return entry, nil
+}
+
+func groupTimeline(entries []timelineEntry) []History {
+	var order []string
+	var grouped = map[string]*History{}
+	var titled = map[string]time.Time{}
+
+	for _, entry := range entries {
+		var item, ok = grouped[entry.url]
+		if !ok {
+			item = &History{URL: entry.url}
+			grouped[entry.url] = item
+			order = append(order, entry.url)
+		}
+
+		//-- Most recent non-empty title wins ----------
+		if entry.title != `` && !entry.visit.Time.Before(titled[entry.url]) {
+			item.Name = entry.title
+			titled[entry.url] = entry.visit.Time
+		}
+
+		item.Timeline = append(item.Timeline, entry.visit)
+	}
+
+	var history = make([]History, 0, len(order))
+	for _, url := range order {
+		var item = grouped[url]
+
+		sort.Slice(item.Timeline, func(i, j int) bool { return item.Timeline[i].Time.Before(item.Timeline[j].Time) })
+		item.Visits = len(item.Timeline)
+
+		history = append(history, *item)
+	}
+
+	return history
+}
+
+func parseTimelineTime(raw string) (time.Time, error) {
+	var value = strings.TrimSpace(raw)
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	} else if moment, err := time.Parse(time.RFC3339, value); err == nil {
+		return moment, nil
+	} else if moment, err := time.ParseInLocation(`2006-01-02 15:04:05`, value, time.Local); err == nil {
+		return moment, nil
+	}
+
+	return time.Time{}, fmt.Errorf(`unable to parse timestamp '%s'`, raw)
+}
@@ -0,0 +1,142 @@
+//-- Package Declaration -----------------------------------------------------------------------------------------------
+package browsers
+
+//-- Imports -----------------------------------------------------------------------------------------------------------
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+//-- Exported Functions ------------------------------------------------------------------------------------------------
+func TestReadTakeoutHistory(t *testing.T) {
+	var cases = []struct {
+		name     string
+		document string
+		want     []History
+		fails    bool
+	}{
+		{`grouped by url`, `{"Browser History": [
+			{"title": "Old", "url": "https://a.example/", "page_transition": "LINK", "time_usec": 1600000000000000},
+			{"title": "New", "url": "https://a.example/", "page_transition": "TYPED", "time_usec": 1600000060000000},
+			{"title": "B", "url": "https://b.example/", "time_usec": 1600000030000000}
+		]}`, []History{
+			{Name: `New`, URL: `https://a.example/`, Visits: 2, Timeline: []Visit{
+				{Time: time.Unix(1600000000, 0), Transition: TransitionLink},
+				{Time: time.Unix(1600000060, 0), Transition: TransitionTyped},
+			}},
+			{Name: `B`, URL: `https://b.example/`, Visits: 1, Timeline: []Visit{{Time: time.Unix(1600000030, 0)}}},
+		}, false},
+		{`extra keys`, `{"Autofill": [{"name": "x"}], "Browser History": [
+			{"favicon_url": "https://c.example/favicon.ico", "page_transition": "form_submit", "title": "C", "url": "https://c.example/", "time_usec": 1600000000500000, "client_id": "abc"}
+		], "Search Engines": {"nested": [1, 2, 3]}}`, []History{
+			{Name: `C`, URL: `https://c.example/`, Visits: 1, Timeline: []Visit{{Time: time.Unix(1600000000, 500000000), Transition: TransitionFormSubmit}}},
+		}, false},
+		{`no history`, `{"Autofill": []}`, nil, false},
+		{`not an object`, `[]`, nil, true},
+		{`history not a list`, `{"Browser History": {}}`, nil, true},
+		{`missing time`, `{"Browser History": [{"url": "https://a.example/"}]}`, nil, true},
+		{`unknown transition`, `{"Browser History": [{"url": "https://a.example/", "time_usec": 1600000000000000, "page_transition": "TELEPORT"}]}`, nil, true},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			var history, err = ReadTakeoutHistory(strings.NewReader(test.document))
+			if test.fails {
+				if err == nil {
+					t.Fatalf(`expected an error, read %+v`, history)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf(`ReadTakeoutHistory: %s`, err)
+			}
+
+			expectHistory(t, history, test.want)
+		})
+	}
+}
+
+func TestReadTimelineCSV(t *testing.T) {
+	var cases = []struct {
+		name     string
+		document string
+		want     []History
+		fails    bool
+	}{
+		{`header and rfc 3339`, "timestamp,url,title,transition\n" +
+			"2020-09-13T12:26:40Z,https://a.example/,A,typed\n" +
+			"2020-09-13T14:26:41+02:00, https://a.example/,,\n", []History{
+			{Name: `A`, URL: `https://a.example/`, Visits: 2, Timeline: []Visit{
+				{Time: time.Unix(1600000000, 0), Transition: TransitionTyped},
+				{Time: time.Unix(1600000001, 0), Transition: TransitionLink},
+			}},
+		}, false},
+		{`no header and unix seconds`, "1600000060,https://b.example/,Later\n" +
+			"1600000000,https://b.example/,Earlier,RELOAD\n" +
+			"1600000030,https://c.example/\n", []History{
+			{Name: `Later`, URL: `https://b.example/`, Visits: 2, Timeline: []Visit{
+				{Time: time.Unix(1600000000, 0), Transition: TransitionReload},
+				{Time: time.Unix(1600000060, 0), Transition: TransitionLink},
+			}},
+			{URL: `https://c.example/`, Visits: 1, Timeline: []Visit{{Time: time.Unix(1600000030, 0)}}},
+		}, false},
+		{`header only`, "Timestamp,URL,Title,Transition\n", nil, false},
+		{`missing url`, "1600000000\n", nil, true},
+		{`bad timestamp`, "yesterday,https://a.example/\n", nil, true},
+		{`unknown transition`, "1600000000,https://a.example/,A,TELEPORT\n", nil, true},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			var history, err = ReadTimelineCSV(strings.NewReader(test.document))
+			if test.fails {
+				if err == nil {
+					t.Fatalf(`expected an error, read %+v`, history)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf(`ReadTimelineCSV: %s`, err)
+			}
+
+			expectHistory(t, history, test.want)
+		})
+	}
+}
+
+func TestStreamTimelineCSVChunks(t *testing.T) {
+	var document = "1600000000,https://a.example/\n1600000001,https://a.example/\n1600000002,https://b.example/\n"
+
+	var chunks [][]History
+	if err := StreamTimelineCSV(strings.NewReader(document), 2, func(chunk []History) error {
+		chunks = append(chunks, chunk)
+		return nil
+	}); err != nil {
+		t.Fatalf(`StreamTimelineCSV: %s`, err)
+	}
+
+	if len(chunks) != 2 || len(chunks[0]) != 1 || chunks[0][0].Visits != 2 || len(chunks[1]) != 1 || chunks[1][0].URL != `https://b.example/` {
+		t.Fatalf(`chunks %+v, want a.example with two visits then b.example`, chunks)
+	}
+}
+
+//-- Internal Functions ------------------------------------------------------------------------------------------------
+func expectHistory(t *testing.T, history []History, want []History) {
+	t.Helper()
+
+	if len(history) != len(want) {
+		t.Fatalf(`read %d history items, want %d: %+v`, len(history), len(want), history)
+	}
+
+	for index := range want {
+		var got, expected = history[index], want[index]
+		if got.Name != expected.Name || got.URL != expected.URL || got.Visits != expected.Visits || len(got.Timeline) != len(expected.Timeline) {
+			t.Fatalf(`item %d is %+v, want %+v`, index, got, expected)
+		}
+
+		for visit := range expected.Timeline {
+			if !got.Timeline[visit].Time.Equal(expected.Timeline[visit].Time) || got.Timeline[visit].Transition != expected.Timeline[visit].Transition {
+				t.Fatalf(`item %d visit %d is %+v, want %+v`, index, visit, got.Timeline[visit], expected.Timeline[visit])
+			}
+		}
+	}
+}